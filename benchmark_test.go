@@ -0,0 +1,147 @@
+package panyl_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RangelReale/panyl/v2"
+	"github.com/RangelReale/panyl/v2/plugins/clean"
+	"github.com/RangelReale/panyl/v2/plugins/consolidate"
+	"github.com/RangelReale/panyl/v2/plugins/metadata"
+	"github.com/RangelReale/panyl/v2/plugins/structure"
+)
+
+func BenchmarkProcessor_JSON(b *testing.B) {
+	benchmarkProcess(b, benchmarkJSONLines(10000))
+}
+
+func BenchmarkProcessor_MixedText(b *testing.B) {
+	benchmarkProcess(b, benchmarkColoredLines(10000))
+}
+
+func BenchmarkProcessor_StackTraces(b *testing.B) {
+	benchmarkProcess(b, benchmarkStackTraceLines(1000))
+}
+
+func BenchmarkProcessor_MultilineJSON(b *testing.B) {
+	benchmarkProcess(b, benchmarkMultilineJSONLines(1000))
+}
+
+// BenchmarkProcessor_Concurrency uses a Metadata plugin which simulates an enrichment lookup, like GeoIP or an
+// external service, which is where the concurrent pipeline helps. Cheap plugins don't benefit from it.
+func BenchmarkProcessor_Concurrency(b *testing.B) {
+	ctx := context.Background()
+	data := benchmarkColoredLines(500)
+
+	processor := panyl.NewProcessor(
+		panyl.WithPlugins(
+			&clean.AnsiEscape{},
+			benchmarkLookupPlugin{latency: 100 * time.Microsecond},
+			&structure.JSON{},
+		),
+	)
+
+	for _, concurrency := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("concurrency-%d", concurrency), func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				err := processor.Process(ctx, strings.NewReader(data), &panyl.OutputNull{},
+					panyl.WithConcurrency(concurrency))
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// benchmarkLookupPlugin is a concurrency-safe Metadata plugin which waits for latency on each line.
+type benchmarkLookupPlugin struct {
+	latency time.Duration
+}
+
+func (m benchmarkLookupPlugin) IsPanylPlugin() {}
+
+func (m benchmarkLookupPlugin) IsConcurrencySafe() bool {
+	return true
+}
+
+func (m benchmarkLookupPlugin) ExtractMetadata(ctx context.Context, item *panyl.Item) (bool, error) {
+	time.Sleep(m.latency)
+	item.Metadata[panyl.MetadataCategory] = "lookup"
+	return true, nil
+}
+
+func benchmarkProcess(b *testing.B, data string, options ...panyl.JobOption) {
+	ctx := context.Background()
+
+	processor := panyl.NewProcessor(
+		panyl.WithPlugins(
+			&clean.AnsiEscape{},
+			&metadata.ForceApplication{Application: "bench"},
+			&structure.JSON{},
+			&consolidate.JoinAllLines{},
+		),
+	)
+
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err := processor.Process(ctx, strings.NewReader(data), &panyl.OutputNull{}, options...)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// benchmarkColoredLines generates text lines with ANSI colors and JSON lines.
+func benchmarkColoredLines(amount int) string {
+	var ret strings.Builder
+	for i := 0; i < amount; i++ {
+		if i%2 == 0 {
+			_, _ = fmt.Fprintf(&ret, "\x1b[32m2024-01-01 10:00:00\x1b[0m \x1b[1;34mINFO\x1b[0m request %d "+
+				"\x1b[33mGET /api/v1/items/%d\x1b[0m completed in \x1b[35m%dms\x1b[0m\n", i, i, i%100)
+		} else {
+			_, _ = fmt.Fprintf(&ret, `{"level":"info","msg":"request %d","path":"/api/v1/items/%d","duration":%d}`+"\n",
+				i, i, i%100)
+		}
+	}
+	return ret.String()
+}
+
+// benchmarkJSONLines generates JSON lines.
+func benchmarkJSONLines(amount int) string {
+	var ret strings.Builder
+	for i := 0; i < amount; i++ {
+		_, _ = fmt.Fprintf(&ret, `{"ts":"2024-01-01T10:00:00Z","level":"info","msg":"request %d",`+
+			`"http":{"method":"GET","path":"/api/v1/items/%d","status":200},"duration":%d}`+"\n", i, i, i%100)
+	}
+	return ret.String()
+}
+
+// benchmarkStackTraceLines generates JSON lines followed by long text stack traces.
+func benchmarkStackTraceLines(amount int) string {
+	var ret strings.Builder
+	for i := 0; i < amount; i++ {
+		_, _ = fmt.Fprintf(&ret, `{"level":"error","msg":"request %d failed"}`+"\n", i)
+		_, _ = fmt.Fprintf(&ret, "java.lang.IllegalStateException: invalid state %d\n", i)
+		for j := 0; j < 30; j++ {
+			_, _ = fmt.Fprintf(&ret, "\tat com.example.service.Handler%d.handle(Handler%d.java:%d)\n", j, j, j*10)
+		}
+	}
+	return ret.String()
+}
+
+// benchmarkMultilineJSONLines generates pretty-printed JSON.
+func benchmarkMultilineJSONLines(amount int) string {
+	var ret strings.Builder
+	for i := 0; i < amount; i++ {
+		_, _ = fmt.Fprintf(&ret, "{\n  \"level\": \"info\",\n  \"msg\": \"request %d\",\n  \"http\": {\n"+
+			"    \"method\": \"GET\",\n    \"status\": 200\n  }\n}\n", i)
+	}
+	return ret.String()
+}
@@ -11,3 +11,22 @@ type DebugLog interface {
 	// LogItem receives one Item right before it is sent to Output.
 	LogItem(ctx context.Context, item *Item)
 }
+
+// DebugLogTrace is an optional interface a DebugLog can implement to receive each decision taken by the
+// processing phases. n is always the number of the current source line.
+type DebugLogTrace interface {
+	DebugLog
+	// LogStructure receives each PluginStructure tried against a slice of the backlog, and whether it matched.
+	LogStructure(ctx context.Context, n int, plugin PluginStructure, lines ItemLines, matched bool)
+	// LogParse receives each PluginParse tried against a slice of the backlog, and whether it matched.
+	LogParse(ctx context.Context, n int, plugin PluginParse, lines ItemLines, matched bool)
+	// LogSequenceBlock receives the PluginSequence that blocked the sequence between the last 2 lines.
+	LogSequenceBlock(ctx context.Context, n int, plugin PluginSequence, lastp, item *Item)
+	// LogConsolidate receives each PluginConsolidate tried against the top of a slice of lines, and the amount
+	// of top lines consolidated if it matched.
+	LogConsolidate(ctx context.Context, n int, plugin PluginConsolidate, lines ItemLines, matched bool, topLines int)
+	// LogBacklogOverflow receives the backlog lines that are being flushed because MaxBacklogLines was exceeded.
+	LogBacklogOverflow(ctx context.Context, n int, lines ItemLines)
+	// LogParseFormat receives each PluginParseFormat tried against an item, and whether it matched.
+	LogParseFormat(ctx context.Context, n int, plugin PluginParseFormat, item *Item, matched bool)
+}
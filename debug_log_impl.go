@@ -17,6 +17,7 @@ type DebugLogOutput struct {
 }
 
 var _ DebugLog = DebugLogOutput{}
+var _ DebugLogTrace = DebugLogOutput{}
 
 // NewDebugLogOutput creates a DebugLogOutput using an io.Writer.
 func NewDebugLogOutput(w io.Writer) DebugLogOutput {
@@ -33,12 +34,7 @@ func (l DebugLogOutput) LogSourceLine(ctx context.Context, n int, line, rawLine
 }
 
 func (l DebugLogOutput) LogItem(ctx context.Context, item *Item) {
-	var lineno string
-	if item.LineCount > 1 {
-		lineno = fmt.Sprintf("[%d-%d]", item.LineNo, item.LineNo+item.LineCount-1)
-	} else {
-		lineno = fmt.Sprintf("[%d]", item.LineNo)
-	}
+	lineno := debugLineRange(item.LineNo, item.LineCount)
 
 	var buf bytes.Buffer
 
@@ -68,3 +64,56 @@ func (l DebugLogOutput) LogItem(ctx context.Context, item *Item) {
 
 	_, _ = fmt.Fprintf(l.w, "*** PROCESS LINE %s: %s\n", lineno, buf.String())
 }
+
+func (l DebugLogOutput) LogStructure(ctx context.Context, n int, plugin PluginStructure, lines ItemLines, matched bool) {
+	_, _ = fmt.Fprintf(l.w, "    --- STRUCTURE %s %T: %s\n", debugItemLinesRange(lines), plugin, debugMatched(matched))
+}
+
+func (l DebugLogOutput) LogParse(ctx context.Context, n int, plugin PluginParse, lines ItemLines, matched bool) {
+	_, _ = fmt.Fprintf(l.w, "    --- PARSE %s %T: %s\n", debugItemLinesRange(lines), plugin, debugMatched(matched))
+}
+
+func (l DebugLogOutput) LogSequenceBlock(ctx context.Context, n int, plugin PluginSequence, lastp, item *Item) {
+	_, _ = fmt.Fprintf(l.w, "    --- SEQUENCE BLOCK [%d|%d] %T\n", lastp.LineNo, item.LineNo, plugin)
+}
+
+func (l DebugLogOutput) LogConsolidate(ctx context.Context, n int, plugin PluginConsolidate, lines ItemLines,
+	matched bool, topLines int) {
+	if matched {
+		_, _ = fmt.Fprintf(l.w, "    --- CONSOLIDATE %s %T: %s %d top lines\n", debugItemLinesRange(lines), plugin,
+			debugMatched(matched), topLines)
+	} else {
+		_, _ = fmt.Fprintf(l.w, "    --- CONSOLIDATE %s %T: %s\n", debugItemLinesRange(lines), plugin,
+			debugMatched(matched))
+	}
+}
+
+func (l DebugLogOutput) LogBacklogOverflow(ctx context.Context, n int, lines ItemLines) {
+	_, _ = fmt.Fprintf(l.w, "    --- BACKLOG OVERFLOW %s: flushing %d lines\n", debugItemLinesRange(lines), len(lines))
+}
+
+func (l DebugLogOutput) LogParseFormat(ctx context.Context, n int, plugin PluginParseFormat, item *Item, matched bool) {
+	_, _ = fmt.Fprintf(l.w, "    --- PARSE FORMAT %s %T: %s\n", debugLineRange(item.LineNo, item.LineCount), plugin,
+		debugMatched(matched))
+}
+
+func debugLineRange(lineNo, lineCount int) string {
+	if lineCount > 1 {
+		return fmt.Sprintf("[%d-%d]", lineNo, lineNo+lineCount-1)
+	}
+	return fmt.Sprintf("[%d]", lineNo)
+}
+
+func debugItemLinesRange(lines ItemLines) string {
+	if len(lines) == 0 {
+		return "[]"
+	}
+	return debugLineRange(lines[0].LineNo, lines[len(lines)-1].LineNo-lines[0].LineNo+1)
+}
+
+func debugMatched(matched bool) string {
+	if matched {
+		return "matched"
+	}
+	return "no match"
+}
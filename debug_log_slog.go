@@ -0,0 +1,126 @@
+package panyl
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"unicode/utf8"
+)
+
+// DebugLogSLog writes structured log records to the slog.Logger returned by SLogFromContext.
+type DebugLogSLog struct {
+	Level slog.Level
+}
+
+var _ DebugLog = DebugLogSLog{}
+var _ DebugLogTrace = DebugLogSLog{}
+
+// NewDebugLogSLog creates a DebugLogSLog which logs using slog.LevelDebug.
+func NewDebugLogSLog() DebugLogSLog {
+	return DebugLogSLog{Level: slog.LevelDebug}
+}
+
+func (l DebugLogSLog) LogSourceLine(ctx context.Context, n int, line, rawLine string) {
+	l.log(ctx, "source line", n, "source",
+		slog.String("line", line))
+}
+
+func (l DebugLogSLog) LogItem(ctx context.Context, item *Item) {
+	l.log(ctx, "item", item.LineNo, "output",
+		slogItemSummary(item))
+}
+
+func (l DebugLogSLog) LogStructure(ctx context.Context, n int, plugin PluginStructure, lines ItemLines, matched bool) {
+	l.log(ctx, "structure plugin", n, "structure",
+		slog.String("plugin", pluginTypeName(plugin)),
+		slogItemLinesRange(lines),
+		slog.Bool("matched", matched))
+}
+
+func (l DebugLogSLog) LogParse(ctx context.Context, n int, plugin PluginParse, lines ItemLines, matched bool) {
+	l.log(ctx, "parse plugin", n, "parse",
+		slog.String("plugin", pluginTypeName(plugin)),
+		slogItemLinesRange(lines),
+		slog.Bool("matched", matched))
+}
+
+func (l DebugLogSLog) LogSequenceBlock(ctx context.Context, n int, plugin PluginSequence, lastp, item *Item) {
+	l.log(ctx, "sequence block", n, "sequence",
+		slog.String("plugin", pluginTypeName(plugin)),
+		slog.Int("last_line", lastp.LineNo))
+}
+
+func (l DebugLogSLog) LogConsolidate(ctx context.Context, n int, plugin PluginConsolidate, lines ItemLines,
+	matched bool, topLines int) {
+	l.log(ctx, "consolidate plugin", n, "consolidate",
+		slog.String("plugin", pluginTypeName(plugin)),
+		slogItemLinesRange(lines),
+		slog.Bool("matched", matched),
+		slog.Int("top_lines", topLines))
+}
+
+func (l DebugLogSLog) LogBacklogOverflow(ctx context.Context, n int, lines ItemLines) {
+	l.log(ctx, "backlog overflow", n, "backlog",
+		slogItemLinesRange(lines),
+		slog.Int("count", len(lines)))
+}
+
+func (l DebugLogSLog) LogParseFormat(ctx context.Context, n int, plugin PluginParseFormat, item *Item, matched bool) {
+	l.log(ctx, "parse format plugin", n, "parse_format",
+		slog.String("plugin", pluginTypeName(plugin)),
+		slogItemSummary(item),
+		slog.Bool("matched", matched))
+}
+
+func (l DebugLogSLog) log(ctx context.Context, msg string, n int, phase string, attrs ...slog.Attr) {
+	logger := SLogFromContext(ctx)
+	if !logger.Enabled(ctx, l.Level) {
+		return
+	}
+	logger.LogAttrs(ctx, l.Level, msg, append([]slog.Attr{
+		slog.Int("line", n),
+		slog.String("phase", phase),
+	}, attrs...)...)
+}
+
+// slogItemSummary returns a short description of an Item, without its full data.
+func slogItemSummary(item *Item) slog.Attr {
+	attrs := []any{
+		slog.Int("line_no", item.LineNo),
+		slog.Int("line_count", item.LineCount),
+	}
+	for _, name := range []string{MetadataStructure, MetadataFormat, MetadataLevel, MetadataApplication} {
+		if value := item.Metadata.StringValue(name); value != "" {
+			attrs = append(attrs, slog.String(name, value))
+		}
+	}
+	if msg := item.Metadata.StringValue(MetadataMessage); msg != "" {
+		attrs = append(attrs, slog.String(MetadataMessage, truncateString(msg, 100)))
+	} else if item.Line != "" {
+		attrs = append(attrs, slog.String("text", truncateString(item.Line, 100)))
+	}
+	return slog.Group("item", attrs...)
+}
+
+func slogItemLinesRange(lines ItemLines) slog.Attr {
+	if len(lines) == 0 {
+		return slog.Group("lines")
+	}
+	return slog.Group("lines",
+		slog.Int("from", lines[0].LineNo),
+		slog.Int("to", lines[len(lines)-1].LineNo))
+}
+
+func pluginTypeName(plugin any) string {
+	return fmt.Sprintf("%T", plugin)
+}
+
+func truncateString(s string, size int) string {
+	if len(s) <= size {
+		return s
+	}
+	for size > 0 && !utf8.RuneStart(s[size]) {
+		size--
+	}
+	return s[:size] + "..."
+}
@@ -0,0 +1,77 @@
+package panyl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDebugLogOutput_Trace(t *testing.T) {
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	p := NewProcessor(
+		WithPlugins(&ParsePrefixPluginTest{prefix: "ok"}),
+		WithDebugLog(NewDebugLogOutput(&buf)),
+	)
+
+	err := p.Process(ctx, strings.NewReader("first\nsecond\nthird\nok line"), &OutputNull{},
+		WithMaxBacklogLines(2))
+	assert.NoError(t, err)
+
+	out := buf.String()
+	assert.Contains(t, out, "--- PARSE [1] *panyl.ParsePrefixPluginTest: no match")
+	assert.Contains(t, out, "--- PARSE [1-2] *panyl.ParsePrefixPluginTest: no match")
+	assert.Contains(t, out, "--- BACKLOG OVERFLOW [1-3]: flushing 3 lines")
+	assert.Contains(t, out, "--- PARSE [4] *panyl.ParsePrefixPluginTest: matched")
+}
+
+func TestDebugLogSLog(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := SLogToContext(context.Background(), slog.New(slog.NewJSONHandler(&buf,
+		&slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	p := NewProcessor(
+		WithPlugins(&ParsePrefixPluginTest{prefix: "ok"}),
+		WithDebugLog(NewDebugLogSLog()),
+	)
+
+	err := p.Process(ctx, strings.NewReader("ok line"), &OutputNull{})
+	assert.NoError(t, err)
+
+	var records []map[string]any
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var record map[string]any
+		assert.NoError(t, dec.Decode(&record))
+		records = append(records, record)
+	}
+
+	assert.Len(t, records, 3)
+	assert.Equal(t, "source", records[0]["phase"])
+	assert.Equal(t, "parse", records[1]["phase"])
+	assert.Equal(t, "*panyl.ParsePrefixPluginTest", records[1]["plugin"])
+	assert.Equal(t, true, records[1]["matched"])
+	assert.Equal(t, "output", records[2]["phase"])
+	assert.Equal(t, "ok line", records[2]["item"].(map[string]any)["text"])
+}
+
+// ParsePrefixPluginTest
+type ParsePrefixPluginTest struct {
+	prefix string
+}
+
+func (pt ParsePrefixPluginTest) IsPanylPlugin() {}
+
+func (pt ParsePrefixPluginTest) ExtractParse(ctx context.Context, lines ItemLines, item *Item) (bool, error) {
+	if len(lines) != 1 || !strings.HasPrefix(lines[0].Line, pt.prefix) {
+		return false, nil
+	}
+	item.Line = lines[0].Line
+	return true, nil
+}
@@ -3,7 +3,7 @@ module github.com/RangelReale/panyl/v2
 go 1.23
 
 require (
-	github.com/imdario/mergo v0.3.12
+	github.com/golang/snappy v1.0.0
 	github.com/stretchr/testify v1.7.1
 )
 
@@ -0,0 +1,329 @@
+// Package ingest provides an http.Handler which processes POSTed log bodies, allowing panyl to be used as a parsing
+// service.
+package ingest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/RangelReale/panyl/v2"
+	"github.com/RangelReale/panyl/v2/output"
+)
+
+const DefaultMaxBodySize = 32 * 1024 * 1024
+
+// ContentTypeNDJSON is the content type of the streamed response.
+const ContentTypeNDJSON = "application/x-ndjson"
+
+// Handler is an http.Handler which processes the body of POST requests with a Processor, one Job for each request.
+// The body can be plain text or NDJSON, optionally gzip encoded. NDJSON lines are processed like any other line,
+// so the Processor needs a JSON structure plugin to parse them.
+//
+// By default each item is streamed back as a NDJSON line using ItemDocument, and the Job is cancelled if the client
+// goes away. If an output is set using WithOutput, the items are sent to it instead, and the response is a JSON
+// object with the amount of items, like {"items": 10}. In this case the whole body is read before processing, so a
+// request whose body can't be read is rejected without sending any item to the output.
+type Handler struct {
+	processor   *panyl.Processor
+	output      *output.Shared
+	jobOptions  []panyl.JobOption
+	maxBodySize int64
+	document    func(item *panyl.Item) any
+}
+
+var _ http.Handler = (*Handler)(nil)
+
+// NewHandler creates a Handler processing the requests with processor.
+func NewHandler(processor *panyl.Processor, options ...Option) *Handler {
+	ret := &Handler{
+		processor:   processor,
+		maxBodySize: DefaultMaxBodySize,
+		document:    func(item *panyl.Item) any { return NewItemDocument(item) },
+	}
+	for _, opt := range options {
+		opt(ret)
+	}
+	return ret
+}
+
+type Option func(*Handler)
+
+// WithOutput sends the items of all requests to out, instead of returning them in the response. The calls to the
+// output are serialized, and it is only flushed at the end of each request, call Handler.Close to close it.
+func WithOutput(out panyl.Output) Option {
+	return func(h *Handler) {
+		h.output = output.NewShared(out)
+	}
+}
+
+// WithJobOptions sets the options of the Job created for each request.
+func WithJobOptions(options ...panyl.JobOption) Option {
+	return func(h *Handler) {
+		h.jobOptions = append(h.jobOptions, options...)
+	}
+}
+
+// WithMaxBodySize sets the maximum size of the request body after decompression, by default DefaultMaxBodySize.
+// Zero or less means no limit.
+func WithMaxBodySize(maxBodySize int64) Option {
+	return func(h *Handler) {
+		h.maxBodySize = maxBodySize
+	}
+}
+
+// WithDocument sets a function returning the value encoded as JSON for each item in the response, by default
+// NewItemDocument.
+func WithDocument(document func(item *panyl.Item) any) Option {
+	return func(h *Handler) {
+		h.document = document
+	}
+}
+
+// Close closes the output set with WithOutput, if any.
+func (h *Handler) Close(ctx context.Context) {
+	if h.output != nil {
+		h.output.Close(ctx)
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := checkContentType(r.Header.Get("Content-Type")); err != nil {
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	var body io.Reader = r.Body
+	switch encoding := strings.ToLower(r.Header.Get("Content-Encoding")); encoding {
+	case "", "identity":
+	case "gzip":
+		gr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid gzip body: %s", err), http.StatusBadRequest)
+			return
+		}
+		defer gr.Close()
+		body = gr
+	default:
+		http.Error(w, fmt.Sprintf("unsupported content encoding %q", encoding), http.StatusUnsupportedMediaType)
+		return
+	}
+	if h.maxBodySize > 0 {
+		body = &limitReader{r: body, n: h.maxBodySize}
+	}
+
+	if h.output != nil {
+		h.serveOutput(ctx, w, body)
+	} else {
+		h.serveStream(ctx, w, body)
+	}
+}
+
+// serveOutput sends the items to the configured output. The body is read first, so the request is rejected if it
+// is invalid before any item is sent to the output.
+func (h *Handler) serveOutput(ctx context.Context, w http.ResponseWriter, body io.Reader) {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		h.logError(ctx, err)
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+	count := &countOutput{output: h.output}
+	if err := h.processor.Process(ctx, bytes.NewReader(data), count, h.jobOptions...); err != nil {
+		// the items processed before the error were already sent to the output
+		h.logError(ctx, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"items": count.count})
+}
+
+// serveStream streams the items back as NDJSON. As the status is sent with the first item, errors after it are
+// returned as a last line, like {"error": "message"}.
+func (h *Handler) serveStream(ctx context.Context, w http.ResponseWriter, body io.Reader) {
+	// the Job is cancelled when the client goes away
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream := &streamOutput{w: w, document: h.document, cancel: cancel}
+	stream.flusher, _ = w.(http.Flusher)
+	if err := h.processor.Process(ctx, body, stream, h.jobOptions...); err != nil {
+		if stream.err != nil {
+			h.logError(ctx, stream.err)
+			return
+		}
+		h.logError(ctx, err)
+		if !stream.started {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"error": err.Error()})
+		return
+	}
+	if stream.err != nil {
+		h.logError(ctx, stream.err)
+		return
+	}
+	stream.start()
+}
+
+func (h *Handler) logError(ctx context.Context, err error) {
+	panyl.SLogFromContext(ctx).WarnContext(ctx, "error processing request body", slog.Any("error", err))
+}
+
+// ItemDocument is the JSON representation of an item in the streamed response.
+type ItemDocument struct {
+	LineNo    int            `json:"line_no"`
+	LineCount int            `json:"line_count"`
+	Metadata  map[string]any `json:"metadata"`
+	Data      map[string]any `json:"data,omitempty"`
+	Line      string         `json:"line,omitempty"`
+	Source    string         `json:"source,omitempty"`
+}
+
+// NewItemDocument creates the ItemDocument of an item. Timestamps are formatted as RFC3339 with nanoseconds.
+func NewItemDocument(item *panyl.Item) ItemDocument {
+	metadata := make(map[string]any, len(item.Metadata))
+	for k, v := range item.Metadata {
+		if ts, ok := v.(time.Time); ok {
+			v = ts.Format(time.RFC3339Nano)
+		}
+		metadata[k] = v
+	}
+	return ItemDocument{
+		LineNo:    item.LineNo,
+		LineCount: item.LineCount,
+		Metadata:  metadata,
+		Data:      item.Data,
+		Line:      item.Line,
+		Source:    item.Source,
+	}
+}
+
+// errBodyTooLarge is returned when the body is larger than the maximum body size.
+var errBodyTooLarge = errors.New("request body too large")
+
+func errorStatus(err error) int {
+	if errors.Is(err, errBodyTooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+func checkContentType(contentType string) error {
+	if contentType == "" {
+		return nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return fmt.Errorf("invalid content type %q", contentType)
+	}
+	switch mediaType {
+	case "text/plain", ContentTypeNDJSON, "application/jsonl", "application/json", "application/octet-stream":
+		return nil
+	}
+	return fmt.Errorf("unsupported content type %q", mediaType)
+}
+
+// limitReader returns errBodyTooLarge after n bytes, unlike io.LimitReader which returns io.EOF.
+type limitReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	if l.n <= 0 {
+		// check if there is more data
+		var b [1]byte
+		n, err := l.r.Read(b[:])
+		if n > 0 {
+			return 0, errBodyTooLarge
+		}
+		return 0, err
+	}
+	if int64(len(p)) > l.n {
+		p = p[:l.n]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	return n, err
+}
+
+// countOutput counts the items sent to the output.
+type countOutput struct {
+	output panyl.Output
+	count  int
+}
+
+func (c *countOutput) OnItem(ctx context.Context, item *panyl.Item) bool {
+	c.count++
+	return c.output.OnItem(ctx, item)
+}
+
+func (c *countOutput) OnFlush(ctx context.Context) {
+	c.output.OnFlush(ctx)
+}
+
+func (c *countOutput) OnClose(ctx context.Context) {
+	c.output.OnClose(ctx)
+}
+
+// streamOutput writes each item as a NDJSON line.
+type streamOutput struct {
+	w        http.ResponseWriter
+	flusher  http.Flusher
+	document func(item *panyl.Item) any
+	cancel   context.CancelFunc // cancels the Job if the client goes away
+	started  bool
+	err      error
+}
+
+func (s *streamOutput) start() {
+	if !s.started {
+		s.w.Header().Set("Content-Type", ContentTypeNDJSON)
+		s.w.WriteHeader(http.StatusOK)
+		s.started = true
+	}
+}
+
+func (s *streamOutput) OnItem(ctx context.Context, item *panyl.Item) bool {
+	if s.err != nil {
+		return false
+	}
+	line, err := json.Marshal(s.document(item))
+	if err != nil {
+		line, _ = json.Marshal(map[string]any{"line_no": item.LineNo,
+			"error": fmt.Sprintf("error encoding item: %s", err)})
+	}
+	s.start()
+	if _, err := s.w.Write(append(line, '\n')); err != nil {
+		// the client went away
+		s.err = err
+		s.cancel()
+		return false
+	}
+	if s.flusher != nil {
+		s.flusher.Flush()
+	}
+	return true
+}
+
+func (s *streamOutput) OnFlush(ctx context.Context) {}
+
+func (s *streamOutput) OnClose(ctx context.Context) {}
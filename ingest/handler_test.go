@@ -0,0 +1,170 @@
+package ingest
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RangelReale/panyl/v2"
+	"github.com/RangelReale/panyl/v2/plugins/structure"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_Stream(t *testing.T) {
+	handler := NewHandler(panyl.NewProcessor(panyl.WithPlugins(&structure.JSON{})))
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	var body bytes.Buffer
+	gw := gzip.NewWriter(&body)
+	_, _ = gw.Write([]byte("{\"level\": \"info\", \"message\": \"started\"}\nplain line\n"))
+	assert.NoError(t, gw.Close())
+
+	req, err := http.NewRequest(http.MethodPost, server.URL, &body)
+	if !assert.NoError(t, err) {
+		return
+	}
+	req.Header.Set("Content-Type", ContentTypeNDJSON)
+	req.Header.Set("Content-Encoding", "gzip")
+	resp, err := http.DefaultClient.Do(req)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, ContentTypeNDJSON, resp.Header.Get("Content-Type"))
+
+	var docs []ItemDocument
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var doc ItemDocument
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &doc))
+		docs = append(docs, doc)
+	}
+	if assert.Len(t, docs, 2) {
+		assert.Equal(t, 1, docs[0].LineNo)
+		assert.Equal(t, panyl.MetadataStructureJSON, docs[0].Metadata[panyl.MetadataStructure])
+		assert.Equal(t, "started", docs[0].Data["message"])
+		assert.Equal(t, "plain line", docs[1].Line)
+	}
+}
+
+func TestHandler_Output(t *testing.T) {
+	res := &panyl.OutputArray{}
+	handler := NewHandler(panyl.NewProcessor(), WithOutput(res))
+
+	for _, tt := range []struct {
+		body     string
+		expected string
+	}{
+		{"first\nsecond\n", `{"items":2}`},
+		{"third\n", `{"items":1}`},
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body)))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, tt.expected, rec.Body.String())
+	}
+	handler.Close(context.Background())
+
+	assert.Len(t, res.List, 3)
+}
+
+func TestHandler_OutputInvalidBody(t *testing.T) {
+	res := &panyl.OutputArray{}
+	handler := NewHandler(panyl.NewProcessor(), WithOutput(res), WithMaxBodySize(10))
+
+	// no item is sent to the output if the body can't be read
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("first\nsecond line\n")))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	handler.Close(context.Background())
+
+	assert.Len(t, res.List, 0)
+}
+
+func TestHandler_StreamClientGone(t *testing.T) {
+	handler := NewHandler(panyl.NewProcessor())
+
+	// the body never ends, so the request only finishes if the Job is cancelled
+	w := &failingResponseWriter{ResponseRecorder: httptest.NewRecorder(), failAfter: 2}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", &infiniteLinesReader{}))
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the request was not cancelled")
+	}
+	assert.Equal(t, 3, w.writes)
+}
+
+// failingResponseWriter fails the writes after failAfter writes, like when the client goes away.
+type failingResponseWriter struct {
+	*httptest.ResponseRecorder
+	failAfter int
+	writes    int
+}
+
+func (w *failingResponseWriter) Write(b []byte) (int, error) {
+	w.writes++
+	if w.writes > w.failAfter {
+		return 0, errors.New("connection closed")
+	}
+	return w.ResponseRecorder.Write(b)
+}
+
+// infiniteLinesReader returns lines forever.
+type infiniteLinesReader struct{}
+
+func (r *infiniteLinesReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = "line\n"[i%5]
+	}
+	return len(p), nil
+}
+
+func TestHandler_Errors(t *testing.T) {
+	handler := NewHandler(panyl.NewProcessor(), WithMaxBodySize(10))
+
+	for _, tt := range []struct {
+		name            string
+		method          string
+		contentType     string
+		contentEncoding string
+		body            string
+		expectedStatus  int
+	}{
+		{"method", http.MethodGet, "", "", "", http.StatusMethodNotAllowed},
+		{"content type", http.MethodPost, "image/png", "", "line", http.StatusUnsupportedMediaType},
+		{"content encoding", http.MethodPost, "text/plain", "br", "line", http.StatusUnsupportedMediaType},
+		{"invalid gzip", http.MethodPost, "text/plain", "gzip", "line", http.StatusBadRequest},
+		{"too large", http.MethodPost, "text/plain; charset=utf-8", "", "a very long line\n",
+			http.StatusRequestEntityTooLarge},
+		{"limit", http.MethodPost, "", "", "123456789\n", http.StatusOK},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			if tt.contentEncoding != "" {
+				req.Header.Set("Content-Encoding", tt.contentEncoding)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+		})
+	}
+}
@@ -1,16 +1,14 @@
 package panyl
 
 import (
-	"fmt"
 	"strings"
-
-	"github.com/imdario/mergo"
 )
 
 // Item is the result of parsing one or more lines
 type Item struct {
 	LineNo    int
 	LineCount int
+	Offset    int64    // byte offset of the first line in the source, only set if the LineProvider supports it
 	Metadata  MapValue // is ALWAYS non-nil
 	Data      MapValue // is ALWAYS non-nil
 	Line      string   // line is the part of the line that might not be parsed
@@ -19,19 +17,42 @@ type Item struct {
 }
 
 // InitItem initializes an empty Item.
+// The Metadata and Data maps are only allocated if they were not set by WithInitMetadata or WithInitData.
 func InitItem(options ...InitItemOption) *Item {
-	ret := &Item{
-		Metadata: MapValue{},
-		Data:     MapValue{},
-	}
+	ret := &Item{}
 	for _, opt := range options {
 		opt(ret)
 	}
+	ret.initMaps()
 	return ret
 }
 
+// initMaps allocates the Metadata and Data maps if they are nil.
+func (p *Item) initMaps() {
+	if p.Metadata == nil {
+		p.Metadata = MapValue{}
+	}
+	if p.Data == nil {
+		p.Data = MapValue{}
+	}
+}
+
 type InitItemOption func(p *Item)
 
+// WithInitMetadata sets Item.Metadata, avoiding allocating a new map. The Item takes ownership of the map.
+func WithInitMetadata(metadata MapValue) InitItemOption {
+	return func(p *Item) {
+		p.Metadata = metadata
+	}
+}
+
+// WithInitData sets Item.Data, avoiding allocating a new map. The Item takes ownership of the map.
+func WithInitData(data MapValue) InitItemOption {
+	return func(p *Item) {
+		p.Data = data
+	}
+}
+
 // WithInitLineNo sets Item.LineNo.
 func WithInitLineNo(lineNo int) InitItemOption {
 	return func(p *Item) {
@@ -60,30 +81,22 @@ func WithInitSource(source string) InitItemOption {
 	}
 }
 
-// WithInitCustom calls a callback to initialize a Item.
+// WithInitCustom calls a callback to initialize a Item. The Metadata and Data maps are allocated before the call.
 func WithInitCustom(f func(*Item)) InitItemOption {
 	return func(p *Item) {
+		p.initMaps()
 		f(p)
 	}
 }
 
-func (p *Item) mergeData(other *Item) error {
-	if err := mergo.Map(&p.Metadata, other.Metadata); err != nil {
-		return fmt.Errorf("Error merging structs: %v", err)
-	}
-	if err := mergo.Map(&p.Data, other.Data); err != nil {
-		return fmt.Errorf("Error merging structs: %v", err)
-	}
-	return nil
-}
-
-// MergeLinesData merges the Metadata and Data maps of a list of Item
+// MergeLinesData merges the Metadata and Data maps of a list of Item, without overwriting non-empty values.
 func (p *Item) MergeLinesData(lines ItemLines) error {
 	for _, line := range lines {
-		err := p.mergeData(line)
-		if err != nil {
-			return err
+		if line == p {
+			continue
 		}
+		p.Metadata.Merge(line.Metadata)
+		p.Data.Merge(line.Data)
 	}
 	return nil
 }
@@ -95,6 +108,7 @@ func (p *Item) Clone() (*Item, error) {
 	}
 	item.LineNo = p.LineNo
 	item.LineCount = p.LineCount
+	item.Offset = p.Offset
 	item.Source = p.Source
 	item.RawSource = p.RawSource
 	return item, nil
@@ -103,15 +117,11 @@ func (p *Item) Clone() (*Item, error) {
 func (p *Item) CloneData() (*Item, error) {
 	ret := &Item{
 		Line:     p.Line,
-		Metadata: map[string]any{},
-		Data:     map[string]any{},
-	}
-	if err := mergo.Map(&ret.Metadata, p.Metadata); err != nil {
-		return nil, fmt.Errorf("error merging structs: %w", err)
-	}
-	if err := mergo.Map(&ret.Data, p.Data); err != nil {
-		return nil, fmt.Errorf("error merging structs: %w", err)
+		Metadata: make(MapValue, len(p.Metadata)),
+		Data:     make(MapValue, len(p.Data)),
 	}
+	ret.Metadata.Merge(p.Metadata)
+	ret.Data.Merge(p.Data)
 	return ret, nil
 }
 
@@ -120,7 +130,7 @@ type ItemLines []*Item
 
 // Lines returns a list of all lines from each Item.
 func (pl ItemLines) Lines() []string {
-	var ret []string
+	ret := make([]string, 0, len(pl))
 	for _, p := range pl {
 		ret = append(ret, p.Line)
 	}
@@ -129,12 +139,12 @@ func (pl ItemLines) Lines() []string {
 
 // Line returns a list of all lines from each Item joined with "\n".
 func (pl ItemLines) Line() string {
-	return strings.Join(pl.Lines(), "\n")
+	return pl.join(func(p *Item) string { return p.Line })
 }
 
 // Sources returns a list of all sources from each Item.
 func (pl ItemLines) Sources() []string {
-	var ret []string
+	ret := make([]string, 0, len(pl))
 	for _, p := range pl {
 		ret = append(ret, p.Source)
 	}
@@ -143,5 +153,27 @@ func (pl ItemLines) Sources() []string {
 
 // Source returns a list of all sources from each Item joined with "\n".
 func (pl ItemLines) Source() string {
-	return strings.Join(pl.Sources(), "\n")
+	return pl.join(func(p *Item) string { return p.Source })
+}
+
+func (pl ItemLines) join(f func(p *Item) string) string {
+	switch len(pl) {
+	case 0:
+		return ""
+	case 1:
+		return f(pl[0])
+	}
+	size := len(pl) - 1
+	for _, p := range pl {
+		size += len(f(p))
+	}
+	var b strings.Builder
+	b.Grow(size)
+	for i, p := range pl {
+		if i > 0 {
+			_ = b.WriteByte('\n')
+		}
+		_, _ = b.WriteString(f(p))
+	}
+	return b.String()
 }
@@ -0,0 +1,24 @@
+package panyl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInitItem(t *testing.T) {
+	data := MapValue{"a": 1}
+	item := InitItem(WithInitData(data), WithInitLine("line"))
+	assert.Equal(t, MapValue{}, item.Metadata)
+	assert.Equal(t, data, item.Data)
+	item.Data["b"] = 2
+	assert.Equal(t, 2, data["b"])
+
+	// the maps are allocated before calling the custom callback
+	item = InitItem(WithInitCustom(func(p *Item) {
+		p.Metadata[MetadataMessage] = "message"
+		p.Data["a"] = 1
+	}))
+	assert.Equal(t, MapValue{MetadataMessage: "message"}, item.Metadata)
+	assert.Equal(t, MapValue{"a": 1}, item.Data)
+}
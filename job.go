@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -19,16 +21,50 @@ type Job struct {
 	lastTime                time.Time
 	lines                   ItemLines
 	sortedPluginPostProcess []PluginPostProcess
+	debugLogTrace           DebugLogTrace
+	timeRangeFinished       bool
+	linesRead               int
+	structureBoundary       []PluginBoundary
+	parseBoundary           []PluginBoundary
+	structureIncomplete     []PluginIncomplete
+	incomplete              IncompleteScanner // scanner of the incomplete structure reserving the backlog lines
+	incompleteStart         int               // backlog index of the first line of the incomplete structure
+	free                    ItemLines         // items of lines merged into other items, reused by initItem
+	finishCreated           bool              // whether the PluginCreateFinish items were output
+	concurrentClean         int
+	concurrentMetadata      int
 	m                       sync.Mutex
 
-	StartLine       int
-	LineAmount      int
-	IncludeSource   bool
-	MaxBacklogLines int
+	StartLine                 int
+	LineAmount                int
+	IncludeSource             bool
+	MaxBacklogLines           int
+	MaxIncompleteBacklogLines int
+	ErrorPolicy               ErrorPolicy
+	OnPluginError             func(context.Context, *PluginError)
+	TimeFrom                  time.Time
+	TimeTo                    time.Time
+	TimeTolerance             time.Duration
+	Reverse                   bool
+	Concurrency               int
 }
 
 var ErrFinished = errors.New("finished")
 
+type jobContextKey struct{}
+
+// JobToContext returns a context with the Job, which the plugins can get using JobFromContext.
+// Processor.ProcessProvider adds its Job to the context automatically.
+func JobToContext(ctx context.Context, job *Job) context.Context {
+	return context.WithValue(ctx, jobContextKey{}, job)
+}
+
+// JobFromContext returns the Job processing the items, or nil if the context doesn't have one.
+func JobFromContext(ctx context.Context) *Job {
+	job, _ := ctx.Value(jobContextKey{}).(*Job)
+	return job
+}
+
 // NewJob manages processing lines and detecting information from them.
 func NewJob(processor *Processor, output Output, options ...JobOption) *Job {
 	ret := &Job{
@@ -38,6 +74,17 @@ func NewJob(processor *Processor, output Output, options ...JobOption) *Job {
 
 		MaxBacklogLines: 50,
 	}
+	if dt, ok := processor.DebugLog.(DebugLogTrace); ok {
+		ret.debugLogTrace = dt
+	}
+	ret.structureBoundary = getPluginBoundaries(processor.pluginStructure)
+	ret.parseBoundary = getPluginBoundaries(processor.pluginParse)
+	for _, plugin := range processor.pluginStructure {
+		if pi, ok := plugin.(PluginIncomplete); ok {
+			ret.structureIncomplete = append(ret.structureIncomplete, pi)
+		}
+	}
+	ret.concurrentClean, ret.concurrentMetadata = getConcurrentPluginCount(processor)
 	for _, o := range options {
 		o(ret)
 	}
@@ -45,92 +92,279 @@ func NewJob(processor *Processor, output Output, options ...JobOption) *Job {
 }
 
 // ProcessLine adds a line to be processed. line can be `string` or `ProcessItem`.
+// In reverse mode, line numbers are counted backwards as negative numbers, -1 being the last line.
 func (p *Job) ProcessLine(ctx context.Context, line any) error {
 	p.m.Lock()
 	defer p.m.Unlock()
 
-	p.lineno++
+	return p.processLine(ctx, line, p.nextLineNo(p.lineno), 0)
+}
+
+// ProcessLineAt adds a line to be processed, informing its line number and byte offset in the source.
+// Following calls to ProcessLine will continue counting from lineNo.
+func (p *Job) ProcessLineAt(ctx context.Context, line any, lineNo int, offset int64) error {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	return p.processLine(ctx, line, lineNo, offset)
+}
+
+// seekStartLine positions the LineProvider at the start line of the line limit, if it supports seeking and is before
+// it.
+func (p *Job) seekStartLine(scanner LineProvider) error {
+	if p.Reverse || p.LineAmount <= 0 {
+		return nil
+	}
+	seek, ok := scanner.(LineProviderSeekLine)
+	if !ok || seek.LineNo()+1 >= p.StartLine {
+		return nil
+	}
+	return seek.SeekLine(p.StartLine)
+}
+
+func (p *Job) nextLineNo(lineno int) int {
+	if p.Reverse {
+		return lineno - 1
+	}
+	return lineno + 1
+}
+
+// jobLine is a line being processed by the Job.
+type jobLine struct {
+	lineno       int
+	process      *Item
+	sourceLine   string // source line for DebugLog
+	cleanLine    string // line after the Clean plugins, for DebugLog
+	cleanDone    int    // amount of Clean plugins already called
+	metadataDone int    // amount of Metadata plugins already called
+	trimmed      bool
+	sourceLogged bool
+	structured   bool  // the line was an *Item with MetadataStructure set
+	err          error // error of the concurrent pre-processing
+}
+
+func (p *Job) processLine(ctx context.Context, line any, lineno int, offset int64) error {
+	if p.timeRangeFinished {
+		return ErrFinished
+	}
+
+	p.lineno = lineno
+
+	jl, err := p.startLine(ctx, line, lineno, offset)
+	if err != nil || jl == nil {
+		return err
+	}
+	if err := p.preProcessLine(ctx, jl, false); err != nil {
+		return err
+	}
+	return p.processItem(ctx, jl)
+}
+
+// startLine checks the line limits and creates the Item for the line. If the line must be skipped, nil is
+// returned.
+func (p *Job) startLine(ctx context.Context, line any, lineno int, offset int64) (*jobLine, error) {
+	p.linesRead++
 
 	if p.LineAmount > 0 {
-		if p.lineno < p.StartLine {
-			return nil
+		// in reverse mode, the limit applies to the amount of lines read
+		limitLineno := lineno
+		if p.Reverse {
+			limitLineno = p.linesRead
 		}
-		if p.lineno > p.StartLine+p.LineAmount {
-			return ErrFinished
+		if limitLineno < p.StartLine {
+			return nil, nil
+		}
+		if limitLineno > p.StartLine+p.LineAmount {
+			return nil, ErrFinished
 		}
 	}
 
 	// read line from LineProvider
-	var sourceLine string
-	var process *Item
+	jl := &jobLine{lineno: lineno}
 	switch l := line.(type) {
 	case string:
-		process = p.initItem(p.lineno, l)
-		sourceLine = l
+		jl.process = p.initItem(lineno, l)
+		jl.process.Offset = offset
+		jl.sourceLine = l
 	case *Item:
-		process = l
-		process.LineNo = p.lineno
-		p.ensureItem(process)
+		jl.process = l
+		jl.process.LineNo = lineno
+		jl.process.Offset = offset
+		p.ensureItem(jl.process)
+		jl.structured = jl.process.Metadata.HasValue(MetadataStructure)
 
 		if p.processor.DebugLog != nil {
 			// encode source line for Logger
-			sourceLineBytes, err := json.Marshal(process.Data)
+			sourceLineBytes, err := json.Marshal(jl.process.Data)
 			if err == nil {
+				jl.sourceLine = string(sourceLineBytes)
+			} else {
 				// ignore errors
-				sourceLine = string(sourceLineBytes)
+				SLogFromContext(ctx).WarnContext(ctx, "error encoding item data for DebugLog",
+					slog.Int("line", lineno), slog.Any("error", err))
 			}
 		}
+	default:
+		return nil, fmt.Errorf("invalid line type %T", line)
+	}
+	return jl, nil
+}
+
+// preProcessLine calls the Clean and Metadata plugins which were not called yet. If concurrent is true, only the
+// plugins which are safe to be called concurrently are called.
+func (p *Job) preProcessLine(ctx context.Context, jl *jobLine, concurrent bool) error {
+	process := jl.process
+
+	cleanLimit, metadataLimit := len(p.processor.pluginClean), len(p.processor.pluginMetadata)
+	if concurrent {
+		cleanLimit, metadataLimit = p.concurrentClean, p.concurrentMetadata
 	}
 
 	// PROCESS: Clean
-	for _, pclean := range p.processor.pluginClean {
-		_, err := pclean.Clean(ctx, process)
+	for ; jl.cleanDone < cleanLimit; jl.cleanDone++ {
+		pclean := p.processor.pluginClean[jl.cleanDone]
+		_, err := p.callPlugin(ctx, pclean, PluginPhaseClean, process, func() error {
+			_, err := pclean.Clean(ctx, process)
+			return err
+		})
 		if err != nil {
 			return err
 		}
 	}
+	if jl.cleanDone < len(p.processor.pluginClean) {
+		return nil
+	}
 
-	// PROCESS: Trim spaces
-	process.Line = strings.TrimSpace(process.Line)
+	if !jl.trimmed {
+		// PROCESS: Trim spaces
+		process.Line = strings.TrimSpace(process.Line)
+		jl.cleanLine = process.Line
+		jl.trimmed = true
+	}
 	// skip empty lines
-	if len(process.Line) == 0 {
+	if len(jl.cleanLine) == 0 && !jl.structured {
 		return nil
 	}
 
 	// DebugLog source line
-	if p.processor.DebugLog != nil {
-		p.processor.DebugLog.LogSourceLine(ctx, p.lineno, process.Line, sourceLine)
+	if !concurrent && !jl.sourceLogged {
+		if p.processor.DebugLog != nil {
+			p.processor.DebugLog.LogSourceLine(ctx, jl.lineno, jl.cleanLine, jl.sourceLine)
+		}
+		jl.sourceLogged = true
 	}
 
 	// PROCESS: Extract metadata
-	for _, pmetadata := range p.processor.pluginMetadata {
-		_, err := pmetadata.ExtractMetadata(ctx, process)
+	for ; jl.metadataDone < metadataLimit; jl.metadataDone++ {
+		pmetadata := p.processor.pluginMetadata[jl.metadataDone]
+		_, err := p.callPlugin(ctx, pmetadata, PluginPhaseMetadata, process, func() error {
+			_, err := pmetadata.ExtractMetadata(ctx, process)
+			return err
+		})
 		if err != nil {
 			return err
 		}
 	}
 
+	return nil
+}
+
+// processItem adds the pre-processed line to the backlog and tries to detect items from it.
+func (p *Job) processItem(ctx context.Context, jl *jobLine) error {
+	process := jl.process
+
+	// skip empty lines
+	if len(jl.cleanLine) == 0 && !jl.structured {
+		return nil
+	}
+
 	if p.IncludeSource {
 		// source with Clean and Metadata plugins applied
 		process.Source = process.Line
 	}
 
-	// add current process to lines
-	p.lines = append(p.lines, process)
+	if jl.structured {
+		return p.processStructuredItem(ctx, process)
+	}
+
+	if err := p.processBacklogItems(ctx, ItemLines{process}); err != nil {
+		return err
+	}
+
+	if p.timeRangeFinished {
+		return ErrFinished
+	}
+
+	return nil
+}
+
+// processBacklogItems processes the items in order, including the lines released from dropped incomplete
+// structures.
+func (p *Job) processBacklogItems(ctx context.Context, pending ItemLines) error {
+	for len(pending) > 0 {
+		released, err := p.processBacklogItem(ctx, pending[0])
+		if err != nil {
+			return err
+		}
+		pending = append(released, pending[1:]...)
+	}
+	return nil
+}
+
+// processBacklogItem adds the item to the backlog and tries to detect items from it. If the item drops the
+// reservation of an incomplete structure whose lines didn't match, the reserved lines after the first one are
+// removed from the backlog and returned to be processed again.
+func (p *Job) processBacklogItem(ctx context.Context, process *Item) (ItemLines, error) {
+	// add current process to lines. The lines are always kept in the source order, so in reverse mode the
+	// current line is the first one.
+	if p.Reverse {
+		p.lines = append(ItemLines{process}, p.lines...)
+	} else {
+		p.lines = append(p.lines, process)
+	}
 
 	lineProcessed := false
-	var lineFound int = -1
+	var lineFound ItemLines
+
+	// the lines of an incomplete structure are reserved for it, so shorter candidates are not checked
+	minAmount := 1
+	dropped := -1
+	if p.incomplete != nil {
+		minAmount = len(p.lines) - p.incompleteStart
+		if !p.incomplete.ScanLine(ctx, process) {
+			// the structure was completed or is invalid, the reservation is only valid for this line
+			dropped = p.incompleteStart
+			p.incomplete = nil
+		}
+	} else {
+		p.findIncomplete(ctx, len(p.lines)-1)
+	}
 
 	// PROCESS: Extract structure from line
-	// loop bottom lines until a match is found
+	// loop lines from the current one until a match is found
 structureloop:
-	for curline := len(p.lines) - 1; curline >= 0; curline-- {
-		for _, pstructure := range p.processor.pluginStructure {
-			if ok, err := pstructure.ExtractStructure(ctx, p.lines[curline:], process); err != nil {
+	for amount := minAmount; amount <= len(p.lines); amount++ {
+		candidate := p.backlogCandidate(amount)
+		for pidx, pstructure := range p.processor.pluginStructure {
+			if !candidateInBoundary(ctx, p.structureBoundary[pidx], candidate) {
+				continue
+			}
+			var ok bool
+			skip, err := p.callPlugin(ctx, pstructure, PluginPhaseStructure, process, func() (err error) {
+				ok, err = pstructure.ExtractStructure(ctx, candidate, process)
 				return err
-			} else if ok {
+			})
+			if err != nil {
+				return nil, err
+			} else if skip {
+				continue
+			}
+			if p.debugLogTrace != nil {
+				p.debugLogTrace.LogStructure(ctx, p.lineno, pstructure, candidate, ok)
+			}
+			if ok {
 				lineProcessed = true
-				lineFound = curline
+				lineFound = candidate
 				// line structure can be found only once
 				break structureloop
 			}
@@ -140,13 +374,28 @@ structureloop:
 	// PROCESS: Parse line
 	if !lineProcessed {
 	lineloop:
-		for curline := len(p.lines) - 1; curline >= 0; curline-- {
-			for _, pparse := range p.processor.pluginParse {
-				if ok, err := pparse.ExtractParse(ctx, p.lines[curline:], process); err != nil {
+		for amount := minAmount; amount <= len(p.lines); amount++ {
+			candidate := p.backlogCandidate(amount)
+			for pidx, pparse := range p.processor.pluginParse {
+				if !candidateInBoundary(ctx, p.parseBoundary[pidx], candidate) {
+					continue
+				}
+				var ok bool
+				skip, err := p.callPlugin(ctx, pparse, PluginPhaseParse, process, func() (err error) {
+					ok, err = pparse.ExtractParse(ctx, candidate, process)
 					return err
-				} else if ok {
+				})
+				if err != nil {
+					return nil, err
+				} else if skip {
+					continue
+				}
+				if p.debugLogTrace != nil {
+					p.debugLogTrace.LogParse(ctx, p.lineno, pparse, candidate, ok)
+				}
+				if ok {
 					lineProcessed = true
-					lineFound = curline
+					lineFound = candidate
 					// line parser can be found only once
 					break lineloop
 				}
@@ -155,35 +404,61 @@ structureloop:
 	}
 
 	if lineProcessed {
-		process.LineNo = p.lines[lineFound].LineNo
-		process.LineCount = len(p.lines) - lineFound
+		process.LineNo = lineFound[0].LineNo
+		process.LineCount = len(lineFound)
+		process.Offset = lineFound[0].Offset
 		if p.IncludeSource {
-			process.Source = ItemLines(p.lines[lineFound:]).Source()
+			process.Source = lineFound.Source()
 		}
 		if p.lastTime.IsZero() {
 			// try to get the timestamp from the processed line if time is Zero
 			if pts, ok := process.Metadata[MetadataTimestamp]; ok {
-				p.lastTime = pts.(time.Time)
+				if ts, ok := pts.(time.Time); ok {
+					p.lastTime = ts
+				} else {
+					SLogFromContext(ctx).WarnContext(ctx, "invalid timestamp metadata type",
+						slog.Int("line", process.LineNo), slog.String("type", fmt.Sprintf("%T", pts)))
+				}
 			}
 		}
 		// process previous lines
 		var err error
-		_, err = p.processResultLines(ctx, p.lines[:lineFound], p.output, p.lastTime, p.sortedPluginPostProcess)
+		_, err = p.processResultLines(ctx, p.backlogRemaining(len(lineFound)), p.output, p.lastTime,
+			p.sortedPluginPostProcess)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		// process current line
 		p.lastTime, err = p.outputItem(ctx, process, p.output, p.lastTime, p.sortedPluginPostProcess)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		p.lines = nil
+		p.recycleItems(lineFound, process)
+		p.resetBacklog()
+	} else if dropped >= 0 {
+		// the reserved lines are not a structure, process them again without the reservation
+		return p.releaseBacklog(dropped), nil
 	} else {
 		if len(p.lines) > 1 {
-			// check if there is any sequence block in the last 2 lines
+			// check if there is any sequence block in the current and the adjacent line
+			lastp, item := p.lines[len(p.lines)-2], p.lines[len(p.lines)-1]
+			if p.Reverse {
+				lastp, item = p.lines[0], p.lines[1]
+			}
 			blockSequence := false
 			for _, psequence := range p.processor.pluginSequence {
-				if bseq := psequence.BlockSequence(ctx, p.lines[len(p.lines)-2], p.lines[len(p.lines)-1]); bseq {
+				var bseq bool
+				_, err := p.callPlugin(ctx, psequence, PluginPhaseSequence, process, func() error {
+					bseq = psequence.BlockSequence(ctx, lastp, item)
+					return nil
+				})
+				if err != nil {
+					return nil, err
+				}
+				if bseq {
+					if p.debugLogTrace != nil {
+						p.debugLogTrace.LogSequenceBlock(ctx, p.lineno, psequence, lastp, item)
+					}
 					blockSequence = true
 					break
 				}
@@ -192,35 +467,127 @@ structureloop:
 			if blockSequence {
 				// process previous lines and leave only the current line
 				var err error
-				p.lastTime, err = p.processResultLines(ctx, p.lines[:len(p.lines)-1], p.output, p.lastTime, p.sortedPluginPostProcess)
+				p.lastTime, err = p.processResultLines(ctx, p.backlogRemaining(1), p.output, p.lastTime, p.sortedPluginPostProcess)
 				if err != nil {
-					return err
+					return nil, err
 				}
-				p.lines = ItemLines{p.lines[len(p.lines)-1]}
+				p.resetBacklog()
+				p.lines = append(p.lines, process)
+				p.findIncomplete(ctx, 0)
 			}
 		}
 	}
 
+	var released ItemLines
 	if len(p.lines) > p.MaxBacklogLines {
-		var err error
-		p.lastTime, err = p.processResultLines(ctx, p.lines, p.output, p.lastTime, p.sortedPluginPostProcess)
-		if err != nil {
-			return err
+		// keep the lines of an incomplete structure in the backlog, up to MaxIncompleteBacklogLines
+		if p.incomplete != nil && len(p.lines)-p.incompleteStart > p.MaxIncompleteBacklogLines {
+			// the structure is too long, process its lines again without the reservation
+			released = p.releaseBacklog(p.incompleteStart)
+		}
+		keep := -1
+		if p.incomplete != nil {
+			keep = p.incompleteStart
+		}
+		flush := p.lines
+		if keep >= 0 {
+			flush = p.lines[:keep]
+		}
+		if len(flush) > 0 {
+			if p.debugLogTrace != nil {
+				p.debugLogTrace.LogBacklogOverflow(ctx, p.lineno, flush)
+			}
+			var err error
+			p.lastTime, err = p.processResultLines(ctx, flush, p.output, p.lastTime, p.sortedPluginPostProcess)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if keep > 0 {
+			n := copy(p.lines, p.lines[keep:])
+			clear(p.lines[n:])
+			p.lines = p.lines[:n]
+			p.incompleteStart = 0
+		} else if keep < 0 {
+			p.resetBacklog()
 		}
-		p.lines = nil
 	}
 
-	return nil
+	return released, nil
 }
 
-func (p *Job) Finish(ctx context.Context) error {
-	if len(p.lines) > 0 {
-		// process any lines left
-		_, err := p.processResultLines(ctx, p.lines, p.output, p.lastTime, p.sortedPluginPostProcess)
-		if err != nil {
-			return err
+// backlogCandidate returns the amount of backlog lines nearest to the current line, in source order.
+func (p *Job) backlogCandidate(amount int) ItemLines {
+	if p.Reverse {
+		return p.lines[:amount]
+	}
+	return p.lines[len(p.lines)-amount:]
+}
+
+// backlogRemaining returns the backlog lines not returned by backlogCandidate(amount), in source order.
+func (p *Job) backlogRemaining(amount int) ItemLines {
+	if p.Reverse {
+		return p.lines[amount:]
+	}
+	return p.lines[:len(p.lines)-amount]
+}
+
+// findIncomplete searches the backlog from the line at index from for the start of a structure which is still
+// incomplete at the last line, reserving the lines for it. Nothing is done if MaxIncompleteBacklogLines is not
+// enabled.
+func (p *Job) findIncomplete(ctx context.Context, from int) {
+	p.incomplete = nil
+	if p.Reverse || p.MaxIncompleteBacklogLines <= p.MaxBacklogLines || len(p.structureIncomplete) == 0 {
+		return
+	}
+	for start := from; start < len(p.lines); start++ {
+		for _, pi := range p.structureIncomplete {
+			if pb, ok := pi.(PluginBoundary); ok && !pb.CanStart(ctx, p.lines[start]) {
+				continue
+			}
+			scanner := pi.StartIncomplete(ctx, p.lines[start])
+			if scanner == nil {
+				continue
+			}
+			incomplete := true
+			for _, line := range p.lines[start+1:] {
+				if !scanner.ScanLine(ctx, line) {
+					incomplete = false
+					break
+				}
+			}
+			if incomplete {
+				p.incomplete, p.incompleteStart = scanner, start
+				return
+			}
 		}
 	}
+}
+
+// releaseBacklog drops the incomplete structure reservation, removing the backlog lines after the line at index
+// start and returning them to be processed again.
+func (p *Job) releaseBacklog(start int) ItemLines {
+	released := slices.Clone(p.lines[start+1:])
+	clear(p.lines[start+1:])
+	p.lines = p.lines[:start+1]
+	p.incomplete = nil
+	return released
+}
+
+// resetBacklog clears the backlog, keeping the allocated slice.
+func (p *Job) resetBacklog() {
+	clear(p.lines)
+	p.lines = p.lines[:0]
+	p.incomplete = nil
+}
+
+func (p *Job) Finish(ctx context.Context) error {
+	if err := p.flushBacklog(ctx); err != nil {
+		return err
+	}
+	if err := p.createFinish(ctx); err != nil {
+		return err
+	}
 
 	// allows output flushing, like flushing network connections
 	p.output.OnFlush(ctx)
@@ -231,12 +598,104 @@ func (p *Job) Finish(ctx context.Context) error {
 	return nil
 }
 
+// processStructuredItem outputs an item which was received already structured, without checking the structure and
+// parse plugins. The lines in the backlog are output before it.
+func (p *Job) processStructuredItem(ctx context.Context, process *Item) error {
+	if len(p.lines) > 0 {
+		var err error
+		p.lastTime, err = p.processResultLines(ctx, p.lines, p.output, p.lastTime, p.sortedPluginPostProcess)
+		if err != nil {
+			return err
+		}
+		p.resetBacklog()
+	}
+
+	if process.LineCount == 0 {
+		process.LineCount = 1
+	}
+	var err error
+	p.lastTime, err = p.outputItem(ctx, process, p.output, p.lastTime, p.sortedPluginPostProcess)
+	if err != nil {
+		return err
+	}
+
+	if p.timeRangeFinished {
+		return ErrFinished
+	}
+	return nil
+}
+
+// flushBacklog outputs any lines left in the backlog.
+func (p *Job) flushBacklog(ctx context.Context) error {
+	p.m.Lock()
+	defer p.m.Unlock()
+	if p.incomplete != nil {
+		// the structure was never completed, process its lines again without the reservation
+		if err := p.processBacklogItems(ctx, p.releaseBacklog(p.incompleteStart)); err != nil {
+			return err
+		}
+	}
+	if len(p.lines) == 0 {
+		return nil
+	}
+	_, err := p.processResultLines(ctx, p.lines, p.output, p.lastTime, p.sortedPluginPostProcess)
+	p.resetBacklog()
+	return err
+}
+
+// createFinish outputs the items created by the PluginCreateFinish plugins, only once.
+func (p *Job) createFinish(ctx context.Context) error {
+	p.m.Lock()
+	defer p.m.Unlock()
+	if p.finishCreated {
+		return nil
+	}
+	p.finishCreated = true
+	for _, pp := range p.processor.pluginCreate {
+		pf, ok := pp.(PluginCreateFinish)
+		if !ok {
+			continue
+		}
+		var items []*Item
+		skip, err := p.callPlugin(ctx, pp, PluginPhaseCreate, nil, func() (err error) {
+			items, err = pf.CreateFinish(ctx)
+			return err
+		})
+		if err != nil {
+			return err
+		} else if skip {
+			continue
+		}
+		for _, item := range items {
+			item.Metadata[MetadataCreated] = true
+			if _, err := p.internalOutputItem(ctx, item, p.output, p.lastTime, false,
+				p.sortedPluginPostProcess); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func (p *Job) initItem(lineno int, line string) *Item {
-	ret := &Item{
-		LineNo:   lineno,
-		Metadata: map[string]interface{}{},
-		Data:     map[string]interface{}{},
-		Line:     line,
+	var ret *Item
+	if n := len(p.free); n > 0 {
+		ret = p.free[n-1]
+		p.free[n-1] = nil
+		p.free = p.free[:n-1]
+		*ret = Item{
+			LineNo:   lineno,
+			Metadata: ret.Metadata,
+			Data:     ret.Data,
+			Line:     line,
+		}
+	} else {
+		ret = &Item{
+			LineNo:   lineno,
+			Metadata: map[string]interface{}{},
+			Data:     map[string]interface{}{},
+			Line:     line,
+		}
 	}
 	if p.IncludeSource {
 		ret.RawSource = line
@@ -244,6 +703,32 @@ func (p *Job) initItem(lineno int, line string) *Item {
 	return ret
 }
 
+// recycleItems keeps the items of lines which were merged into the item, so initItem can reuse them and their
+// maps instead of allocating new ones. At most MaxBacklogLines items are kept.
+func (p *Job) recycleItems(lines ItemLines, item *Item) {
+	for _, line := range lines {
+		if len(p.free) >= p.MaxBacklogLines {
+			return
+		}
+		if line == item {
+			continue
+		}
+		clear(line.Metadata)
+		clear(line.Data)
+		p.free = append(p.free, line)
+	}
+}
+
+func (p *Job) resetItem(process *Item, lineno int) {
+	clear(process.Metadata)
+	clear(process.Data)
+	*process = Item{
+		LineNo:   lineno,
+		Metadata: process.Metadata,
+		Data:     process.Data,
+	}
+}
+
 func (p *Job) ensureItem(process *Item) {
 	if process.Metadata == nil {
 		process.Metadata = map[string]interface{}{}
@@ -260,26 +745,61 @@ func (p *Job) ensureItem(process *Item) {
 func (p *Job) processResultLines(ctx context.Context, lines ItemLines, output Output, lastTime time.Time,
 	sortedPluginPostProcess []PluginPostProcess) (time.Time, error) {
 	var rts = lastTime
+	// in reverse mode the items are output after consolidation, from the last to the first
+	var reverseItems []*Item
+	emit := func(item *Item) error {
+		if p.Reverse {
+			reverseItems = append(reverseItems, item)
+			return nil
+		}
+		var err error
+		rts, err = p.outputItem(ctx, item, output, rts, sortedPluginPostProcess)
+		return err
+	}
+
+	var consolidateProcess *Item
 	startLine := 0
 	for startLine < len(lines) {
 		processed := false
 		for _, pc := range p.processor.pluginConsolidate {
-			consolidateProcess := p.initItem(lines[startLine].LineNo, "")
-			if ok, topLines, err := pc.Consolidate(ctx, lines[startLine:], consolidateProcess); err != nil {
-				return time.Time{}, err
-			} else if ok {
-				if topLines > len(lines)-startLine {
-					return time.Time{}, fmt.Errorf("Plugin requestd %d top lines but only %d are available", topLines, len(lines)-startLine)
+			if consolidateProcess == nil {
+				consolidateProcess = p.initItem(lines[startLine].LineNo, "")
+			} else {
+				// reuse the item from the previous unsuccessful call
+				p.resetItem(consolidateProcess, lines[startLine].LineNo)
+			}
+			consolidateProcess.Offset = lines[startLine].Offset
+			var ok bool
+			var topLines int
+			// errors are recorded on the source line, as consolidateProcess is only output if the plugin succeeds
+			skip, err := p.callPlugin(ctx, pc, PluginPhaseConsolidate, lines[startLine], func() (err error) {
+				ok, topLines, err = pc.Consolidate(ctx, lines[startLine:], consolidateProcess)
+				if err == nil && ok && topLines > len(lines)-startLine {
+					return fmt.Errorf("Plugin requestd %d top lines but only %d are available", topLines, len(lines)-startLine)
 				}
-
+				return err
+			})
+			if err != nil {
+				return time.Time{}, err
+			} else if skip {
+				continue
+			}
+			if p.debugLogTrace != nil {
+				p.debugLogTrace.LogConsolidate(ctx, p.lineno, pc, lines[startLine:], ok, topLines)
+			}
+			if ok {
 				consolidateProcess.LineCount = topLines
+				for _, perr := range lines[startLine].Metadata.ListValue(MetadataErrors) {
+					consolidateProcess.Metadata.ListValueAdd(MetadataErrors, perr)
+				}
 				if p.IncludeSource {
 					consolidateProcess.Source = ItemLines(lines[startLine : startLine+topLines]).Source()
 				}
-				rts, err = p.outputItem(ctx, consolidateProcess, output, rts, sortedPluginPostProcess)
-				if err != nil {
+				if err := emit(consolidateProcess); err != nil {
 					return time.Time{}, err
 				}
+				p.recycleItems(lines[startLine:startLine+topLines], consolidateProcess)
+				consolidateProcess = nil
 				startLine += topLines
 				processed = true
 				break
@@ -287,14 +807,19 @@ func (p *Job) processResultLines(ctx context.Context, lines ItemLines, output Ou
 		}
 		if !processed {
 			lines[startLine].LineCount = 1
-			var err error
-			rts, err = p.outputItem(ctx, lines[startLine], output, rts, sortedPluginPostProcess)
-			if err != nil {
+			if err := emit(lines[startLine]); err != nil {
 				return time.Time{}, err
 			}
 			startLine++
 		}
 	}
+	for i := len(reverseItems) - 1; i >= 0; i-- {
+		var err error
+		rts, err = p.outputItem(ctx, reverseItems[i], output, rts, sortedPluginPostProcess)
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
 	return rts, nil
 }
 
@@ -304,10 +829,20 @@ func (p *Job) outputItem(ctx context.Context, process *Item, output Output, last
 	// if no format was detected, call the ParseFormat plugins
 	if _, ok := process.Metadata[MetadataFormat]; !ok {
 		for _, pp := range p.processor.pluginParseFormat {
-			ok, err := pp.ParseFormat(ctx, process)
+			var ok bool
+			skip, err := p.callPlugin(ctx, pp, PluginPhaseParseFormat, process, func() (err error) {
+				ok, err = pp.ParseFormat(ctx, process)
+				return err
+			})
 			if err != nil {
 				return time.Time{}, err
-			} else if ok {
+			} else if skip {
+				continue
+			}
+			if p.debugLogTrace != nil {
+				p.debugLogTrace.LogParseFormat(ctx, p.lineno, pp, process, ok)
+			}
+			if ok {
 				break
 			}
 		}
@@ -320,7 +855,10 @@ func (p *Job) outputItem(ctx context.Context, process *Item, output Output, last
 func (p *Job) internalOutputItem(ctx context.Context, process *Item, output Output, lastTime time.Time, create bool,
 	sortedPluginPostProcess []PluginPostProcess) (time.Time, error) {
 	for _, pp := range sortedPluginPostProcess {
-		_, err := pp.PostProcess(ctx, process)
+		_, err := p.callPlugin(ctx, pp, PluginPhasePostProcess, process, func() error {
+			_, err := pp.PostProcess(ctx, process)
+			return err
+		})
 		if err != nil {
 			return time.Time{}, err
 		}
@@ -328,34 +866,45 @@ func (p *Job) internalOutputItem(ctx context.Context, process *Item, output Outp
 
 	retTime := lastTime
 	// check for timestamp in metadata, add the last one if not available
-	if _, ok := process.Metadata[MetadataTimestamp]; !ok {
+	if pts, ok := process.Metadata[MetadataTimestamp]; !ok {
 		if lastTime.IsZero() {
 			process.Metadata[MetadataTimestamp] = time.Now()
 		} else {
 			process.Metadata[MetadataTimestamp] = lastTime
 		}
 		process.Metadata[MetadataTimestampCalculated] = true
+	} else if ts, ok := pts.(time.Time); ok {
+		retTime = ts
 	} else {
-		retTime = process.Metadata[MetadataTimestamp].(time.Time)
+		SLogFromContext(ctx).WarnContext(ctx, "invalid timestamp metadata type",
+			slog.Int("line", process.LineNo), slog.String("type", fmt.Sprintf("%T", pts)))
 	}
 
 	if process.Metadata.BoolValue(MetadataSkip) {
 		return lastTime, nil
 	}
 
+	if !p.inTimeRange(process) {
+		return retTime, nil
+	}
+
 	createFunc := func(isBefore bool) error {
 		// call create plugins
 		if create {
 			for _, pp := range p.processor.pluginCreate {
 				var items []*Item
-				var err error
-				if isBefore {
-					items, err = pp.CreateBefore(ctx, process)
-				} else {
-					items, err = pp.CreateAfter(ctx, process)
-				}
+				skip, err := p.callPlugin(ctx, pp, PluginPhaseCreate, process, func() (err error) {
+					if isBefore {
+						items, err = pp.CreateBefore(ctx, process)
+					} else {
+						items, err = pp.CreateAfter(ctx, process)
+					}
+					return err
+				})
 				if err != nil {
 					return err
+				} else if skip {
+					continue
 				}
 				for _, item := range items {
 					item.Metadata[MetadataCreated] = true
@@ -391,6 +940,52 @@ func (p *Job) internalOutputItem(ctx context.Context, process *Item, output Outp
 	return retTime, nil
 }
 
+// inTimeRange checks whether the item timestamp is inside the [TimeFrom, TimeTo) range. If the item timestamp
+// was not calculated and is past TimeTo plus TimeTolerance (or before TimeFrom minus TimeTolerance in reverse
+// mode), the job is marked as finished.
+func (p *Job) inTimeRange(process *Item) bool {
+	if p.TimeFrom.IsZero() && p.TimeTo.IsZero() {
+		return true
+	}
+	ts, ok := process.Metadata[MetadataTimestamp].(time.Time)
+	if !ok {
+		return false
+	}
+	calculated := process.Metadata.BoolValue(MetadataTimestampCalculated)
+	if !p.TimeTo.IsZero() && !ts.Before(p.TimeTo) {
+		if !p.Reverse && !calculated && !ts.Before(p.TimeTo.Add(p.TimeTolerance)) {
+			p.timeRangeFinished = true
+		}
+		return false
+	}
+	if !p.TimeFrom.IsZero() && ts.Before(p.TimeFrom) {
+		if p.Reverse && !calculated && ts.Before(p.TimeFrom.Add(-p.TimeTolerance)) {
+			p.timeRangeFinished = true
+		}
+		return false
+	}
+	return true
+}
+
+// getPluginBoundaries returns the PluginBoundary implementation of each plugin, or nil if not implemented.
+func getPluginBoundaries[T Plugin](plugins []T) []PluginBoundary {
+	ret := make([]PluginBoundary, len(plugins))
+	for i, plugin := range plugins {
+		if pb, ok := any(plugin).(PluginBoundary); ok {
+			ret[i] = pb
+		}
+	}
+	return ret
+}
+
+// candidateInBoundary checks whether the lines can be a match for a plugin with an optional PluginBoundary.
+func candidateInBoundary(ctx context.Context, boundary PluginBoundary, lines ItemLines) bool {
+	if boundary == nil {
+		return true
+	}
+	return boundary.CanStart(ctx, lines[0]) && boundary.CanEnd(ctx, lines[len(lines)-1])
+}
+
 func getSortedPluginPostProcess(processor *Processor) []PluginPostProcess {
 	orderPlugins := map[int][]PluginPostProcess{}
 	var orderList []int
@@ -0,0 +1,201 @@
+package panyl
+
+import (
+	"context"
+	"sync"
+)
+
+// concurrentBatchSize is the maximum amount of lines sent to a worker at once, so the synchronization cost is
+// shared by the lines.
+const concurrentBatchSize = 64
+
+// scannedLine is a line read from the LineProvider by the reader goroutine.
+type scannedLine struct {
+	line   any
+	lineno int
+	offset int64
+}
+
+// concurrentBatch is a sequence of lines pre-processed by a worker.
+type concurrentBatch struct {
+	lines []*jobLine
+	done  chan struct{} // closed when the worker finishes
+}
+
+// processConcurrent reads all lines from the LineProvider, calling the concurrency-safe Clean and Metadata plugins
+// on a pool of Concurrency goroutines, while the remaining phases are executed in the line order.
+// The reader goroutine only calls the LineProvider, the Job state is only changed by the calling goroutine. As the
+// reader may still be in a Scan call when processing stops early, the LineProvider error is returned by this
+// function, and must not be read by the caller.
+func (p *Job) processConcurrent(ctx context.Context, scanner LineProvider) error {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	position, hasPosition := scanner.(LineProviderPosition)
+
+	scanned := make(chan scannedLine, p.Concurrency*concurrentBatchSize)
+	var scanErr error // set before scanned is closed
+	go func() {
+		defer close(scanned)
+		for scanner.Scan(ctx) {
+			sl := scannedLine{line: scanner.Line()}
+			if hasPosition {
+				sl.lineno, sl.offset = position.LineNo(), position.Offset()
+			}
+			select {
+			case scanned <- sl:
+			case <-ctx.Done():
+				return
+			}
+		}
+		scanErr = scanner.Err()
+	}()
+
+	// at most window batches are pending, so sending to work never blocks
+	window := p.Concurrency * 2
+	work := make(chan *concurrentBatch, window)
+	var wg sync.WaitGroup
+	for range p.Concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range work {
+				for _, jl := range batch.lines {
+					if jl.err = ctx.Err(); jl.err != nil {
+						break
+					}
+					if jl.err = p.preProcessLine(ctx, jl, true); jl.err != nil {
+						break
+					}
+				}
+				close(batch.done)
+			}
+		}()
+	}
+
+	var queue []*concurrentBatch
+	var err, stopErr error // stopErr is returned after the lines already read are processed
+	reading := true
+	lineno := p.lineno
+	for err == nil {
+		var next <-chan scannedLine
+		if reading && stopErr == nil && len(queue) < window {
+			next = scanned
+		}
+		var head <-chan struct{}
+		if len(queue) > 0 {
+			head = queue[0].done
+		}
+		if next == nil && head == nil {
+			break
+		}
+
+		select {
+		case sl, ok := <-next:
+			if !ok {
+				reading = false
+				break
+			}
+			// add the lines which were already read to the batch, without waiting for more
+			batch := &concurrentBatch{done: make(chan struct{})}
+			for ok {
+				if hasPosition {
+					lineno = sl.lineno
+				} else {
+					lineno = p.nextLineNo(lineno)
+				}
+				jl, lerr := p.startLine(ctx, sl.line, lineno, sl.offset)
+				if lerr != nil {
+					stopErr = lerr
+					break
+				}
+				if jl != nil {
+					batch.lines = append(batch.lines, jl)
+				}
+				if len(batch.lines) >= concurrentBatchSize {
+					break
+				}
+				select {
+				case sl, ok = <-scanned:
+					if !ok {
+						reading = false
+					}
+				default:
+					ok = false
+				}
+			}
+			if len(batch.lines) > 0 {
+				queue = append(queue, batch)
+				work <- batch
+			}
+		case <-head:
+			batch := queue[0]
+			queue[0] = nil
+			queue = queue[1:]
+			err = p.processConcurrentBatch(ctx, batch)
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+	}
+	if err == nil {
+		err = stopErr
+	}
+	if err == nil && !reading {
+		err = scanErr
+	}
+
+	// stop the goroutines. The reader is not waited for, as it may be blocked in a Scan call which doesn't use the
+	// context. It exits when the call returns, without changing the Job.
+	cancel()
+	close(work)
+	wg.Wait()
+
+	return err
+}
+
+// processConcurrentBatch calls the remaining plugins on the pre-processed lines, in order.
+func (p *Job) processConcurrentBatch(ctx context.Context, batch *concurrentBatch) error {
+	for _, jl := range batch.lines {
+		if jl.err != nil {
+			return jl.err
+		}
+		if p.timeRangeFinished {
+			return ErrFinished
+		}
+		p.lineno = jl.lineno
+		if err := p.preProcessLine(ctx, jl, false); err != nil {
+			return err
+		}
+		if err := p.processItem(ctx, jl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getConcurrentPluginCount returns the amount of Clean and Metadata plugins which can be called concurrently.
+func getConcurrentPluginCount(processor *Processor) (clean int, metadata int) {
+	for _, plugin := range processor.pluginClean {
+		if !isPluginConcurrencySafe(plugin) {
+			return clean, 0
+		}
+		clean++
+	}
+	for _, plugin := range processor.pluginMetadata {
+		if !isPluginConcurrencySafe(plugin) {
+			break
+		}
+		metadata++
+	}
+	return clean, metadata
+}
+
+func isPluginConcurrencySafe(plugin Plugin) bool {
+	if cs, ok := plugin.(PluginConcurrencySafe); ok {
+		return cs.IsConcurrencySafe()
+	}
+	return false
+}
@@ -0,0 +1,679 @@
+package panyl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJob_ErrorPolicyFailFast(t *testing.T) {
+	ctx := context.Background()
+
+	p := NewProcessor(WithPlugins(&ErrorPluginTest{err: errTest}))
+
+	res := &OutputArray{}
+	err := p.Process(ctx, strings.NewReader("line1\nfail\nline3"), res)
+	assert.ErrorIs(t, err, errTest)
+
+	var perr *PluginError
+	if assert.ErrorAs(t, err, &perr) {
+		assert.Equal(t, PluginPhaseMetadata, perr.Phase)
+		assert.IsType(t, &ErrorPluginTest{}, perr.Plugin)
+		assert.Equal(t, 2, perr.LineNo)
+	}
+}
+
+func TestJob_ErrorPolicySkipPlugin(t *testing.T) {
+	ctx := context.Background()
+
+	p := NewProcessor(WithPlugins(&ErrorPluginTest{err: errTest}))
+
+	var pluginErrors []*PluginError
+	res := &OutputArray{}
+	err := p.Process(ctx, strings.NewReader("line1\nfail\nline3"), res,
+		WithErrorPolicy(ErrorPolicySkipPlugin),
+		WithOnPluginError(func(ctx context.Context, perr *PluginError) {
+			pluginErrors = append(pluginErrors, perr)
+		}))
+	assert.NoError(t, err)
+
+	assert.Len(t, res.List, 3)
+	assert.Equal(t, "fail", res.List[1].Line)
+	assert.False(t, res.List[1].Metadata.HasValue(MetadataErrors))
+
+	assert.Len(t, pluginErrors, 1)
+	assert.Equal(t, PluginPhaseMetadata, pluginErrors[0].Phase)
+	assert.Equal(t, 2, pluginErrors[0].LineNo)
+	assert.ErrorIs(t, pluginErrors[0], errTest)
+}
+
+func TestJob_ErrorPolicyMetadata(t *testing.T) {
+	ctx := context.Background()
+
+	p := NewProcessor(WithPlugins(&ErrorPluginTest{err: errTest}))
+
+	res := &OutputArray{}
+	err := p.Process(ctx, strings.NewReader("line1\nfail\nline3"), res,
+		WithErrorPolicy(ErrorPolicyMetadata))
+	assert.NoError(t, err)
+
+	assert.Len(t, res.List, 3)
+	assert.Len(t, res.List[1].Metadata.ListValue(MetadataErrors), 1)
+	assert.False(t, res.List[2].Metadata.HasValue(MetadataErrors))
+}
+
+func TestJob_ErrorPolicyMetadataBacklog(t *testing.T) {
+	ctx := context.Background()
+
+	// the structure plugin is called for each backlog candidate, failing with a different message each time
+	plugin := &ErrorStructurePluginTest{}
+	p := NewProcessor(WithPlugins(plugin))
+
+	var pluginErrors []*PluginError
+	res := &OutputArray{}
+	err := p.Process(ctx, strings.NewReader("line1\nline2\nline3\nline4"), res,
+		WithErrorPolicy(ErrorPolicyMetadata),
+		WithOnPluginError(func(ctx context.Context, perr *PluginError) {
+			pluginErrors = append(pluginErrors, perr)
+		}))
+	assert.NoError(t, err)
+	assert.Len(t, pluginErrors, 10)
+
+	// each item has the error of the plugin only once
+	if assert.Len(t, res.List, 4) {
+		for i, item := range res.List {
+			errs := item.Metadata.ListValue(MetadataErrors)
+			if assert.Len(t, errs, 1) {
+				assert.Contains(t, errs[0], fmt.Sprintf("at line %d: candidate of 1 lines", i+1))
+			}
+		}
+	}
+}
+
+func TestJob_ErrorPolicyMetadataConsolidate(t *testing.T) {
+	ctx := context.Background()
+
+	for _, tt := range []struct {
+		name          string
+		plugins       []Plugin
+		expectedItems int
+	}{
+		{"not consolidated", []Plugin{&ErrorConsolidatePluginTest{}}, 3},
+		{"consolidated", []Plugin{&ErrorConsolidatePluginTest{}, &ErrorConsolidatePluginTest{consolidate: true}}, 2},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewProcessor(WithPlugins(tt.plugins...))
+
+			res := &OutputArray{}
+			err := p.Process(ctx, strings.NewReader("line1\nfail\nline3"), res,
+				WithErrorPolicy(ErrorPolicyMetadata))
+			assert.NoError(t, err)
+
+			if assert.Len(t, res.List, tt.expectedItems) {
+				assert.False(t, res.List[0].Metadata.HasValue(MetadataErrors))
+				assert.Equal(t, 2, res.List[1].LineNo)
+				assert.Len(t, res.List[1].Metadata.ListValue(MetadataErrors), 1)
+			}
+		})
+	}
+}
+
+func TestJob_ErrorPolicyPanic(t *testing.T) {
+	ctx := context.Background()
+
+	p := NewProcessor(WithPlugins(&ErrorPluginTest{panic: true}))
+
+	var pluginErrors []*PluginError
+	res := &OutputArray{}
+	err := p.Process(ctx, strings.NewReader("line1\nfail\nline3"), res,
+		WithErrorPolicy(ErrorPolicySkipPlugin),
+		WithOnPluginError(func(ctx context.Context, perr *PluginError) {
+			pluginErrors = append(pluginErrors, perr)
+		}))
+	assert.NoError(t, err)
+
+	assert.Len(t, res.List, 3)
+	assert.Len(t, pluginErrors, 1)
+	assert.ErrorContains(t, pluginErrors[0], "plugin panic")
+}
+
+func TestJob_TimeRange(t *testing.T) {
+	ctx := context.Background()
+
+	p := NewProcessor(WithPlugins(&TimestampPluginTest{}, &ParsePrefixPluginTest{}))
+
+	var lines []string
+	for i := 0; i < 20; i++ {
+		lines = append(lines, fmt.Sprintf("2024-01-01T14:%02d:00Z line %d", i, i))
+		if i == 12 {
+			// out of order line
+			lines = append(lines, "2024-01-01T14:08:30Z out of order")
+		}
+	}
+
+	lp := &countLineProvider{LineProvider: NewStaticLineProvider(toAnySlice(lines))}
+
+	res := &OutputArray{}
+	err := p.ProcessProvider(ctx, lp, res, WithTimeRange(
+		time.Date(2024, 1, 1, 14, 2, 0, 0, time.UTC),
+		time.Date(2024, 1, 1, 14, 10, 0, 0, time.UTC),
+		5*time.Minute))
+	assert.NoError(t, err)
+
+	assert.Len(t, res.List, 9)
+	assert.Equal(t, "line 2", res.List[0].Line)
+	assert.Equal(t, "line 9", res.List[7].Line)
+	assert.Equal(t, "out of order", res.List[8].Line)
+	// stopped at line 15
+	assert.Equal(t, 17, lp.count)
+}
+
+func TestJob_Reverse(t *testing.T) {
+	ctx := context.Background()
+
+	p := NewProcessor(WithPlugins(&ParsePrefixPluginTest{prefix: "log"}, &BracesStructurePluginTest{}))
+
+	data := "log 1\n{\na\n}\ntrace\nlog 2\n"
+
+	res := &OutputArray{}
+	err := p.ProcessProvider(ctx, NewReverseLineProvider(strings.NewReader(data), int64(len(data)), 4, 0, nil),
+		res, WithReverse(true))
+	assert.NoError(t, err)
+
+	assert.Len(t, res.List, 4)
+	assert.Equal(t, "log 2", res.List[0].Line)
+	assert.Equal(t, -1, res.List[0].LineNo)
+	assert.Equal(t, "trace", res.List[1].Line)
+	assert.Equal(t, -2, res.List[1].LineNo)
+	assert.Equal(t, "{\na\n}", res.List[2].Line)
+	assert.Equal(t, -5, res.List[2].LineNo)
+	assert.Equal(t, 3, res.List[2].LineCount)
+	assert.Equal(t, int64(6), res.List[2].Offset)
+	assert.Equal(t, "log 1", res.List[3].Line)
+	assert.Equal(t, -6, res.List[3].LineNo)
+}
+
+func TestJob_Concurrency(t *testing.T) {
+	ctx := context.Background()
+
+	var data strings.Builder
+	for i := 0; i < 500; i++ {
+		if i%10 == 0 {
+			data.WriteString("{\nmulti\n}\n")
+		} else {
+			_, _ = fmt.Fprintf(&data, "log %d\n", i)
+		}
+	}
+
+	newProcessor := func() *Processor {
+		return NewProcessor(WithPlugins(&ConcurrentCleanPluginTest{}, &ParsePrefixPluginTest{prefix: "log"},
+			&BracesStructurePluginTest{}))
+	}
+
+	expected := &OutputArray{}
+	err := newProcessor().Process(ctx, strings.NewReader(data.String()), expected)
+	assert.NoError(t, err)
+
+	res := &OutputArray{}
+	err = newProcessor().Process(ctx, strings.NewReader(data.String()), res, WithConcurrency(8))
+	assert.NoError(t, err)
+
+	assert.Len(t, res.List, len(expected.List))
+	for i := range expected.List {
+		assert.Equal(t, expected.List[i].Line, res.List[i].Line)
+		assert.Equal(t, expected.List[i].LineNo, res.List[i].LineNo)
+		assert.Equal(t, expected.List[i].LineCount, res.List[i].LineCount)
+	}
+}
+
+func TestJob_ConcurrencyBlockingProvider(t *testing.T) {
+	ctx := context.Background()
+
+	for _, test := range []struct {
+		name     string
+		options  []JobOption
+		plugins  []Plugin
+		expected []string
+		err      error
+	}{
+		{
+			name:     "line limit",
+			options:  []JobOption{WithLineLimit(2, 1)},
+			expected: []string{"line2", "fail"},
+		},
+		{
+			name:    "plugin error",
+			plugins: []Plugin{&ErrorPluginTest{err: errTest}},
+			err:     errTest,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			// the provider blocks ignoring the context after the lines, like a network connection
+			release := make(chan struct{})
+			defer close(release)
+			scanner := &blockingLineProviderTest{lines: []string{"line1", "line2", "fail", "line3"}, release: release}
+
+			res := &OutputArray{}
+			done := make(chan error, 1)
+			go func() {
+				done <- NewProcessor(WithPlugins(append([]Plugin{&ConcurrentCleanPluginTest{}}, test.plugins...)...)).
+					ProcessProvider(ctx, scanner, res, append(test.options, WithConcurrency(4))...)
+			}()
+
+			select {
+			case err := <-done:
+				if test.err != nil {
+					assert.ErrorIs(t, err, test.err)
+				} else {
+					assert.NoError(t, err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("ProcessProvider didn't return")
+			}
+			var lines []string
+			for _, item := range res.List {
+				lines = append(lines, item.Line)
+			}
+			assert.Equal(t, test.expected, lines)
+		})
+	}
+}
+
+func TestJob_PluginBoundary(t *testing.T) {
+	ctx := context.Background()
+
+	pl := &BoundaryStructurePluginTest{}
+	p := NewProcessor(WithPlugins(pl))
+
+	res := &OutputArray{}
+	err := p.Process(ctx, strings.NewReader("text\n{\ntext\n}\ntext\ntext\n{\n}"), res)
+	assert.NoError(t, err)
+
+	// only the candidates starting with "{" and ending with "}" are checked
+	assert.Equal(t, 2, pl.calls)
+	assert.Len(t, res.List, 5)
+	assert.Equal(t, "{\ntext\n}", res.List[1].Line)
+	assert.Equal(t, "{\n}", res.List[4].Line)
+}
+
+func TestJob_MaxIncompleteBacklogLines(t *testing.T) {
+	ctx := context.Background()
+
+	var data strings.Builder
+	data.WriteString("text 1\ntext 2\n{\n")
+	for i := 0; i < 20; i++ {
+		_, _ = fmt.Fprintf(&data, "line %d\n", i)
+	}
+	data.WriteString("}\ntext 3\n")
+
+	for _, tt := range []struct {
+		name                      string
+		maxIncompleteBacklogLines int
+		expectedItems             int
+	}{
+		{"disabled", 0, 25},
+		{"enabled", 100, 4},
+		{"over the limit", 10, 25},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewProcessor(WithPlugins(&IncompleteStructurePluginTest{}))
+
+			res := &OutputArray{}
+			err := p.Process(ctx, strings.NewReader(data.String()), res,
+				WithMaxBacklogLines(5),
+				WithMaxIncompleteBacklogLines(tt.maxIncompleteBacklogLines))
+			assert.NoError(t, err)
+			assert.Len(t, res.List, tt.expectedItems)
+			if tt.expectedItems == 4 {
+				assert.Equal(t, 3, res.List[2].LineNo)
+				assert.Equal(t, 22, res.List[2].LineCount)
+			}
+		})
+	}
+}
+
+func TestJob_MaxIncompleteBacklogLinesNested(t *testing.T) {
+	ctx := context.Background()
+
+	p := NewProcessor(WithPlugins(&IncompleteStructurePluginTest{}))
+
+	res := &OutputArray{}
+	err := p.Process(ctx, strings.NewReader("{\n{\n}\n{\n}\n}\ntext"), res,
+		WithMaxIncompleteBacklogLines(100))
+	assert.NoError(t, err)
+	assert.Len(t, res.List, 2)
+	assert.Equal(t, 6, res.List[0].LineCount)
+}
+
+var errTest = errors.New("test error")
+
+// ErrorPluginTest fails on lines equal to "fail"
+type ErrorPluginTest struct {
+	err   error
+	panic bool
+}
+
+func (pt ErrorPluginTest) IsPanylPlugin() {}
+
+func (pt ErrorPluginTest) ExtractMetadata(ctx context.Context, item *Item) (bool, error) {
+	if item.Line != "fail" {
+		return false, nil
+	}
+	if pt.panic {
+		panic("test panic")
+	}
+	return false, pt.err
+}
+
+// ErrorStructurePluginTest fails for all candidates, with the amount of lines in the message
+type ErrorStructurePluginTest struct{}
+
+func (pt *ErrorStructurePluginTest) IsPanylPlugin() {}
+
+func (pt *ErrorStructurePluginTest) ExtractStructure(ctx context.Context, lines ItemLines, item *Item) (bool, error) {
+	return false, fmt.Errorf("candidate of %d lines", len(lines))
+}
+
+// ErrorConsolidatePluginTest fails on lines equal to "fail", or consolidates them with the next line if consolidate
+// is true
+type ErrorConsolidatePluginTest struct {
+	consolidate bool
+}
+
+func (pt ErrorConsolidatePluginTest) IsPanylPlugin() {}
+
+func (pt ErrorConsolidatePluginTest) Consolidate(ctx context.Context, lines ItemLines, item *Item) (bool, int, error) {
+	if lines[0].Line != "fail" {
+		return false, 0, nil
+	}
+	if !pt.consolidate || len(lines) < 2 {
+		return false, 0, errTest
+	}
+	item.Line = lines[:2].Line()
+	return true, 2, nil
+}
+
+// TimestampPluginTest parses a RFC3339 timestamp from the start of the line
+type TimestampPluginTest struct {
+}
+
+func (pt TimestampPluginTest) IsPanylPlugin() {}
+
+func (pt TimestampPluginTest) ExtractMetadata(ctx context.Context, item *Item) (bool, error) {
+	ts, line, ok := strings.Cut(item.Line, " ")
+	if !ok {
+		return false, nil
+	}
+	t, err := time.Parse(time.RFC3339, ts)
+	if err != nil {
+		return false, nil
+	}
+	item.Metadata[MetadataTimestamp] = t
+	item.Line = line
+	return true, nil
+}
+
+type countLineProvider struct {
+	LineProvider
+	count int
+}
+
+func (c *countLineProvider) Scan(ctx context.Context) bool {
+	if !c.LineProvider.Scan(ctx) {
+		return false
+	}
+	c.count++
+	return true
+}
+
+func toAnySlice[T any](list []T) []any {
+	ret := make([]any, 0, len(list))
+	for _, item := range list {
+		ret = append(ret, item)
+	}
+	return ret
+}
+
+// BracesStructurePluginTest matches lines between "{" and "}"
+type BracesStructurePluginTest struct {
+}
+
+func (pt BracesStructurePluginTest) IsPanylPlugin() {}
+
+func (pt BracesStructurePluginTest) ExtractStructure(ctx context.Context, lines ItemLines, item *Item) (bool, error) {
+	line := lines.Line()
+	if !strings.HasPrefix(line, "{") || !strings.HasSuffix(line, "}") {
+		return false, nil
+	}
+	item.Line = line
+	return true, nil
+}
+
+// MergeStructurePluginTest is a BracesStructurePluginTest which merges the lines data
+type MergeStructurePluginTest struct {
+	BracesStructurePluginTest
+}
+
+func (pt MergeStructurePluginTest) ExtractStructure(ctx context.Context, lines ItemLines, item *Item) (bool, error) {
+	if ok, err := pt.BracesStructurePluginTest.ExtractStructure(ctx, lines, item); !ok || err != nil {
+		return ok, err
+	}
+	return true, item.MergeLinesData(lines)
+}
+
+// LineDataPluginTest sets a nested map with the line in the Data
+type LineDataPluginTest struct {
+}
+
+func (pt LineDataPluginTest) IsPanylPlugin() {}
+
+func (pt LineDataPluginTest) ExtractMetadata(ctx context.Context, item *Item) (bool, error) {
+	item.Data["lines"] = map[string]any{item.Line: true}
+	return true, nil
+}
+
+// ConcurrentCleanPluginTest is a concurrency-safe clean plugin which takes a variable amount of time
+type ConcurrentCleanPluginTest struct {
+}
+
+func (pt ConcurrentCleanPluginTest) IsPanylPlugin() {}
+
+func (pt ConcurrentCleanPluginTest) IsConcurrencySafe() bool {
+	return true
+}
+
+func (pt ConcurrentCleanPluginTest) Clean(ctx context.Context, item *Item) (bool, error) {
+	time.Sleep(time.Duration(item.LineNo%5) * 10 * time.Microsecond)
+	return false, nil
+}
+
+// blockingLineProviderTest returns the lines, and then blocks until release is closed, ignoring the context
+type blockingLineProviderTest struct {
+	lines   []string
+	line    string
+	release chan struct{}
+}
+
+func (b *blockingLineProviderTest) Err() error {
+	return nil
+}
+
+func (b *blockingLineProviderTest) Line() any {
+	return b.line
+}
+
+func (b *blockingLineProviderTest) Scan(ctx context.Context) bool {
+	if len(b.lines) == 0 {
+		<-b.release
+		return false
+	}
+	b.line, b.lines = b.lines[0], b.lines[1:]
+	return true
+}
+
+// PhasesPluginTest records the Data["value"] of the items of the structure, parse and parse format phases
+type PhasesPluginTest struct {
+	structure, parse, parseFormat []string
+}
+
+func (pt *PhasesPluginTest) IsPanylPlugin() {}
+
+func (pt *PhasesPluginTest) ExtractStructure(ctx context.Context, lines ItemLines, item *Item) (bool, error) {
+	pt.structure = append(pt.structure, lines[len(lines)-1].Data.StringValue("value"))
+	return false, nil
+}
+
+func (pt *PhasesPluginTest) ExtractParse(ctx context.Context, lines ItemLines, item *Item) (bool, error) {
+	pt.parse = append(pt.parse, lines[len(lines)-1].Data.StringValue("value"))
+	return false, nil
+}
+
+func (pt *PhasesPluginTest) ParseFormat(ctx context.Context, item *Item) (bool, error) {
+	pt.parseFormat = append(pt.parseFormat, item.Data.StringValue("value"))
+	return false, nil
+}
+
+// BoundaryStructurePluginTest is a BracesStructurePluginTest implementing PluginBoundary
+type BoundaryStructurePluginTest struct {
+	BracesStructurePluginTest
+	calls int
+}
+
+func (pt *BoundaryStructurePluginTest) ExtractStructure(ctx context.Context, lines ItemLines, item *Item) (bool, error) {
+	pt.calls++
+	return pt.BracesStructurePluginTest.ExtractStructure(ctx, lines, item)
+}
+
+func (pt *BoundaryStructurePluginTest) CanStart(ctx context.Context, item *Item) bool {
+	return item.Line == "{"
+}
+
+func (pt *BoundaryStructurePluginTest) CanEnd(ctx context.Context, item *Item) bool {
+	return item.Line == "}"
+}
+
+// IncompleteStructurePluginTest is a BracesStructurePluginTest implementing PluginIncomplete
+type IncompleteStructurePluginTest struct {
+	BracesStructurePluginTest
+}
+
+func (pt IncompleteStructurePluginTest) ExtractStructure(ctx context.Context, lines ItemLines, item *Item) (bool, error) {
+	if depth, closed := pt.depth(lines); depth != 0 || !closed {
+		return false, nil
+	}
+	return pt.BracesStructurePluginTest.ExtractStructure(ctx, lines, item)
+}
+
+func (pt IncompleteStructurePluginTest) StartIncomplete(ctx context.Context, item *Item) IncompleteScanner {
+	if item.Line != "{" {
+		return nil
+	}
+	return &incompleteScannerTest{depth: 1}
+}
+
+// depth returns the brace depth at the end of the lines, and whether the first brace was closed before that.
+func (pt IncompleteStructurePluginTest) depth(lines ItemLines) (int, bool) {
+	if lines[0].Line != "{" {
+		return 0, false
+	}
+	depth := 0
+	for i, line := range lines {
+		switch line.Line {
+		case "{":
+			depth++
+		case "}":
+			depth--
+			if depth == 0 {
+				return depth, i == len(lines)-1
+			}
+		}
+	}
+	return depth, false
+}
+
+// incompleteScannerTest counts the brace depth of the lines
+type incompleteScannerTest struct {
+	depth int
+}
+
+func (st *incompleteScannerTest) ScanLine(ctx context.Context, item *Item) bool {
+	switch item.Line {
+	case "{":
+		st.depth++
+	case "}":
+		st.depth--
+	}
+	return st.depth > 0
+}
+
+func TestJob_RecycledLines(t *testing.T) {
+	ctx := context.Background()
+
+	p := NewProcessor(WithPlugins(&MergeStructurePluginTest{}, &LineDataPluginTest{}))
+
+	res := &OutputArray{}
+	err := p.ProcessProvider(ctx, NewStaticLineProvider([]any{"{", "a", "}", "{", "b", "}", "c"}), res)
+	assert.NoError(t, err)
+
+	// the items of the merged lines are reused for the next lines, without changing the items already output
+	if assert.Len(t, res.List, 3) {
+		assert.Equal(t, MapValue{"lines": map[string]any{"{": true, "a": true, "}": true}}, res.List[0].Data)
+		assert.Equal(t, MapValue{"lines": map[string]any{"{": true, "b": true, "}": true}}, res.List[1].Data)
+		assert.Equal(t, MapValue{"lines": map[string]any{"c": true}}, res.List[2].Data)
+	}
+}
+
+func TestJob_StructuredItemPlugins(t *testing.T) {
+	ctx := context.Background()
+
+	structured := InitItem()
+	structured.Metadata[MetadataStructure] = "test"
+	structured.Data["value"] = "structured"
+	unstructured := InitItem()
+	unstructured.Data["value"] = "unstructured"
+	unstructured.Line = "unstructured"
+
+	plugin := &PhasesPluginTest{}
+	p := NewProcessor(WithPlugins(plugin))
+
+	res := &OutputArray{}
+	err := p.ProcessProvider(ctx, NewStaticLineProvider([]any{structured, unstructured}), res)
+	assert.NoError(t, err)
+	assert.Len(t, res.List, 2)
+
+	// only items without MetadataStructure go through the structure and parse plugins, the other phases are
+	// called for both
+	assert.Equal(t, []string{"unstructured"}, plugin.structure)
+	assert.Equal(t, []string{"unstructured"}, plugin.parse)
+	assert.Equal(t, []string{"structured", "unstructured"}, plugin.parseFormat)
+}
+
+func TestJob_StructuredItem(t *testing.T) {
+	ctx := context.Background()
+
+	structured := InitItem()
+	structured.Metadata[MetadataStructure] = "test"
+	structured.Metadata[MetadataMessage] = "structured"
+
+	p := NewProcessor(WithPlugins(&IncompleteStructurePluginTest{}))
+
+	res := &OutputArray{}
+	err := p.ProcessProvider(ctx, NewStaticLineProvider([]any{"{", "text 1", structured, "text 2"}), res,
+		WithMaxIncompleteBacklogLines(100))
+	assert.NoError(t, err)
+
+	// the incomplete structure in the backlog is output before the structured item, which is never merged
+	if assert.Len(t, res.List, 4) {
+		assert.Equal(t, "{", res.List[0].Line)
+		assert.Equal(t, "text 1", res.List[1].Line)
+		assert.Equal(t, "structured", res.List[2].Metadata[MetadataMessage])
+		assert.Equal(t, 3, res.List[2].LineNo)
+		assert.Equal(t, 1, res.List[2].LineCount)
+		assert.Equal(t, "text 2", res.List[3].Line)
+	}
+}
@@ -0,0 +1,359 @@
+// Package journald reads systemd journal entries, as written by "journalctl -o export" and "journalctl -o json".
+package journald
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/RangelReale/panyl/v2"
+)
+
+// MetadataStructureJournald is the MetadataStructure of the items read by LineProvider.
+const MetadataStructureJournald = "journald"
+
+// Journal fields used by the items.
+const (
+	FieldMessage           = "MESSAGE"
+	FieldPriority          = "PRIORITY"
+	FieldRealtimeTimestamp = "__REALTIME_TIMESTAMP"
+	FieldSystemdUnit       = "_SYSTEMD_UNIT"
+	FieldSyslogIdentifier  = "SYSLOG_IDENTIFIER"
+)
+
+const DefaultMaxFieldSize = 16 * 1024 * 1024
+
+// Format is the journalctl output format.
+type Format int
+
+const (
+	// FormatAuto detects the format from the first character of the input, JSON if it is "{".
+	FormatAuto Format = iota
+	// FormatExport is the journal export format ("journalctl -o export").
+	FormatExport
+	// FormatJSON is one JSON object for each entry ("journalctl -o json").
+	FormatJSON
+)
+
+// LineProvider is a panyl.LineProvider which reads journal entries, returning a *panyl.Item for each one:
+//   - the fields to Data, except MESSAGE. Fields which are repeated in the entry are returned as a list
+//   - MESSAGE to MetadataMessage and Line. Binary messages which are not valid UTF-8 have the invalid bytes
+//     replaced by U+FFFD, and are also kept in Data
+//   - the first application field found, by default _SYSTEMD_UNIT and SYSLOG_IDENTIFIER, to MetadataApplication
+//   - PRIORITY to MetadataLevel, see PriorityLevel
+//   - __REALTIME_TIMESTAMP to MetadataTimestamp
+//
+// Binary field values are returned as strings if they are valid UTF-8, and as []byte otherwise.
+// MetadataStructure is set to MetadataStructureJournald, so the Job doesn't try to parse the items again.
+type LineProvider struct {
+	r                 *bufio.Reader
+	dec               *json.Decoder
+	format            Format
+	maxFieldSize      int
+	applicationFields []string
+	item              *panyl.Item
+	err               error
+}
+
+var _ panyl.LineProvider = (*LineProvider)(nil)
+
+// NewLineProvider creates a LineProvider reading from r.
+func NewLineProvider(r io.Reader, options ...Option) *LineProvider {
+	ret := &LineProvider{
+		r:                 bufio.NewReader(r),
+		format:            FormatAuto,
+		maxFieldSize:      DefaultMaxFieldSize,
+		applicationFields: []string{FieldSystemdUnit, FieldSyslogIdentifier},
+	}
+	for _, opt := range options {
+		opt(ret)
+	}
+	return ret
+}
+
+type Option func(*LineProvider)
+
+// WithFormat sets the input format, by default FormatAuto.
+func WithFormat(format Format) Option {
+	return func(l *LineProvider) {
+		l.format = format
+	}
+}
+
+// WithMaxFieldSize sets the maximum size of a binary field in the export format, by default DefaultMaxFieldSize.
+func WithMaxFieldSize(maxFieldSize int) Option {
+	return func(l *LineProvider) {
+		l.maxFieldSize = maxFieldSize
+	}
+}
+
+// WithApplicationFields sets the fields checked in order for MetadataApplication.
+func WithApplicationFields(fields ...string) Option {
+	return func(l *LineProvider) {
+		l.applicationFields = fields
+	}
+}
+
+func (l *LineProvider) Err() error {
+	return l.err
+}
+
+func (l *LineProvider) Line() any {
+	return l.item
+}
+
+func (l *LineProvider) Scan(ctx context.Context) bool {
+	if l.err != nil {
+		return false
+	}
+	if err := ctx.Err(); err != nil {
+		l.err = err
+		return false
+	}
+
+	if l.format == FormatAuto {
+		l.format = l.detectFormat()
+	}
+
+	var fields map[string]any
+	var err error
+	if l.format == FormatJSON {
+		fields, err = l.readJSON()
+	} else {
+		fields, err = l.readExport()
+	}
+	if err != nil {
+		if !errors.Is(err, io.EOF) {
+			l.err = err
+		}
+		return false
+	}
+	l.item = l.newItem(fields)
+	return true
+}
+
+// detectFormat returns FormatJSON if the first non-space character is "{".
+func (l *LineProvider) detectFormat() Format {
+	for {
+		b, err := l.r.ReadByte()
+		if err != nil {
+			return FormatExport
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		_ = l.r.UnreadByte()
+		if b == '{' {
+			return FormatJSON
+		}
+		return FormatExport
+	}
+}
+
+// readExport reads an entry in the export format. Each field is either "NAME=value\n", or for binary values
+// "NAME\n" followed by the value size as a little-endian uint64, the value and "\n". Entries end with an empty line.
+func (l *LineProvider) readExport() (map[string]any, error) {
+	fields := map[string]any{}
+	for {
+		line, err := l.r.ReadBytes('\n')
+		if err != nil {
+			if errors.Is(err, io.EOF) && len(line) == 0 {
+				if len(fields) > 0 {
+					return fields, nil
+				}
+				return nil, io.EOF
+			}
+			if !errors.Is(err, io.EOF) {
+				return nil, err
+			}
+		}
+		line = bytes.TrimSuffix(line, []byte{'\n'})
+		if len(line) == 0 {
+			if len(fields) > 0 {
+				return fields, nil
+			}
+			continue
+		}
+
+		if name, value, found := bytes.Cut(line, []byte{'='}); found {
+			addField(fields, string(name), bytes.Clone(value))
+			continue
+		}
+
+		// binary value
+		var size uint64
+		if err := binary.Read(l.r, binary.LittleEndian, &size); err != nil {
+			return nil, fmt.Errorf("error reading size of field %q: %w", line, unexpectedEOF(err))
+		}
+		if size > uint64(l.maxFieldSize) {
+			return nil, fmt.Errorf("field %q has %d bytes, more than the maximum of %d", line, size,
+				l.maxFieldSize)
+		}
+		value := make([]byte, size)
+		if _, err := io.ReadFull(l.r, value); err != nil {
+			return nil, fmt.Errorf("error reading value of field %q: %w", line, unexpectedEOF(err))
+		}
+		if b, err := l.r.ReadByte(); err != nil || b != '\n' {
+			return nil, fmt.Errorf("missing newline after value of field %q", line)
+		}
+		addField(fields, string(line), value)
+	}
+}
+
+// readJSON reads an entry in the JSON format. Values are strings, arrays of numbers for binary values, arrays of
+// values for repeated fields, or null for values which are too large.
+func (l *LineProvider) readJSON() (map[string]any, error) {
+	if l.dec == nil {
+		l.dec = json.NewDecoder(l.r)
+	}
+	var entry map[string]any
+	if err := l.dec.Decode(&entry); err != nil {
+		return nil, err
+	}
+	fields := make(map[string]any, len(entry))
+	for name, value := range entry {
+		if list, ok := value.([]any); ok && !isBinary(list) {
+			for _, v := range list {
+				addField(fields, name, jsonValue(v))
+			}
+			continue
+		}
+		addField(fields, name, jsonValue(value))
+	}
+	return fields, nil
+}
+
+// newItem creates the item of an entry.
+func (l *LineProvider) newItem(fields map[string]any) *panyl.Item {
+	item := panyl.InitItem(panyl.WithInitData(make(panyl.MapValue, len(fields))))
+	item.Metadata[panyl.MetadataStructure] = MetadataStructureJournald
+
+	for name, value := range fields {
+		if name == FieldMessage {
+			var message string
+			switch v := fieldValue(value).(type) {
+			case string:
+				message = v
+			case []byte:
+				// binary messages which are not valid UTF-8 are also kept in Data, as they can't be represented
+				// in the Line
+				message = strings.ToValidUTF8(string(v), "\uFFFD")
+				item.Data[name] = value
+			default:
+				message = fmt.Sprint(v)
+			}
+			item.Metadata[panyl.MetadataMessage] = message
+			item.Line = message
+			continue
+		}
+		item.Data[name] = value
+	}
+
+	for _, field := range l.applicationFields {
+		if application, ok := fieldValue(fields[field]).(string); ok && application != "" {
+			item.Metadata[panyl.MetadataApplication] = application
+			break
+		}
+	}
+	if priority, ok := fieldValue(fields[FieldPriority]).(string); ok {
+		if n, err := strconv.Atoi(priority); err == nil {
+			if level := PriorityLevel(n); level != "" {
+				item.Metadata[panyl.MetadataLevel] = level
+			}
+		}
+	}
+	if ts, ok := fieldValue(fields[FieldRealtimeTimestamp]).(string); ok {
+		if usec, err := strconv.ParseInt(ts, 10, 64); err == nil {
+			item.Metadata[panyl.MetadataTimestamp] = time.UnixMicro(usec).UTC()
+		}
+	}
+	return item
+}
+
+// PriorityLevel returns the MetadataLevel of a syslog priority, or an empty string if it is invalid.
+// Emergency, alert and critical are returned as MetadataLevelFATAL, and notice as info.
+func PriorityLevel(priority int) string {
+	switch priority {
+	case 0, 1, 2:
+		return panyl.MetadataLevelFATAL
+	case 3:
+		return panyl.MetadataLevelERROR
+	case 4:
+		return panyl.MetadataLevelWARNING
+	case 5, 6:
+		return panyl.MetadataLevelINFO
+	case 7:
+		return panyl.MetadataLevelDEBUG
+	}
+	return ""
+}
+
+// addField adds a field value, converting binary values to string if they are valid UTF-8. Repeated fields are
+// stored as a list.
+func addField(fields map[string]any, name string, value any) {
+	if b, ok := value.([]byte); ok && utf8.Valid(b) {
+		value = string(b)
+	}
+	switch current := fields[name].(type) {
+	case nil:
+		if _, exists := fields[name]; exists {
+			fields[name] = []any{nil, value}
+		} else {
+			fields[name] = value
+		}
+	case []any:
+		fields[name] = append(current, value)
+	default:
+		fields[name] = []any{current, value}
+	}
+}
+
+// fieldValue returns the first value of a repeated field.
+func fieldValue(value any) any {
+	if list, ok := value.([]any); ok && len(list) > 0 {
+		return list[0]
+	}
+	return value
+}
+
+// jsonValue converts a binary value, an array of numbers, to []byte.
+func jsonValue(value any) any {
+	if list, ok := value.([]any); ok && isBinary(list) {
+		ret := make([]byte, len(list))
+		for i, v := range list {
+			ret[i] = byte(v.(float64))
+		}
+		return ret
+	}
+	return value
+}
+
+// isBinary returns whether a JSON array is a binary value, a list of numbers.
+func isBinary(list []any) bool {
+	if len(list) == 0 {
+		return false
+	}
+	for _, v := range list {
+		if _, ok := v.(float64); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,99 @@
+package journald
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RangelReale/panyl/v2"
+	"github.com/RangelReale/panyl/v2/plugins/structure"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLineProvider_Export(t *testing.T) {
+	ctx := context.Background()
+
+	var input bytes.Buffer
+	input.WriteString("__CURSOR=s=1\n__REALTIME_TIMESTAMP=1709632800123456\nPRIORITY=4\n" +
+		"_SYSTEMD_UNIT=nginx.service\nSYSLOG_IDENTIFIER=nginx\nMESSAGE={\"not\": \"parsed\"}\n\n")
+	// binary message with a newline, and a binary field which is not valid UTF-8
+	input.WriteString("__REALTIME_TIMESTAMP=1709632801000000\nPRIORITY=3\nSYSLOG_IDENTIFIER=app\nMESSAGE\n")
+	_ = binary.Write(&input, binary.LittleEndian, uint64(12))
+	input.WriteString("first\nsecond\nDATA\n")
+	_ = binary.Write(&input, binary.LittleEndian, uint64(2))
+	input.Write([]byte{0xff, 0xfe})
+	input.WriteString("\nTAG=a\nTAG=b\n")
+
+	p := panyl.NewProcessor(panyl.WithPlugins(&structure.JSON{}))
+	res := &panyl.OutputArray{}
+	err := p.ProcessProvider(ctx, NewLineProvider(&input), res)
+	assert.NoError(t, err)
+
+	if assert.Len(t, res.List, 2) {
+		item := res.List[0]
+		assert.Equal(t, MetadataStructureJournald, item.Metadata[panyl.MetadataStructure])
+		assert.Equal(t, `{"not": "parsed"}`, item.Line)
+		assert.Equal(t, `{"not": "parsed"}`, item.Metadata[panyl.MetadataMessage])
+		assert.Equal(t, "nginx.service", item.Metadata[panyl.MetadataApplication])
+		assert.Equal(t, panyl.MetadataLevelWARNING, item.Metadata[panyl.MetadataLevel])
+		assert.Equal(t, time.Date(2024, 3, 5, 10, 0, 0, 123456000, time.UTC), item.Metadata[panyl.MetadataTimestamp])
+		assert.Equal(t, "s=1", item.Data["__CURSOR"])
+		assert.NotContains(t, item.Data, FieldMessage)
+
+		item = res.List[1]
+		assert.Equal(t, "first\nsecond", item.Line)
+		assert.Equal(t, "app", item.Metadata[panyl.MetadataApplication])
+		assert.Equal(t, panyl.MetadataLevelERROR, item.Metadata[panyl.MetadataLevel])
+		assert.Equal(t, []byte{0xff, 0xfe}, item.Data["DATA"])
+		assert.Equal(t, []any{"a", "b"}, item.Data["TAG"])
+	}
+}
+
+func TestLineProvider_JSON(t *testing.T) {
+	ctx := context.Background()
+
+	input := `{"__REALTIME_TIMESTAMP":"1709632800000000","PRIORITY":"6","SYSLOG_IDENTIFIER":"sshd","MESSAGE":"accepted"}
+{"PRIORITY":"7","MESSAGE":[104,105,10],"TAG":["a","b"],"DATA":[255,254],"LARGE":null}
+{"PRIORITY":"2","MESSAGE":[111,107,255,254]}
+`
+	lp := NewLineProvider(strings.NewReader(input))
+	var items []*panyl.Item
+	for lp.Scan(ctx) {
+		items = append(items, lp.Line().(*panyl.Item))
+	}
+	assert.NoError(t, lp.Err())
+
+	if assert.Len(t, items, 3) {
+		assert.Equal(t, "accepted", items[0].Line)
+		assert.Equal(t, "sshd", items[0].Metadata[panyl.MetadataApplication])
+		assert.Equal(t, panyl.MetadataLevelINFO, items[0].Metadata[panyl.MetadataLevel])
+		assert.Equal(t, time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC), items[0].Metadata[panyl.MetadataTimestamp])
+
+		assert.Equal(t, "hi\n", items[1].Line)
+		assert.Equal(t, panyl.MetadataLevelDEBUG, items[1].Metadata[panyl.MetadataLevel])
+		assert.Equal(t, []any{"a", "b"}, items[1].Data["TAG"])
+		assert.Equal(t, []byte{0xff, 0xfe}, items[1].Data["DATA"])
+		assert.Contains(t, items[1].Data, "LARGE")
+		assert.Nil(t, items[1].Data["LARGE"])
+		assert.NotContains(t, items[1].Data, FieldMessage)
+
+		assert.Equal(t, "ok\uFFFD", items[2].Line)
+		assert.Equal(t, "ok\uFFFD", items[2].Metadata[panyl.MetadataMessage])
+		assert.Equal(t, []byte{'o', 'k', 0xff, 0xfe}, items[2].Data[FieldMessage])
+		assert.Equal(t, panyl.MetadataLevelFATAL, items[2].Metadata[panyl.MetadataLevel])
+	}
+}
+
+func TestLineProvider_ExportInvalid(t *testing.T) {
+	var input bytes.Buffer
+	input.WriteString("MESSAGE\n")
+	_ = binary.Write(&input, binary.LittleEndian, uint64(100))
+	input.WriteString("short")
+
+	lp := NewLineProvider(&input, WithFormat(FormatExport))
+	assert.False(t, lp.Scan(context.Background()))
+	assert.ErrorContains(t, lp.Err(), `error reading value of field "MESSAGE"`)
+}
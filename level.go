@@ -0,0 +1,34 @@
+package panyl
+
+import "strings"
+
+// Level orders returned by LevelOrder.
+const (
+	LevelOrderUnknown = -1
+	LevelOrderTrace   = 0
+	LevelOrderDebug   = 1
+	LevelOrderInfo    = 2
+	LevelOrderWarning = 3
+	LevelOrderError   = 4
+	LevelOrderFatal   = 5
+)
+
+// LevelOrder returns the severity order of a MetadataLevel value, accepting the MetadataLevel constants and common
+// aliases like "warning", "err", "critical" and "fatal", ignoring case. Unknown levels return LevelOrderUnknown.
+func LevelOrder(level string) int {
+	switch strings.ToLower(level) {
+	case MetadataLevelTRACE:
+		return LevelOrderTrace
+	case MetadataLevelDEBUG, "dbg":
+		return LevelOrderDebug
+	case MetadataLevelINFO, "information", "informational", "notice":
+		return LevelOrderInfo
+	case MetadataLevelWARNING, "warning":
+		return LevelOrderWarning
+	case MetadataLevelERROR, "err":
+		return LevelOrderError
+	case MetadataLevelFATAL, "critical", "crit", "panic", "alert", "emerg", "emergency":
+		return LevelOrderFatal
+	}
+	return LevelOrderUnknown
+}
@@ -0,0 +1,17 @@
+package panyl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLevelOrder(t *testing.T) {
+	assert.Equal(t, LevelOrderTrace, LevelOrder(MetadataLevelTRACE))
+	assert.Equal(t, LevelOrderDebug, LevelOrder("DEBUG"))
+	assert.Equal(t, LevelOrderWarning, LevelOrder("warning"))
+	assert.Equal(t, LevelOrderError, LevelOrder(MetadataLevelERROR))
+	assert.Equal(t, LevelOrderFatal, LevelOrder("Critical"))
+	assert.Equal(t, LevelOrderUnknown, LevelOrder("verbose"))
+	assert.Less(t, LevelOrder(MetadataLevelINFO), LevelOrder(MetadataLevelWARNING))
+}
@@ -13,7 +13,9 @@ type LineProvider interface {
 	Err() error
 	// Line returns the most recent data generated by a call to Scan. This can be either a string, or a
 	// *Item instance.
-	// If returning *Item instances, usually only Item.Data should be filled.
+	// If returning *Item instances, usually only Item.Data should be filled. Items with an empty Line are
+	// skipped, unless they have MetadataStructure set. These are considered already structured, so they are
+	// output directly, without checking the structure and parse plugins.
 	Line() any
 	// Scan advances the Scanner to the next token, which will then be
 	// available through the Line method. It returns false when the
@@ -23,3 +25,20 @@ type LineProvider interface {
 	// will return nil.
 	Scan(ctx context.Context) bool
 }
+
+// LineProviderPosition is an optional interface a LineProvider can implement to inform the position of the
+// current line in the source. When available, the line number and offset are set in each Item.
+type LineProviderPosition interface {
+	// LineNo returns the 1-based line number of the current line in the source.
+	LineNo() int
+	// Offset returns the byte offset of the start of the current line in the source.
+	Offset() int64
+}
+
+// LineProviderSeekLine is an optional interface a LineProvider can implement to position at a line number without
+// reading the previous lines. It is used to skip to the start line of WithLineLimit.
+type LineProviderSeekLine interface {
+	LineProviderPosition
+	// SeekLine positions the provider so the next call to Scan returns the line lineNo (1-based).
+	SeekLine(lineNo int) error
+}
@@ -0,0 +1,243 @@
+package panyl
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+)
+
+// LineIndexSidecarExt is the extension appended to a file name to get its line index sidecar file name.
+const LineIndexSidecarExt = ".panylidx"
+
+// DefaultLineIndexInterval is the default amount of lines between each LineIndex entry.
+const DefaultLineIndexInterval = 10000
+
+var lineIndexMagic = [8]byte{'P', 'N', 'Y', 'L', 'I', 'D', 'X', '1'}
+
+// ErrLineIndexInvalid is returned when a line index file is invalid or doesn't match its source.
+var ErrLineIndexInvalid = errors.New("invalid line index")
+
+// LineIndex is a sparse index of line start offsets, with one entry every Interval lines.
+type LineIndex struct {
+	Interval int
+	Offsets  []int64 // Offsets[i] is the offset of line number i*Interval+1
+	Lines    int     // total amount of lines in the source
+	Size     int64   // size of the source in bytes
+	ModTime  int64   // modification time of the source in unix nanoseconds, 0 if unknown
+}
+
+// BuildLineIndex reads all data from r and builds a LineIndex with one entry every interval lines.
+func BuildLineIndex(ctx context.Context, r io.Reader, interval int) (*LineIndex, error) {
+	if interval <= 0 {
+		interval = DefaultLineIndexInterval
+	}
+	ret := &LineIndex{
+		Interval: interval,
+		Offsets:  []int64{0},
+	}
+
+	buf := make([]byte, 64*1024)
+	var offset int64
+	lastByte := byte('\n')
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		n, err := r.Read(buf)
+		data := buf[:n]
+		for len(data) > 0 {
+			idx := bytes.IndexByte(data, '\n')
+			if idx < 0 {
+				break
+			}
+			ret.Lines++
+			if ret.Lines%interval == 0 {
+				ret.Offsets = append(ret.Offsets, offset+int64(idx)+1)
+			}
+			offset += int64(idx) + 1
+			data = data[idx+1:]
+		}
+		offset += int64(len(data))
+		if n > 0 {
+			lastByte = buf[n-1]
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	if lastByte != '\n' {
+		// last line without line break
+		ret.Lines++
+	} else if ret.Lines > 0 && ret.Lines%interval == 0 {
+		// the last entry points to the end of the file
+		ret.Offsets = ret.Offsets[:len(ret.Offsets)-1]
+	}
+	ret.Size = offset
+	return ret, nil
+}
+
+// Lookup returns the nearest indexed line number which is less or equal to lineNo, and its byte offset.
+func (i *LineIndex) Lookup(lineNo int) (int, int64) {
+	if lineNo < 1 || len(i.Offsets) == 0 {
+		return 1, 0
+	}
+	entry := (lineNo - 1) / i.Interval
+	if entry >= len(i.Offsets) {
+		entry = len(i.Offsets) - 1
+	}
+	return entry*i.Interval + 1, i.Offsets[entry]
+}
+
+// LookupOffset returns the nearest indexed line number which starts at or before offset, and its byte offset.
+func (i *LineIndex) LookupOffset(offset int64) (int, int64) {
+	entry := sort.Search(len(i.Offsets), func(idx int) bool {
+		return i.Offsets[idx] > offset
+	}) - 1
+	if entry < 0 {
+		return 1, 0
+	}
+	return entry*i.Interval + 1, i.Offsets[entry]
+}
+
+// WriteTo writes the index in binary format.
+func (i *LineIndex) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+	var n int64
+	write := func(data []byte) error {
+		wn, err := bw.Write(data)
+		n += int64(wn)
+		return err
+	}
+
+	if err := write(lineIndexMagic[:]); err != nil {
+		return n, err
+	}
+	var vbuf [binary.MaxVarintLen64]byte
+	for _, v := range []int64{int64(i.Interval), int64(i.Lines), i.Size, i.ModTime, int64(len(i.Offsets))} {
+		if err := write(vbuf[:binary.PutVarint(vbuf[:], v)]); err != nil {
+			return n, err
+		}
+	}
+	var last int64
+	for _, offset := range i.Offsets {
+		// offsets are delta-encoded
+		if err := write(vbuf[:binary.PutUvarint(vbuf[:], uint64(offset-last))]); err != nil {
+			return n, err
+		}
+		last = offset
+	}
+	return n, bw.Flush()
+}
+
+// ReadLineIndex reads an index written by LineIndex.WriteTo.
+func ReadLineIndex(r io.Reader) (*LineIndex, error) {
+	br := bufio.NewReader(r)
+
+	var magic [8]byte
+	if _, err := io.ReadFull(br, magic[:]); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrLineIndexInvalid, err)
+	}
+	if magic != lineIndexMagic {
+		return nil, fmt.Errorf("%w: invalid header", ErrLineIndexInvalid)
+	}
+
+	var header [5]int64
+	for idx := range header {
+		v, err := binary.ReadVarint(br)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrLineIndexInvalid, err)
+		}
+		header[idx] = v
+	}
+	if header[0] <= 0 || header[4] < 0 {
+		return nil, fmt.Errorf("%w: invalid header", ErrLineIndexInvalid)
+	}
+
+	ret := &LineIndex{
+		Interval: int(header[0]),
+		Lines:    int(header[1]),
+		Size:     header[2],
+		ModTime:  header[3],
+	}
+	var last int64
+	for range header[4] {
+		delta, err := binary.ReadUvarint(br)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrLineIndexInvalid, err)
+		}
+		last += int64(delta)
+		ret.Offsets = append(ret.Offsets, last)
+	}
+	return ret, nil
+}
+
+// LoadLineIndex loads the line index for a file from its sidecar file (filename + LineIndexSidecarExt).
+// If the sidecar file doesn't exist or doesn't match the current file size and modification time, the index is
+// built and the sidecar file is written. Failing to write the sidecar file, like in a read-only directory, is not an
+// error, the index built is returned and the failure is logged to the context slog logger.
+func LoadLineIndex(ctx context.Context, filename string, interval int) (*LineIndex, error) {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	sidecarFilename := filename + LineIndexSidecarExt
+	if idx, err := readLineIndexFile(sidecarFilename); err == nil &&
+		idx.Size == info.Size() && idx.ModTime == info.ModTime().UnixNano() &&
+		(interval <= 0 || idx.Interval == interval) {
+		return idx, nil
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	idx, err := BuildLineIndex(ctx, f, interval)
+	if err != nil {
+		return nil, err
+	}
+	idx.ModTime = info.ModTime().UnixNano()
+
+	if err := writeLineIndexFile(sidecarFilename, idx); err != nil {
+		SLogFromContext(ctx).WarnContext(ctx, "error writing line index file",
+			slog.String("filename", sidecarFilename), slog.Any("error", err))
+	}
+	return idx, nil
+}
+
+func readLineIndexFile(filename string) (*LineIndex, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadLineIndex(f)
+}
+
+func writeLineIndexFile(filename string, idx *LineIndex) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if _, err := idx.WriteTo(f); err != nil {
+		_ = f.Close()
+		_ = os.Remove(filename)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(filename)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package panyl
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+)
+
+// DefaultReverseBlockSize is the default size of the blocks read by ReverseLineProvider.
+const DefaultReverseBlockSize = 64 * 1024
+
+// ReverseLineProvider is a LineProvider that reads lines from the end of an io.ReaderAt to its start, in blocks.
+// Use it with the WithReverse JobOption.
+// If a LineIndex is available, line numbers are calculated from it, otherwise they are counted backwards as negative
+// numbers, -1 being the last line.
+type ReverseLineProvider struct {
+	r           io.ReaderAt
+	blockSize   int
+	maxLineSize int
+	totalLines  int
+	pos         int64  // offset of the start of buf in the source
+	buf         []byte // data not yet returned as lines
+	done        bool
+	count       int
+	line        string
+	offset      int64
+	err         error
+}
+
+var _ LineProvider = (*ReverseLineProvider)(nil)
+var _ LineProviderPosition = (*ReverseLineProvider)(nil)
+
+// NewReverseLineProvider is a LineProvider that reads lines from the end of an io.ReaderAt with size bytes.
+// blockSize and maxLineSize may be 0 to use the defaults. index is optional.
+func NewReverseLineProvider(r io.ReaderAt, size int64, blockSize int, maxLineSize int,
+	index *LineIndex) *ReverseLineProvider {
+	if blockSize <= 0 {
+		blockSize = DefaultReverseBlockSize
+	}
+	if maxLineSize <= 0 {
+		maxLineSize = DefaultScannerBufferSize
+	}
+	ret := &ReverseLineProvider{
+		r:           r,
+		blockSize:   blockSize,
+		maxLineSize: maxLineSize,
+		pos:         size,
+		done:        size == 0,
+	}
+	if index != nil {
+		ret.totalLines = index.Lines
+	}
+	return ret
+}
+
+func (r *ReverseLineProvider) Err() error {
+	return r.err
+}
+
+func (r *ReverseLineProvider) Line() any {
+	return r.line
+}
+
+// LineNo returns the line number of the current line.
+func (r *ReverseLineProvider) LineNo() int {
+	if r.totalLines > 0 {
+		return r.totalLines - r.count + 1
+	}
+	return -r.count
+}
+
+// Offset returns the byte offset of the current line.
+func (r *ReverseLineProvider) Offset() int64 {
+	return r.offset
+}
+
+func (r *ReverseLineProvider) Scan(ctx context.Context) bool {
+	if r.err != nil || r.done {
+		return false
+	}
+
+	if r.count == 0 {
+		// ignore the line break at the end of the last line
+		if err := r.readBlock(); err != nil {
+			r.err = err
+			return false
+		}
+		if len(r.buf) > 0 && r.buf[len(r.buf)-1] == '\n' {
+			r.buf = r.buf[:len(r.buf)-1]
+		}
+	}
+
+	for {
+		if idx := bytes.LastIndexByte(r.buf, '\n'); idx >= 0 {
+			r.setLine(r.buf[idx+1:], r.pos+int64(idx)+1)
+			r.buf = r.buf[:idx]
+			return true
+		}
+		if r.pos == 0 {
+			r.setLine(r.buf, 0)
+			r.buf = nil
+			r.done = true
+			return true
+		}
+		if err := r.readBlock(); err != nil {
+			r.err = err
+			return false
+		}
+		if len(r.buf) > r.maxLineSize {
+			r.err = fmt.Errorf("line at offset %d: %w", r.pos, bufio.ErrTooLong)
+			return false
+		}
+	}
+}
+
+func (r *ReverseLineProvider) setLine(line []byte, offset int64) {
+	r.line = string(bytes.TrimSuffix(line, []byte{'\r'}))
+	r.offset = offset
+	r.count++
+}
+
+// readBlock reads the block before the current position.
+func (r *ReverseLineProvider) readBlock() error {
+	size := int64(r.blockSize)
+	if size > r.pos {
+		size = r.pos
+	}
+	block := make([]byte, int(size), int(size)+len(r.buf))
+	if _, err := r.r.ReadAt(block, r.pos-size); err != nil && err != io.EOF {
+		return err
+	}
+	r.buf = append(block, r.buf...)
+	r.pos -= size
+	return nil
+}
@@ -0,0 +1,166 @@
+package panyl
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// SeekLineProvider is a LineProvider that reads from an io.ReadSeeker, allowing to seek to a byte offset or to a
+// line number. Using a LineIndex allows seeking to a line number without reading all previous lines.
+type SeekLineProvider struct {
+	r           io.ReadSeeker
+	reader      *bufio.Reader
+	maxLineSize int
+	index       *LineIndex
+	lineNo      int
+	offset      int64
+	nextOffset  int64
+	line        string
+	buf         []byte
+	err         error
+}
+
+var _ LineProvider = (*SeekLineProvider)(nil)
+var _ LineProviderSeekLine = (*SeekLineProvider)(nil)
+
+// ErrLineIndexRequired is returned when seeking to a byte offset without a LineIndex, as the line numbers after the
+// offset would be unknown.
+var ErrLineIndexRequired = errors.New("line index required")
+
+// NewSeekLineProvider is a LineProvider that reads from an io.ReadSeeker. maxLineSize limits the size of a line,
+// use 0 for DefaultScannerBufferSize. index is optional.
+func NewSeekLineProvider(r io.ReadSeeker, maxLineSize int, index *LineIndex) *SeekLineProvider {
+	if maxLineSize <= 0 {
+		maxLineSize = DefaultScannerBufferSize
+	}
+	return &SeekLineProvider{
+		r:           r,
+		reader:      bufio.NewReader(r),
+		maxLineSize: maxLineSize,
+		index:       index,
+	}
+}
+
+// SeekLine positions the provider so the next call to Scan returns the line lineNo (1-based).
+func (r *SeekLineProvider) SeekLine(lineNo int) error {
+	startLineNo, offset := 1, int64(0)
+	if r.index != nil {
+		startLineNo, offset = r.index.Lookup(lineNo)
+	}
+	if err := r.seek(offset, startLineNo-1); err != nil {
+		return err
+	}
+	for r.lineNo < lineNo-1 {
+		if _, err := r.readLine(); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			r.err = err
+			return err
+		}
+	}
+	return nil
+}
+
+// SeekOffset positions the provider at the first line starting at or after the byte offset. Line numbers are
+// calculated from the LineIndex, so ErrLineIndexRequired is returned if there is none and offset is not 0.
+func (r *SeekLineProvider) SeekOffset(offset int64) error {
+	if offset <= 0 {
+		return r.seek(0, 0)
+	}
+	if r.index == nil {
+		return ErrLineIndexRequired
+	}
+	startLineNo, startOffset := r.index.LookupOffset(offset)
+	if err := r.seek(startOffset, startLineNo-1); err != nil {
+		return err
+	}
+	for r.nextOffset < offset {
+		if _, err := r.readLine(); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			r.err = err
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *SeekLineProvider) Err() error {
+	return r.err
+}
+
+func (r *SeekLineProvider) Line() any {
+	return r.line
+}
+
+// LineNo returns the line number of the current line.
+func (r *SeekLineProvider) LineNo() int {
+	return r.lineNo
+}
+
+// Offset returns the byte offset of the current line.
+func (r *SeekLineProvider) Offset() int64 {
+	return r.offset
+}
+
+func (r *SeekLineProvider) Scan(ctx context.Context) bool {
+	if r.err != nil {
+		return false
+	}
+	offset := r.nextOffset
+	line, err := r.readLine()
+	if err != nil {
+		if !errors.Is(err, io.EOF) {
+			r.err = err
+		}
+		return false
+	}
+	r.offset = offset
+	r.line = string(line)
+	return true
+}
+
+func (r *SeekLineProvider) seek(offset int64, lineNo int) error {
+	if _, err := r.r.Seek(offset, io.SeekStart); err != nil {
+		r.err = err
+		return err
+	}
+	r.reader.Reset(r.r)
+	r.err = nil
+	r.offset = offset
+	r.nextOffset = offset
+	r.lineNo = lineNo
+	r.line = ""
+	return nil
+}
+
+// readLine reads the next line, without the line break. It returns io.EOF only if there is no more data.
+func (r *SeekLineProvider) readLine() ([]byte, error) {
+	r.buf = r.buf[:0]
+	for {
+		data, err := r.reader.ReadSlice('\n')
+		r.buf = append(r.buf, data...)
+		if len(r.buf) > r.maxLineSize {
+			return nil, fmt.Errorf("line %d: %w", r.lineNo+1, bufio.ErrTooLong)
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		} else if errors.Is(err, io.EOF) {
+			if len(r.buf) == 0 {
+				return nil, io.EOF
+			}
+		} else if err != nil {
+			return nil, err
+		}
+		break
+	}
+	r.nextOffset += int64(len(r.buf))
+	r.lineNo++
+	return bytes.TrimSuffix(bytes.TrimSuffix(r.buf, []byte{'\n'}), []byte{'\r'}), nil
+}
@@ -1,7 +1,10 @@
 package panyl
 
 import (
+	"bytes"
 	"context"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -47,3 +50,152 @@ func TestLineProvider_Static(t *testing.T) {
 	assert.NoError(t, lp.Err())
 	assert.Equal(t, 3, ct, "should have 3 lines")
 }
+
+func TestLineProvider_Seek(t *testing.T) {
+	ctx := context.Background()
+
+	data := "first\nsecond\r\nthird\nfourth\nfifth"
+
+	index, err := BuildLineIndex(ctx, strings.NewReader(data), 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{0, 14, 27}, index.Offsets)
+	assert.Equal(t, 5, index.Lines)
+
+	for _, tt := range []struct {
+		name  string
+		index *LineIndex
+	}{
+		{"without index", nil},
+		{"with index", index},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			lp := NewSeekLineProvider(strings.NewReader(data), 0, tt.index)
+
+			assert.NoError(t, lp.SeekLine(3))
+			assert.True(t, lp.Scan(ctx))
+			assert.Equal(t, "third", lp.Line())
+			assert.Equal(t, 3, lp.LineNo())
+			assert.Equal(t, int64(14), lp.Offset())
+			assert.True(t, lp.Scan(ctx))
+			assert.True(t, lp.Scan(ctx))
+			assert.Equal(t, "fifth", lp.Line())
+			assert.Equal(t, 5, lp.LineNo())
+			assert.Equal(t, int64(27), lp.Offset())
+			assert.False(t, lp.Scan(ctx))
+			assert.NoError(t, lp.Err())
+
+			if tt.index == nil {
+				// the line numbers after the offset are unknown without an index
+				assert.ErrorIs(t, lp.SeekOffset(8), ErrLineIndexRequired)
+
+				assert.NoError(t, lp.SeekOffset(0))
+				assert.True(t, lp.Scan(ctx))
+				assert.Equal(t, "first", lp.Line())
+				assert.Equal(t, 1, lp.LineNo())
+				return
+			}
+
+			assert.NoError(t, lp.SeekOffset(8))
+			assert.True(t, lp.Scan(ctx))
+			assert.Equal(t, "third", lp.Line())
+			assert.Equal(t, int64(14), lp.Offset())
+			assert.Equal(t, 3, lp.LineNo())
+
+			assert.NoError(t, lp.SeekOffset(6))
+			assert.True(t, lp.Scan(ctx))
+			assert.Equal(t, "second", lp.Line())
+			assert.Equal(t, 2, lp.LineNo())
+		})
+	}
+}
+
+func TestLineIndex_ReadWrite(t *testing.T) {
+	ctx := context.Background()
+
+	index, err := BuildLineIndex(ctx, strings.NewReader(strings.Repeat("line\n", 100)), 10)
+	assert.NoError(t, err)
+	assert.Len(t, index.Offsets, 10)
+
+	var buf bytes.Buffer
+	_, err = index.WriteTo(&buf)
+	assert.NoError(t, err)
+
+	readIndex, err := ReadLineIndex(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, index, readIndex)
+
+	_, err = ReadLineIndex(strings.NewReader("invalid"))
+	assert.ErrorIs(t, err, ErrLineIndexInvalid)
+}
+
+func TestLoadLineIndex(t *testing.T) {
+	ctx := context.Background()
+
+	filename := filepath.Join(t.TempDir(), "test.log")
+	assert.NoError(t, os.WriteFile(filename, []byte(strings.Repeat("line\n", 100)), 0o600))
+
+	index, err := LoadLineIndex(ctx, filename, 10)
+	assert.NoError(t, err)
+	assert.FileExists(t, filename+LineIndexSidecarExt)
+
+	loadedIndex, err := LoadLineIndex(ctx, filename, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, index, loadedIndex)
+
+	lineNo, offset := loadedIndex.Lookup(55)
+	assert.Equal(t, 51, lineNo)
+	assert.Equal(t, int64(250), offset)
+}
+
+func TestLoadLineIndex_WriteError(t *testing.T) {
+	ctx := context.Background()
+
+	filename := filepath.Join(t.TempDir(), "test.log")
+	assert.NoError(t, os.WriteFile(filename, []byte(strings.Repeat("line\n", 100)), 0o600))
+	// the sidecar file can't be created, as a directory exists with its name
+	assert.NoError(t, os.Mkdir(filename+LineIndexSidecarExt, 0o700))
+
+	index, err := LoadLineIndex(ctx, filename, 10)
+	if assert.NoError(t, err) {
+		lineNo, offset := index.Lookup(55)
+		assert.Equal(t, 51, lineNo)
+		assert.Equal(t, int64(250), offset)
+	}
+}
+
+func TestLineProvider_Reverse(t *testing.T) {
+	ctx := context.Background()
+
+	data := "first\nsecond\r\nthird\n\nfifth\n"
+
+	index, err := BuildLineIndex(ctx, strings.NewReader(data), 2)
+	assert.NoError(t, err)
+
+	for _, tt := range []struct {
+		name      string
+		blockSize int
+		index     *LineIndex
+		lineNos   []int
+	}{
+		{"small blocks", 3, nil, []int{-1, -2, -3, -4, -5}},
+		{"large blocks", 0, nil, []int{-1, -2, -3, -4, -5}},
+		{"with index", 3, index, []int{5, 4, 3, 2, 1}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			lp := NewReverseLineProvider(strings.NewReader(data), int64(len(data)), tt.blockSize, 0, tt.index)
+
+			var lines []string
+			var lineNos []int
+			var offsets []int64
+			for lp.Scan(ctx) {
+				lines = append(lines, lp.Line().(string))
+				lineNos = append(lineNos, lp.LineNo())
+				offsets = append(offsets, lp.Offset())
+			}
+			assert.NoError(t, lp.Err())
+			assert.Equal(t, []string{"fifth", "", "third", "second", "first"}, lines)
+			assert.Equal(t, tt.lineNos, lineNos)
+			assert.Equal(t, []int64{21, 20, 14, 6, 0}, offsets)
+		})
+	}
+}
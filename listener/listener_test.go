@@ -0,0 +1,256 @@
+package listener
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RangelReale/panyl/v2"
+	"github.com/RangelReale/panyl/v2/plugins/structure"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamLineProvider(t *testing.T) {
+	peer := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 5140}
+
+	for _, tt := range []struct {
+		name           string
+		framing        Framing
+		maxMessageSize int
+		input          string
+		expected       []string
+	}{
+		{"newline", FramingNewline, 16, "first\r\nsecond\n\nthird", []string{"first", "second", "", "third"}},
+		{"octet counting", FramingOctetCounting, 16, "5 first12 second line\n",
+			[]string{"first", "second line"}},
+		{"auto", FramingAuto, 16, "12 <13>message\n12 not syslog\n8 <13>last",
+			[]string{"<13>message", "12 not syslog", "<13>last"}},
+		{"truncated", FramingOctetCounting, 8, "12 <13>message!5 after", []string{"<13>mess", "after"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			lp := NewStreamLineProvider(strings.NewReader(tt.input), peer, WithFraming(tt.framing),
+				WithMaxMessageSize(tt.maxMessageSize))
+			var lines []string
+			for lp.Scan(context.Background()) {
+				item := lp.Line().(*panyl.Item)
+				assert.Equal(t, "127.0.0.1:5140", item.Metadata[panyl.MetadataPeerAddress])
+				lines = append(lines, item.Line)
+			}
+			assert.NoError(t, lp.Err())
+			assert.Equal(t, tt.expected, lines)
+		})
+	}
+}
+
+func TestStreamLineProvider_InvalidOctetCount(t *testing.T) {
+	lp := NewStreamLineProvider(strings.NewReader("abc message"), nil, WithFraming(FramingOctetCounting))
+	assert.False(t, lp.Scan(context.Background()))
+	assert.ErrorContains(t, lp.Err(), "invalid octet count")
+}
+
+func TestServer_TCP(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	res := &panyl.OutputArray{}
+	server := NewServer(panyl.NewProcessor(panyl.WithPlugins(&structure.JSON{})), res,
+		WithJobOptions(panyl.WithMaxIncompleteBacklogLines(10)))
+	served := make(chan error)
+	go func() {
+		served <- server.ServeTCP(ctx, l)
+	}()
+
+	conn1, err := net.Dial("tcp", l.Addr().String())
+	if !assert.NoError(t, err) {
+		return
+	}
+	conn2, err := net.Dial("tcp", l.Addr().String())
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// the lines of the connections are interleaved, but the JSON object is still joined
+	_, _ = conn1.Write([]byte("{\n"))
+	_, _ = conn2.Write([]byte("plain\n"))
+	time.Sleep(50 * time.Millisecond)
+	_, _ = conn1.Write([]byte("\"a\": 1\n}\n"))
+	_ = conn1.Close()
+	time.Sleep(50 * time.Millisecond)
+
+	// the connection left open finishes when the server stops
+	cancel()
+	assert.NoError(t, <-served)
+	server.Close(context.Background())
+
+	if assert.Len(t, res.List, 2) {
+		byPeer := map[string]*panyl.Item{}
+		for _, item := range res.List {
+			byPeer[item.Metadata.StringValue(panyl.MetadataPeerAddress)] = item
+		}
+		assert.Equal(t, float64(1), byPeer[conn1.LocalAddr().String()].Data["a"])
+		assert.Equal(t, "plain", byPeer[conn2.LocalAddr().String()].Line)
+	}
+	_ = conn2.Close()
+}
+
+func TestServer_UDP(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	res := &panyl.OutputArray{}
+	server := NewServer(panyl.NewProcessor(), res, WithPeerTimeout(100*time.Millisecond))
+	served := make(chan error)
+	go func() {
+		served <- server.ServeUDP(ctx, conn)
+	}()
+
+	peer1, err := net.Dial("udp", conn.LocalAddr().String())
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer peer1.Close()
+	peer2, err := net.Dial("udp", conn.LocalAddr().String())
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer peer2.Close()
+
+	_, _ = peer1.Write([]byte("<13>first\n<13>second"))
+	_, _ = peer2.Write([]byte("<13>other\n"))
+	// wait for the peer jobs to time out
+	time.Sleep(300 * time.Millisecond)
+	_, _ = peer1.Write([]byte("<13>third"))
+	time.Sleep(50 * time.Millisecond)
+
+	cancel()
+	assert.NoError(t, <-served)
+	server.Close(context.Background())
+
+	lines := map[string][]string{}
+	for _, item := range res.List {
+		peer := item.Metadata.StringValue(panyl.MetadataPeerAddress)
+		lines[peer] = append(lines[peer], item.Line)
+	}
+	assert.Equal(t, map[string][]string{
+		peer1.LocalAddr().String(): {"<13>first", "<13>second", "<13>third"},
+		peer2.LocalAddr().String(): {"<13>other"},
+	}, lines)
+}
+
+func TestPacketLineProvider(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	peer, err := net.Dial("udp", conn.LocalAddr().String())
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer peer.Close()
+	_, _ = peer.Write([]byte("first\r\nsecond\n"))
+
+	lp := NewPacketLineProvider(conn)
+	var lines []string
+	for i := 0; i < 2 && lp.Scan(ctx); i++ {
+		item := lp.Line().(*panyl.Item)
+		assert.Equal(t, peer.LocalAddr().String(), item.Metadata[panyl.MetadataPeerAddress])
+		lines = append(lines, item.Line)
+	}
+	assert.Equal(t, []string{"first", "second"}, lines)
+
+	// the context stops waiting for datagrams
+	cancel()
+	assert.False(t, lp.Scan(ctx))
+	assert.ErrorIs(t, lp.Err(), context.Canceled)
+}
+
+func TestServer_UDPDropped(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	var m sync.Mutex
+	droppedPeers := map[string]int{}
+	res := &blockingOutput{release: make(chan struct{})}
+	server := NewServer(panyl.NewProcessor(), res, WithMaxPeers(1),
+		WithOnDropped(func(ctx context.Context, peer string, items int) {
+			m.Lock()
+			defer m.Unlock()
+			droppedPeers[peer] += items
+		}))
+	served := make(chan error)
+	go func() {
+		served <- server.ServeUDP(ctx, conn)
+	}()
+
+	peer1, err := net.Dial("udp", conn.LocalAddr().String())
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer peer1.Close()
+	peer2, err := net.Dial("udp", conn.LocalAddr().String())
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer peer2.Close()
+
+	// the output is blocked, so the Job of the first peer can't receive all the items
+	_, _ = peer1.Write([]byte(strings.Repeat("line\n", 2000)))
+	time.Sleep(50 * time.Millisecond)
+	// the maximum amount of peers was reached
+	_, _ = peer2.Write([]byte("other\n"))
+	time.Sleep(50 * time.Millisecond)
+
+	cancel()
+	close(res.release)
+	assert.NoError(t, <-served)
+	server.Close(context.Background())
+
+	m.Lock()
+	defer m.Unlock()
+	assert.Equal(t, 1, droppedPeers[peer2.LocalAddr().String()])
+	assert.Greater(t, droppedPeers[peer1.LocalAddr().String()], 0)
+	assert.Equal(t, int64(droppedPeers[peer1.LocalAddr().String()]+1), server.Dropped())
+	assert.Len(t, res.List, 2000-droppedPeers[peer1.LocalAddr().String()])
+}
+
+// blockingOutput is an OutputArray whose OnItem waits until release is closed
+type blockingOutput struct {
+	panyl.OutputArray
+	release chan struct{}
+}
+
+func (o *blockingOutput) OnItem(ctx context.Context, item *panyl.Item) bool {
+	<-o.release
+	return o.OutputArray.OnItem(ctx, item)
+}
+
+func TestNewConfig_PeerTimeout(t *testing.T) {
+	assert.Equal(t, DefaultPeerTimeout, newConfig(WithPeerTimeout(0)).peerTimeout)
+	assert.Equal(t, DefaultPeerTimeout, newConfig(WithPeerTimeout(-time.Second)).peerTimeout)
+	assert.Equal(t, time.Second, newConfig(WithPeerTimeout(time.Second)).peerTimeout)
+}
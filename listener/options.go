@@ -0,0 +1,96 @@
+package listener
+
+import (
+	"context"
+	"time"
+
+	"github.com/RangelReale/panyl/v2"
+)
+
+const DefaultPeerTimeout = time.Minute
+
+// DefaultMaxPeers is the default maximum amount of UDP peers processed at the same time.
+const DefaultMaxPeers = 1024
+
+// config holds the options of the line providers and of the Server.
+type config struct {
+	framing        Framing
+	maxMessageSize int
+	peerTimeout    time.Duration
+	maxPeers       int
+	jobOptions     []panyl.JobOption
+	onError        func(ctx context.Context, err error)
+	onDropped      func(ctx context.Context, peer string, items int)
+}
+
+func newConfig(options ...Option) config {
+	ret := config{
+		framing:        FramingAuto,
+		maxMessageSize: DefaultMaxMessageSize,
+		peerTimeout:    DefaultPeerTimeout,
+		maxPeers:       DefaultMaxPeers,
+	}
+	for _, opt := range options {
+		opt(&ret)
+	}
+	if ret.peerTimeout <= 0 {
+		// the read deadline and the peer sweep depend on a positive timeout
+		ret.peerTimeout = DefaultPeerTimeout
+	}
+	return ret
+}
+
+type Option func(*config)
+
+// WithFraming sets the framing of stream messages, by default FramingAuto.
+func WithFraming(framing Framing) Option {
+	return func(c *config) {
+		c.framing = framing
+	}
+}
+
+// WithMaxMessageSize sets the maximum size of a message, by default DefaultMaxMessageSize.
+func WithMaxMessageSize(maxMessageSize int) Option {
+	return func(c *config) {
+		c.maxMessageSize = maxMessageSize
+	}
+}
+
+// WithPeerTimeout sets how long the Server keeps the Job of an UDP peer which is not sending messages, by default
+// DefaultPeerTimeout, which is also used if the value is not positive.
+func WithPeerTimeout(peerTimeout time.Duration) Option {
+	return func(c *config) {
+		c.peerTimeout = peerTimeout
+	}
+}
+
+// WithMaxPeers sets the maximum amount of UDP peers the Server processes at the same time, by default
+// DefaultMaxPeers. The messages of new peers are dropped while the limit is reached. Use 0 for no limit.
+func WithMaxPeers(maxPeers int) Option {
+	return func(c *config) {
+		c.maxPeers = maxPeers
+	}
+}
+
+// WithJobOptions sets the options of the Jobs created by the Server.
+func WithJobOptions(options ...panyl.JobOption) Option {
+	return func(c *config) {
+		c.jobOptions = append(c.jobOptions, options...)
+	}
+}
+
+// WithOnError sets a callback for the errors of the Jobs created by the Server. By default they are logged to the
+// context slog logger.
+func WithOnError(onError func(ctx context.Context, err error)) Option {
+	return func(c *config) {
+		c.onError = onError
+	}
+}
+
+// WithOnDropped sets a callback for the items of UDP peers which the Server drops, either because the Job of the
+// peer is not keeping up or because the maximum amount of peers was reached.
+func WithOnDropped(onDropped func(ctx context.Context, peer string, items int)) Option {
+	return func(c *config) {
+		c.onDropped = onDropped
+	}
+}
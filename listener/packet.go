@@ -0,0 +1,87 @@
+package listener
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/RangelReale/panyl/v2"
+)
+
+// PacketLineProvider is a panyl.LineProvider which reads datagrams from a connection, like an UDP socket,
+// returning a *panyl.Item for each line of each datagram with MetadataPeerAddress set.
+// All peers are returned in the same Job, use Server.ServeUDP to have one Job for each peer.
+type PacketLineProvider struct {
+	conn  net.PacketConn
+	buf   []byte
+	items []*panyl.Item
+	item  *panyl.Item
+	err   error
+}
+
+var _ panyl.LineProvider = (*PacketLineProvider)(nil)
+
+// NewPacketLineProvider creates a PacketLineProvider reading from conn. The WithMaxMessageSize option is used.
+// Scan returns false without error if the connection is closed.
+func NewPacketLineProvider(conn net.PacketConn, options ...Option) *PacketLineProvider {
+	cfg := newConfig(options...)
+	return &PacketLineProvider{
+		conn: conn,
+		buf:  make([]byte, cfg.maxMessageSize),
+	}
+}
+
+func (p *PacketLineProvider) Err() error {
+	return p.err
+}
+
+func (p *PacketLineProvider) Line() any {
+	return p.item
+}
+
+func (p *PacketLineProvider) Scan(ctx context.Context) bool {
+	if len(p.items) == 0 && p.err == nil {
+		// unblock the read when the context is done
+		stop := context.AfterFunc(ctx, func() {
+			_ = p.conn.SetReadDeadline(time.Unix(1, 0))
+		})
+		defer stop()
+	}
+	for len(p.items) == 0 {
+		if p.err != nil {
+			return false
+		}
+		if err := ctx.Err(); err != nil {
+			p.err = err
+			return false
+		}
+		n, addr, err := p.conn.ReadFrom(p.buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				p.err = ctx.Err()
+			} else if !errors.Is(err, net.ErrClosed) {
+				p.err = err
+			}
+			return false
+		}
+		p.items = packetItems(p.buf[:n], peerAddress(addr))
+	}
+	p.item = p.items[0]
+	p.items[0] = nil
+	p.items = p.items[1:]
+	return true
+}
+
+// packetItems returns an item for each non-empty line of a datagram.
+func packetItems(data []byte, peer string) []*panyl.Item {
+	var ret []*panyl.Item
+	for _, line := range bytes.Split(data, []byte{'\n'}) {
+		line = bytes.TrimRight(line, "\r\x00")
+		if len(line) > 0 {
+			ret = append(ret, newItem(line, peer))
+		}
+	}
+	return ret
+}
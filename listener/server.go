@@ -0,0 +1,238 @@
+package listener
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/RangelReale/panyl/v2"
+	"github.com/RangelReale/panyl/v2/output"
+)
+
+// Server processes lines received from the network using a Processor. Each TCP connection and each UDP peer is
+// processed in its own Job, so multi-line items from different senders are never mixed.
+// All the Jobs send the items to the same Output, with the calls serialized. The Jobs only flush the Output, call
+// Close after all the Serve methods return to close it.
+type Server struct {
+	processor *panyl.Processor
+	output    *output.Shared
+	dropped   atomic.Int64
+	config
+}
+
+// NewServer creates a Server processing the lines with processor and sending the items to out.
+func NewServer(processor *panyl.Processor, out panyl.Output, options ...Option) *Server {
+	return &Server{
+		processor: processor,
+		output:    output.NewShared(out),
+		config:    newConfig(options...),
+	}
+}
+
+// ServeTCP accepts connections on l until the context is done or the listener is closed, reading the messages
+// with StreamLineProvider. When the context is done the listener is closed, and the connections stop reading and
+// output any item left. ServeTCP returns after all the connections finish.
+func (s *Server) ServeTCP(ctx context.Context, l net.Listener) error {
+	stop := context.AfterFunc(ctx, func() {
+		_ = l.Close()
+	})
+	defer stop()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.serveConn(ctx, conn)
+		}()
+	}
+}
+
+func (s *Server) serveConn(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetReadDeadline(time.Unix(1, 0))
+	})
+	defer stop()
+
+	provider := NewStreamLineProvider(conn, conn.RemoteAddr(), WithFraming(s.framing),
+		WithMaxMessageSize(s.maxMessageSize))
+	s.process(ctx, &stopLineProvider{LineProvider: provider, ctx: ctx})
+}
+
+// ServeUDP reads datagrams from conn until the context is done or the connection is closed, creating a Job for
+// each peer address. The Job of a peer finishes after it doesn't send messages for the peer timeout. ServeUDP
+// returns after all the Jobs finish.
+// Reading never waits for the Jobs, so a slow peer doesn't stall the others: the items of a peer whose Job is not
+// keeping up are dropped, as are the messages of new peers while the maximum amount of peers is reached.
+func (s *Server) ServeUDP(ctx context.Context, conn net.PacketConn) error {
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetReadDeadline(time.Unix(1, 0))
+	})
+	defer stop()
+
+	var wg sync.WaitGroup
+	peers := map[string]*udpPeer{}
+	defer func() {
+		for _, peer := range peers {
+			close(peer.items)
+		}
+		wg.Wait()
+	}()
+
+	lastSweep := time.Now()
+	sweep := func(now time.Time) {
+		for key, peer := range peers {
+			if now.Sub(peer.lastSeen) >= s.peerTimeout {
+				close(peer.items)
+				delete(peers, key)
+			}
+		}
+		lastSweep = now
+	}
+
+	buf := make([]byte, s.maxMessageSize)
+	for {
+		// the deadline allows finishing idle peers while no messages are received
+		if err := conn.SetReadDeadline(time.Now().Add(s.peerTimeout)); err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		n, addr, err := conn.ReadFrom(buf)
+		now := time.Now()
+		if err != nil {
+			var netErr net.Error
+			switch {
+			case ctx.Err() != nil || errors.Is(err, net.ErrClosed):
+				return nil
+			case !errors.As(err, &netErr) || !netErr.Timeout():
+				return err
+			}
+		} else if items := packetItems(buf[:n], peerAddress(addr)); len(items) > 0 {
+			key := addr.String()
+			peer, ok := peers[key]
+			if !ok && s.maxPeers > 0 && len(peers) >= s.maxPeers {
+				sweep(now)
+			}
+			if !ok && (s.maxPeers <= 0 || len(peers) < s.maxPeers) {
+				peer = &udpPeer{items: make(chan *panyl.Item, udpPeerBufferSize)}
+				peers[key] = peer
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					s.process(ctx, &chanLineProvider{items: peer.items})
+				}()
+			}
+			if peer == nil {
+				s.drop(ctx, key, len(items))
+			} else {
+				peer.lastSeen = now
+				if dropped := peer.send(items); dropped > 0 {
+					s.drop(ctx, key, dropped)
+				}
+			}
+		}
+
+		if now.Sub(lastSweep) >= s.peerTimeout/2 {
+			sweep(now)
+		}
+	}
+}
+
+// Dropped returns the amount of UDP items dropped by the Server.
+func (s *Server) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+func (s *Server) drop(ctx context.Context, peer string, items int) {
+	s.dropped.Add(int64(items))
+	if s.onDropped != nil {
+		s.onDropped(ctx, peer, items)
+	}
+}
+
+// Close closes the Output.
+func (s *Server) Close(ctx context.Context) {
+	s.output.Close(ctx)
+}
+
+// process runs a Job. The Job doesn't use the cancellation of the context, so the items left are still output
+// when it is done.
+func (s *Server) process(ctx context.Context, provider panyl.LineProvider) {
+	jobCtx := context.WithoutCancel(ctx)
+	if err := s.processor.ProcessProvider(jobCtx, provider, s.output, s.jobOptions...); err != nil {
+		if s.onError != nil {
+			s.onError(jobCtx, err)
+			return
+		}
+		panyl.SLogFromContext(jobCtx).WarnContext(jobCtx, "error processing network lines", slog.Any("error", err))
+	}
+}
+
+// udpPeerBufferSize is the amount of items of an UDP peer waiting for its Job.
+const udpPeerBufferSize = 1024
+
+type udpPeer struct {
+	items    chan *panyl.Item
+	lastSeen time.Time
+}
+
+// send queues the items for the Job without blocking, returning the amount of items which didn't fit.
+func (p *udpPeer) send(items []*panyl.Item) int {
+	for i, item := range items {
+		select {
+		case p.items <- item:
+		default:
+			return len(items) - i
+		}
+	}
+	return 0
+}
+
+// chanLineProvider returns the items received from a channel until it is closed.
+type chanLineProvider struct {
+	items chan *panyl.Item
+	item  *panyl.Item
+}
+
+func (c *chanLineProvider) Err() error {
+	return nil
+}
+
+func (c *chanLineProvider) Line() any {
+	return c.item
+}
+
+func (c *chanLineProvider) Scan(ctx context.Context) bool {
+	var ok bool
+	c.item, ok = <-c.items
+	return ok
+}
+
+// stopLineProvider ignores the read error caused by the context being done.
+type stopLineProvider struct {
+	panyl.LineProvider
+	ctx context.Context
+}
+
+func (s *stopLineProvider) Err() error {
+	if s.ctx.Err() != nil {
+		return nil
+	}
+	return s.LineProvider.Err()
+}
@@ -0,0 +1,178 @@
+// Package listener receives lines from the network, allowing a Processor to work as a lightweight syslog receiver.
+package listener
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+
+	"github.com/RangelReale/panyl/v2"
+)
+
+// Framing is how messages are delimited in a stream, as described by RFC 6587.
+type Framing int
+
+const (
+	// FramingAuto detects the framing of each message. Messages starting with a length, a space and a syslog
+	// priority ("<") use octet counting, all others use newlines.
+	FramingAuto Framing = iota
+	// FramingNewline delimits messages with a newline ("non-transparent framing").
+	FramingNewline
+	// FramingOctetCounting prefixes each message with its length in bytes and a space.
+	FramingOctetCounting
+)
+
+const DefaultMaxMessageSize = 64 * 1024
+
+// maxOctetCountDigits is the maximum amount of digits of an octet count.
+const maxOctetCountDigits = 10
+
+// StreamLineProvider is a panyl.LineProvider which reads messages from a stream, like a TCP connection, returning
+// a *panyl.Item for each one with MetadataPeerAddress set.
+// Messages longer than the maximum message size are split in newline framing, and truncated in octet counting
+// framing.
+type StreamLineProvider struct {
+	r              *bufio.Reader
+	peer           string
+	framing        Framing
+	maxMessageSize int
+	item           *panyl.Item
+	err            error
+}
+
+var _ panyl.LineProvider = (*StreamLineProvider)(nil)
+
+// NewStreamLineProvider creates a StreamLineProvider reading from r. The peer address may be nil.
+// The WithFraming and WithMaxMessageSize options are used.
+func NewStreamLineProvider(r io.Reader, peer net.Addr, options ...Option) *StreamLineProvider {
+	cfg := newConfig(options...)
+	return &StreamLineProvider{
+		r:              bufio.NewReaderSize(r, cfg.maxMessageSize),
+		peer:           peerAddress(peer),
+		framing:        cfg.framing,
+		maxMessageSize: cfg.maxMessageSize,
+	}
+}
+
+func (s *StreamLineProvider) Err() error {
+	return s.err
+}
+
+func (s *StreamLineProvider) Line() any {
+	return s.item
+}
+
+func (s *StreamLineProvider) Scan(ctx context.Context) bool {
+	if s.err != nil {
+		return false
+	}
+	if err := ctx.Err(); err != nil {
+		s.err = err
+		return false
+	}
+
+	var message []byte
+	var err error
+	if s.isOctetCounting() {
+		message, err = s.readOctetCounting()
+	} else {
+		message, err = s.readNewline()
+	}
+	if err != nil && (!errors.Is(err, io.EOF) || len(message) == 0) {
+		if !errors.Is(err, io.EOF) {
+			s.err = err
+		}
+		return false
+	}
+	s.item = newItem(message, s.peer)
+	return true
+}
+
+// isOctetCounting returns whether the next message uses octet counting framing.
+func (s *StreamLineProvider) isOctetCounting() bool {
+	switch s.framing {
+	case FramingNewline:
+		return false
+	case FramingOctetCounting:
+		return true
+	}
+	// FramingAuto, only peek one byte at a time to avoid waiting for more data than the message
+	for i := 0; i <= maxOctetCountDigits; i++ {
+		peek, err := s.r.Peek(i + 1)
+		if err != nil {
+			return false
+		}
+		if c := peek[i]; c >= '0' && c <= '9' {
+			continue
+		} else if c != ' ' || i == 0 {
+			return false
+		}
+		peek, err = s.r.Peek(i + 2)
+		return err == nil && peek[i+1] == '<'
+	}
+	return false
+}
+
+// readNewline reads a message until a newline, or the maximum message size.
+func (s *StreamLineProvider) readNewline() ([]byte, error) {
+	line, err := s.r.ReadSlice('\n')
+	if errors.Is(err, bufio.ErrBufferFull) {
+		err = nil
+	}
+	line = bytes.TrimRight(line, "\r\n\x00")
+	return bytes.Clone(line), err
+}
+
+// readOctetCounting reads a message prefixed by its length.
+func (s *StreamLineProvider) readOctetCounting() ([]byte, error) {
+	count, err := s.r.ReadSlice(' ')
+	if err != nil {
+		if errors.Is(err, io.EOF) && len(bytes.TrimSpace(count)) == 0 {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("invalid octet count %q: %w", truncate(count, maxOctetCountDigits), err)
+	}
+	length, err := strconv.Atoi(string(count[:len(count)-1]))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid octet count %q", truncate(count, maxOctetCountDigits))
+	}
+
+	message := make([]byte, min(length, s.maxMessageSize))
+	if _, err := io.ReadFull(s.r, message); err != nil {
+		return nil, fmt.Errorf("error reading message of %d bytes: %w", length, err)
+	}
+	if length > len(message) {
+		if _, err := s.r.Discard(length - len(message)); err != nil {
+			return nil, fmt.Errorf("error reading message of %d bytes: %w", length, err)
+		}
+	}
+	return bytes.TrimRight(message, "\r\n\x00"), nil
+}
+
+// newItem creates an item with the message as its line.
+func newItem(message []byte, peer string) *panyl.Item {
+	item := panyl.InitItem(panyl.WithInitLine(string(message)))
+	if peer != "" {
+		item.Metadata[panyl.MetadataPeerAddress] = peer
+	}
+	return item
+}
+
+func peerAddress(peer net.Addr) string {
+	if peer == nil {
+		return ""
+	}
+	return peer.String()
+}
+
+func truncate(data []byte, size int) string {
+	if len(data) > size {
+		return string(data[:size]) + "..."
+	}
+	return string(data)
+}
@@ -15,6 +15,18 @@ const (
 	MetadataExtraCategories     = "extra_categories"  // a list of extra categories to log to
 	MetadataCreated             = "created"           // bool [whether the process was created instead of being in the log file]
 	MetadataSkip                = "skip"              // bool [if true, the line will be skipped]
+	MetadataErrors              = "errors"            // []string [plugin errors ignored by ErrorPolicyMetadata]
+	MetadataTraceID             = "trace_id"          // string [lowercase hex trace id, like W3C trace-context]
+	MetadataSpanID              = "span_id"           // string [lowercase hex span id]
+	MetadataParentSpanID        = "parent_span_id"    // string [lowercase hex parent span id]
+	MetadataRequestID           = "request_id"        // string
+	MetadataPattern             = "pattern"           // string [message template with the variable tokens masked]
+	MetadataPatternID           = "pattern_id"        // string [stable id of the message template]
+	MetadataRepeatCount         = "repeat_count"      // int [amount of repeated items collapsed into a created item]
+	MetadataRepeatFirst         = "repeat_first"      // time.Time [timestamp of the first repeated item]
+	MetadataRepeatLast          = "repeat_last"       // time.Time [timestamp of the last repeated item]
+	MetadataSampleDropped       = "sample_dropped"    // int [amount of items dropped by sampling before a created item]
+	MetadataPeerAddress         = "peer_address"      // string [network address of the sender of the line]
 )
 
 const (
@@ -28,6 +40,7 @@ const (
 	MetadataLevelINFO    = "info"
 	MetadataLevelWARNING = "warn"
 	MetadataLevelERROR   = "error"
+	MetadataLevelFATAL   = "fatal"
 )
 
 const (
@@ -1,6 +1,9 @@
 package panyl
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 const (
 	PostProcessOrderFirst   = 0
@@ -20,6 +23,38 @@ func WithLineLimit(startLine, lineAmount int) JobOption {
 	}
 }
 
+// WithTimeRange outputs only items with MetadataTimestamp in the [from, to) range. A zero time means no limit.
+// Processing stops with ErrFinished when an item with a timestamp later than to+tolerance is found (or earlier than
+// from-tolerance in reverse mode), the tolerance allows for out-of-order lines.
+func WithTimeRange(from, to time.Time, tolerance time.Duration) JobOption {
+	return func(p *Job) {
+		p.TimeFrom = from
+		p.TimeTo = to
+		p.TimeTolerance = tolerance
+	}
+}
+
+// WithReverse sets whether the lines are received in reverse order, from the last to the first, like the ones
+// returned by ReverseLineProvider. Multi-line items are detected in the source order, and items are output
+// from the last to the first. In reverse mode WithLineLimit applies to the amount of lines read.
+func WithReverse(reverse bool) JobOption {
+	return func(p *Job) {
+		p.Reverse = reverse
+	}
+}
+
+// WithConcurrency sets the amount of goroutines used to call the Clean and Metadata plugins which implement
+// PluginConcurrencySafe when using Processor.ProcessProvider. The other phases are still executed in the line order,
+// and the output order is preserved. Values less than 2 disable concurrency.
+// When enabled, the OnPluginError callback may be called concurrently, and the lines are read ahead by another
+// goroutine. If processing stops before the end of the lines, ProcessProvider may return while a Scan call is still
+// pending, which is cancelled through the context.
+func WithConcurrency(concurrency int) JobOption {
+	return func(p *Job) {
+		p.Concurrency = concurrency
+	}
+}
+
 // WithMaxBacklogLines sets the maximum amount of unprocessed lines to try until giving up.
 // This is used to detect multiline logs.
 func WithMaxBacklogLines(maxBacklogLines int) JobOption {
@@ -28,6 +63,15 @@ func WithMaxBacklogLines(maxBacklogLines int) JobOption {
 	}
 }
 
+// WithMaxIncompleteBacklogLines sets the maximum amount of lines to keep in the backlog for a structure which a
+// PluginIncomplete plugin reports as incomplete, when the backlog exceeds the MaxBacklogLines amount.
+// The default is 0, which disables this check.
+func WithMaxIncompleteBacklogLines(maxIncompleteBacklogLines int) JobOption {
+	return func(p *Job) {
+		p.MaxIncompleteBacklogLines = maxIncompleteBacklogLines
+	}
+}
+
 // WithIncludeSource sets whether to set Item.Source with the source line.
 func WithIncludeSource(includeSource bool) JobOption {
 	return func(p *Job) {
@@ -35,6 +79,20 @@ func WithIncludeSource(includeSource bool) JobOption {
 	}
 }
 
+// WithErrorPolicy sets what to do when a plugin returns an error. The default is ErrorPolicyFailFast.
+func WithErrorPolicy(errorPolicy ErrorPolicy) JobOption {
+	return func(p *Job) {
+		p.ErrorPolicy = errorPolicy
+	}
+}
+
+// WithOnPluginError sets a callback to be called for each plugin error, regardless of the ErrorPolicy.
+func WithOnPluginError(f func(context.Context, *PluginError)) JobOption {
+	return func(p *Job) {
+		p.OnPluginError = f
+	}
+}
+
 // WithDebugLog sets a DebugLog to be used for debugging.
 func WithDebugLog(logger DebugLog) Option {
 	return func(p *Processor) {
@@ -51,7 +109,8 @@ func WithPlugins(plugin ...Plugin) Option {
 	}
 }
 
-// WithOnJobFinished sets a callback to be called when a Job is about to finish.
+// WithOnJobFinished sets a callback to be called when a Job is about to finish, after all the items were sent to the
+// output and before it is flushed and closed.
 func WithOnJobFinished(f func(context.Context, *Job) error) Option {
 	return func(p *Processor) {
 		p.onJobFinished = append(p.onJobFinished, f)
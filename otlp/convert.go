@@ -0,0 +1,209 @@
+package otlp
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/RangelReale/panyl/v2"
+)
+
+// Severity numbers of the start of each OpenTelemetry severity range.
+const (
+	SeverityUnspecified = 0
+	SeverityTrace       = 1
+	SeverityDebug       = 5
+	SeverityInfo        = 9
+	SeverityWarn        = 13
+	SeverityError       = 17
+	SeverityFatal       = 21
+)
+
+const (
+	// ScopeName is the instrumentation scope name of the converted items.
+	ScopeName = "panyl"
+
+	// AttributeServiceName is the resource attribute which receives MetadataApplication.
+	AttributeServiceName = "service.name"
+)
+
+// SeverityNumber returns the severity number of a MetadataLevel value, using panyl.LevelOrder.
+// Unknown levels return SeverityUnspecified.
+func SeverityNumber(level string) int {
+	switch panyl.LevelOrder(level) {
+	case panyl.LevelOrderTrace:
+		return SeverityTrace
+	case panyl.LevelOrderDebug:
+		return SeverityDebug
+	case panyl.LevelOrderInfo:
+		return SeverityInfo
+	case panyl.LevelOrderWarning:
+		return SeverityWarn
+	case panyl.LevelOrderError:
+		return SeverityError
+	case panyl.LevelOrderFatal:
+		return SeverityFatal
+	}
+	return SeverityUnspecified
+}
+
+// NewLogRecord converts an item to a LogRecord:
+//   - MetadataTimestamp to the timestamp, if it was not calculated, and observed as the observed timestamp
+//   - MetadataLevel to the severity number and text
+//   - MetadataMessage, or the Line if not set, to the body
+//   - Data to the attributes
+//   - MetadataTraceID and MetadataSpanID to the trace and span ids
+//
+// MetadataApplication is part of the resource, see NewLogsData.
+func NewLogRecord(item *panyl.Item, observed time.Time) LogRecord {
+	ret := LogRecord{
+		ObservedTimeUnixNano: Uint64(observed.UnixNano()),
+	}
+	if ts, ok := item.Metadata[panyl.MetadataTimestamp].(time.Time); ok &&
+		!item.Metadata.BoolValue(panyl.MetadataTimestampCalculated) {
+		ret.TimeUnixNano = Uint64(ts.UnixNano())
+	}
+	if level := item.Metadata.StringValue(panyl.MetadataLevel); level != "" {
+		ret.SeverityNumber = SeverityNumber(level)
+		ret.SeverityText = level
+	}
+	body := item.Metadata.StringValue(panyl.MetadataMessage)
+	if body == "" {
+		body = item.Line
+	}
+	if body != "" {
+		value := NewAnyValue(body)
+		ret.Body = &value
+	}
+	ret.Attributes = newKeyValues(item.Data)
+	ret.TraceID = item.Metadata.StringValue(panyl.MetadataTraceID)
+	ret.SpanID = item.Metadata.StringValue(panyl.MetadataSpanID)
+	return ret
+}
+
+// NewLogsData converts items to LogsData, with one ResourceLogs for each MetadataApplication, in the order they
+// were found.
+func NewLogsData(items []*panyl.Item, observed time.Time) LogsData {
+	var ret LogsData
+	resources := map[string]int{}
+	for _, item := range items {
+		application := item.Metadata.StringValue(panyl.MetadataApplication)
+		idx, ok := resources[application]
+		if !ok {
+			rl := ResourceLogs{
+				ScopeLogs: []ScopeLogs{{Scope: &Scope{Name: ScopeName}}},
+			}
+			if application != "" {
+				rl.Resource = &Resource{Attributes: []KeyValue{
+					{Key: AttributeServiceName, Value: NewAnyValue(application)},
+				}}
+			}
+			idx = len(ret.ResourceLogs)
+			resources[application] = idx
+			ret.ResourceLogs = append(ret.ResourceLogs, rl)
+		}
+		sl := &ret.ResourceLogs[idx].ScopeLogs[0]
+		sl.LogRecords = append(sl.LogRecords, NewLogRecord(item, observed))
+	}
+	return ret
+}
+
+// NewAnyValue converts a value, like the ones decoded from JSON, to AnyValue. Maps and slices are converted
+// recursively, time.Time to a RFC3339 string, and unknown types to their fmt representation. Unsigned integers
+// larger than the maximum int64 are converted to a decimal string, as intValue can't represent them.
+func NewAnyValue(value any) AnyValue {
+	switch v := value.(type) {
+	case string:
+		return AnyValue{StringValue: &v}
+	case bool:
+		return AnyValue{BoolValue: &v}
+	case float64:
+		return AnyValue{DoubleValue: &v}
+	case float32:
+		f := float64(v)
+		return AnyValue{DoubleValue: &f}
+	case uint, uint64:
+		u := reflect.ValueOf(v).Uint()
+		if u > math.MaxInt64 {
+			s := strconv.FormatUint(u, 10)
+			return AnyValue{StringValue: &s}
+		}
+		i := Int64(u)
+		return AnyValue{IntValue: &i}
+	case int, int8, int16, int32, int64, uint8, uint16, uint32:
+		i := Int64(reflect.ValueOf(v).Convert(reflect.TypeOf(int64(0))).Int())
+		return AnyValue{IntValue: &i}
+	case []byte:
+		return AnyValue{BytesValue: v}
+	case time.Time:
+		s := v.Format(time.RFC3339Nano)
+		return AnyValue{StringValue: &s}
+	case map[string]any:
+		return AnyValue{KvlistValue: &KvlistValue{Values: newKeyValues(v)}}
+	case panyl.MapValue:
+		return AnyValue{KvlistValue: &KvlistValue{Values: newKeyValues(v)}}
+	case []any:
+		arr := &ArrayValue{Values: make([]AnyValue, 0, len(v))}
+		for _, av := range v {
+			arr.Values = append(arr.Values, NewAnyValue(av))
+		}
+		return AnyValue{ArrayValue: arr}
+	case nil:
+		return AnyValue{}
+	}
+	s := fmt.Sprint(value)
+	return AnyValue{StringValue: &s}
+}
+
+// Value returns the Go value of the AnyValue, with the types used by encoding/json: maps are returned as
+// map[string]any and arrays as []any. Integers are returned as int64 and bytes as []byte.
+func (v AnyValue) Value() any {
+	switch {
+	case v.StringValue != nil:
+		return *v.StringValue
+	case v.BoolValue != nil:
+		return *v.BoolValue
+	case v.IntValue != nil:
+		return int64(*v.IntValue)
+	case v.DoubleValue != nil:
+		return *v.DoubleValue
+	case v.ArrayValue != nil:
+		ret := make([]any, 0, len(v.ArrayValue.Values))
+		for _, av := range v.ArrayValue.Values {
+			ret = append(ret, av.Value())
+		}
+		return ret
+	case v.KvlistValue != nil:
+		return KeyValuesMap(v.KvlistValue.Values)
+	case v.BytesValue != nil:
+		return v.BytesValue
+	}
+	return nil
+}
+
+// KeyValuesMap converts a list of KeyValue to a map.
+func KeyValuesMap(values []KeyValue) map[string]any {
+	ret := make(map[string]any, len(values))
+	for _, kv := range values {
+		ret[kv.Key] = kv.Value.Value()
+	}
+	return ret
+}
+
+// newKeyValues converts a map to a list of KeyValue sorted by key.
+func newKeyValues(m map[string]any) []KeyValue {
+	if len(m) == 0 {
+		return nil
+	}
+	ret := make([]KeyValue, 0, len(m))
+	for k, v := range m {
+		ret = append(ret, KeyValue{Key: k, Value: NewAnyValue(v)})
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Key < ret[j].Key
+	})
+	return ret
+}
@@ -0,0 +1,85 @@
+package otlp
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/RangelReale/panyl/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewLogsData(t *testing.T) {
+	ts := time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)
+	observed := ts.Add(time.Minute)
+
+	item := panyl.InitItem(panyl.WithInitLine("raw line"))
+	item.Metadata[panyl.MetadataTimestamp] = ts
+	item.Metadata[panyl.MetadataLevel] = "warning"
+	item.Metadata[panyl.MetadataMessage] = "disk almost full"
+	item.Metadata[panyl.MetadataApplication] = "api"
+	item.Metadata[panyl.MetadataTraceID] = "4bf92f3577b34da6a3ce929d0e0e4736"
+	item.Metadata[panyl.MetadataSpanID] = "00f067aa0ba902b7"
+	item.Data["disk"] = map[string]any{"used": 0.95, "tags": []any{"a", true}}
+	item.Data["count"] = 3
+
+	calculated := panyl.InitItem(panyl.WithInitLine("no timestamp"))
+	calculated.Metadata[panyl.MetadataTimestamp] = ts
+	calculated.Metadata[panyl.MetadataTimestampCalculated] = true
+
+	data := NewLogsData([]*panyl.Item{item, calculated}, observed)
+
+	encoded, err := json.Marshal(data)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"resourceLogs":[
+		{
+			"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"api"}}]},
+			"scopeLogs":[{"scope":{"name":"panyl"},"logRecords":[{
+				"timeUnixNano":"1709632800000000000",
+				"observedTimeUnixNano":"1709632860000000000",
+				"severityNumber":13,
+				"severityText":"warning",
+				"body":{"stringValue":"disk almost full"},
+				"attributes":[
+					{"key":"count","value":{"intValue":"3"}},
+					{"key":"disk","value":{"kvlistValue":{"values":[
+						{"key":"tags","value":{"arrayValue":{"values":[{"stringValue":"a"},{"boolValue":true}]}}},
+						{"key":"used","value":{"doubleValue":0.95}}
+					]}}}
+				],
+				"traceId":"4bf92f3577b34da6a3ce929d0e0e4736",
+				"spanId":"00f067aa0ba902b7"
+			}]}]
+		},
+		{
+			"scopeLogs":[{"scope":{"name":"panyl"},"logRecords":[{
+				"observedTimeUnixNano":"1709632860000000000",
+				"body":{"stringValue":"no timestamp"}
+			}]}]
+		}
+	]}`, string(encoded))
+
+	var decoded LogsData
+	assert.NoError(t, json.Unmarshal(encoded, &decoded))
+	assert.Equal(t, data, decoded)
+	assert.Equal(t, map[string]any{"count": int64(3), "disk": map[string]any{"used": 0.95, "tags": []any{"a", true}}},
+		KeyValuesMap(decoded.ResourceLogs[0].ScopeLogs[0].LogRecords[0].Attributes))
+}
+
+func TestSeverityNumber(t *testing.T) {
+	assert.Equal(t, SeverityTrace, SeverityNumber(panyl.MetadataLevelTRACE))
+	assert.Equal(t, SeverityInfo, SeverityNumber(panyl.MetadataLevelINFO))
+	assert.Equal(t, SeverityError, SeverityNumber("ERROR"))
+	assert.Equal(t, SeverityFatal, SeverityNumber("fatal"))
+	assert.Equal(t, SeverityUnspecified, SeverityNumber("verbose"))
+}
+
+func TestNewAnyValue_Unsigned(t *testing.T) {
+	assert.Equal(t, int64(42), NewAnyValue(uint64(42)).Value())
+	assert.Equal(t, int64(math.MaxInt64), NewAnyValue(uint64(math.MaxInt64)).Value())
+	// values which don't fit intValue are kept exactly as strings
+	assert.Equal(t, "18446744073709551615", NewAnyValue(uint64(math.MaxUint64)).Value())
+	assert.Equal(t, "9223372036854775808", NewAnyValue(uint(math.MaxInt64+1)).Value())
+	assert.Equal(t, int64(-1), NewAnyValue(int8(-1)).Value())
+}
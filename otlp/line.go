@@ -0,0 +1,157 @@
+package otlp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/RangelReale/panyl/v2"
+)
+
+// MetadataStructureOTLP is the MetadataStructure of the items read by LineProvider.
+const MetadataStructureOTLP = "otlp"
+
+// LineProvider is a panyl.LineProvider which reads OTLP JSON logs, returning a *panyl.Item for each log record.
+// The input can be a single JSON document, like the body of an OTLP/HTTP request, or many documents one after the
+// other, like the files written by the collector file exporter. See NewItem for how the records are converted.
+type LineProvider struct {
+	dec   *json.Decoder
+	items []*panyl.Item
+	item  *panyl.Item
+	err   error
+}
+
+var _ panyl.LineProvider = (*LineProvider)(nil)
+
+// NewLineProvider creates a LineProvider reading from r.
+func NewLineProvider(r io.Reader) *LineProvider {
+	return &LineProvider{
+		dec: json.NewDecoder(r),
+	}
+}
+
+func (l *LineProvider) Err() error {
+	return l.err
+}
+
+func (l *LineProvider) Line() any {
+	return l.item
+}
+
+func (l *LineProvider) Scan(ctx context.Context) bool {
+	for len(l.items) == 0 {
+		if l.err != nil {
+			return false
+		}
+		if err := ctx.Err(); err != nil {
+			l.err = err
+			return false
+		}
+		var data LogsData
+		if err := l.dec.Decode(&data); err != nil {
+			if !errors.Is(err, io.EOF) {
+				l.err = err
+			}
+			return false
+		}
+		l.items = NewItems(data)
+	}
+	l.item = l.items[0]
+	l.items[0] = nil
+	l.items = l.items[1:]
+	return true
+}
+
+// NewItems converts all the log records of the LogsData to items, using NewItem.
+func NewItems(data LogsData) []*panyl.Item {
+	var ret []*panyl.Item
+	for _, rl := range data.ResourceLogs {
+		for _, sl := range rl.ScopeLogs {
+			for _, record := range sl.LogRecords {
+				ret = append(ret, NewItem(rl.Resource, record))
+			}
+		}
+	}
+	return ret
+}
+
+// NewItem converts a log record to an item, the reverse of NewLogRecord:
+//   - the timestamp, or the observed timestamp if not set, to MetadataTimestamp
+//   - the severity text, or a level from the severity number if not set, to MetadataLevel
+//   - a string body to MetadataMessage and Line. Other body types are set in Data["body"], and as JSON in Line
+//   - the attributes to Data, merged with the resource attributes. Record attributes have precedence over
+//     resource attributes with the same name
+//   - the service.name resource attribute to MetadataApplication
+//   - the trace and span ids to MetadataTraceID and MetadataSpanID
+//
+// MetadataStructure is set to MetadataStructureOTLP, so the Job doesn't try to parse the items again.
+func NewItem(resource *Resource, record LogRecord) *panyl.Item {
+	item := panyl.InitItem()
+	item.Metadata[panyl.MetadataStructure] = MetadataStructureOTLP
+
+	item.Data.Merge(KeyValuesMap(record.Attributes))
+	if resource != nil && len(resource.Attributes) > 0 {
+		attributes := KeyValuesMap(resource.Attributes)
+		if application, ok := attributes[AttributeServiceName].(string); ok && application != "" {
+			item.Metadata[panyl.MetadataApplication] = application
+		}
+		// the record attributes are more specific, so they have precedence
+		item.Data.Merge(attributes)
+	}
+
+	if record.TimeUnixNano != 0 {
+		item.Metadata[panyl.MetadataTimestamp] = time.Unix(0, int64(record.TimeUnixNano)).UTC()
+	} else if record.ObservedTimeUnixNano != 0 {
+		item.Metadata[panyl.MetadataTimestamp] = time.Unix(0, int64(record.ObservedTimeUnixNano)).UTC()
+	}
+
+	if record.SeverityText != "" {
+		item.Metadata[panyl.MetadataLevel] = record.SeverityText
+	} else if level := SeverityLevel(record.SeverityNumber); level != "" {
+		item.Metadata[panyl.MetadataLevel] = level
+	}
+
+	if record.Body != nil {
+		switch body := record.Body.Value().(type) {
+		case string:
+			item.Metadata[panyl.MetadataMessage] = body
+			item.Line = body
+		case nil:
+		default:
+			item.Data["body"] = body
+			if line, err := json.Marshal(body); err == nil {
+				item.Line = string(line)
+			}
+		}
+	}
+
+	if record.TraceID != "" {
+		item.Metadata[panyl.MetadataTraceID] = strings.ToLower(record.TraceID)
+	}
+	if record.SpanID != "" {
+		item.Metadata[panyl.MetadataSpanID] = strings.ToLower(record.SpanID)
+	}
+	return item
+}
+
+// SeverityLevel returns the MetadataLevel of a severity number, or an empty string for SeverityUnspecified.
+func SeverityLevel(severityNumber int) string {
+	switch {
+	case severityNumber >= SeverityFatal:
+		return panyl.MetadataLevelFATAL
+	case severityNumber >= SeverityError:
+		return panyl.MetadataLevelERROR
+	case severityNumber >= SeverityWarn:
+		return panyl.MetadataLevelWARNING
+	case severityNumber >= SeverityInfo:
+		return panyl.MetadataLevelINFO
+	case severityNumber >= SeverityDebug:
+		return panyl.MetadataLevelDEBUG
+	case severityNumber >= SeverityTrace:
+		return panyl.MetadataLevelTRACE
+	}
+	return ""
+}
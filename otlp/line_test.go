@@ -0,0 +1,87 @@
+package otlp
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RangelReale/panyl/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLineProvider(t *testing.T) {
+	ctx := context.Background()
+
+	// two documents, like the collector file exporter writes
+	input := `{"resourceLogs":[{
+		"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"api"}},
+			{"key":"host.name","value":{"stringValue":"host1"}}]},
+		"scopeLogs":[{"scope":{"name":"app"},"logRecords":[
+			{"timeUnixNano":"1709632800000000000","severityNumber":13,"severityText":"WARN",
+				"body":{"stringValue":"disk almost full"},
+				"attributes":[{"key":"disk.used","value":{"doubleValue":0.95}},{"key":"count","value":{"intValue":"3"}},
+					{"key":"resource","value":{"stringValue":"disk"}},{"key":"host.name","value":{"stringValue":"pod1"}}],
+				"traceId":"4BF92F3577B34DA6A3CE929D0E0E4736","spanId":"00f067aa0ba902b7"},
+			{"observedTimeUnixNano":1709632860000000000,"severityNumber":17,
+				"body":{"kvlistValue":{"values":[{"key":"event","value":{"stringValue":"failed"}}]}}}
+		]}]}]}
+{"resourceLogs":[{"scopeLogs":[{"logRecords":[{"body":{"stringValue":""}}]}]}]}
+`
+
+	p := panyl.NewProcessor()
+	res := &panyl.OutputArray{}
+	err := p.ProcessProvider(ctx, NewLineProvider(strings.NewReader(input)), res)
+	assert.NoError(t, err)
+
+	if assert.Len(t, res.List, 3) {
+		item := res.List[0]
+		assert.Equal(t, 1, item.LineNo)
+		assert.Equal(t, "disk almost full", item.Line)
+		assert.Equal(t, MetadataStructureOTLP, item.Metadata[panyl.MetadataStructure])
+		assert.Equal(t, "disk almost full", item.Metadata[panyl.MetadataMessage])
+		assert.Equal(t, "WARN", item.Metadata[panyl.MetadataLevel])
+		assert.Equal(t, "api", item.Metadata[panyl.MetadataApplication])
+		assert.Equal(t, time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC), item.Metadata[panyl.MetadataTimestamp])
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", item.Metadata[panyl.MetadataTraceID])
+		assert.Equal(t, "00f067aa0ba902b7", item.Metadata[panyl.MetadataSpanID])
+		assert.Equal(t, 0.95, item.Data["disk.used"])
+		assert.Equal(t, int64(3), item.Data["count"])
+		// resource attributes are merged, without overwriting the record attributes
+		assert.Equal(t, "disk", item.Data["resource"])
+		assert.Equal(t, "pod1", item.Data["host.name"])
+		assert.Equal(t, "api", item.Data["service.name"])
+
+		item = res.List[1]
+		assert.Equal(t, "host1", item.Data["host.name"])
+		assert.Equal(t, `{"event":"failed"}`, item.Line)
+		assert.Equal(t, panyl.MetadataLevelERROR, item.Metadata[panyl.MetadataLevel])
+		assert.Equal(t, time.Date(2024, 3, 5, 10, 1, 0, 0, time.UTC), item.Metadata[panyl.MetadataTimestamp])
+		assert.Equal(t, map[string]any{"event": "failed"}, item.Data["body"])
+
+		// records with an empty body are not skipped
+		item = res.List[2]
+		assert.Equal(t, "", item.Line)
+		assert.Equal(t, 3, item.LineNo)
+	}
+}
+
+func TestLineProvider_InvalidJSON(t *testing.T) {
+	lp := NewLineProvider(strings.NewReader(`{"resourceLogs":[]} {invalid`))
+	assert.False(t, lp.Scan(context.Background()))
+	assert.Error(t, lp.Err())
+}
+
+func TestSeverityLevel(t *testing.T) {
+	for severityNumber, expected := range map[int]string{
+		SeverityUnspecified: "",
+		SeverityTrace:       panyl.MetadataLevelTRACE,
+		8:                   panyl.MetadataLevelDEBUG,
+		SeverityInfo:        panyl.MetadataLevelINFO,
+		SeverityWarn + 2:    panyl.MetadataLevelWARNING,
+		SeverityError:       panyl.MetadataLevelERROR,
+		24:                  panyl.MetadataLevelFATAL,
+	} {
+		assert.Equal(t, expected, SeverityLevel(severityNumber), "severity number %d", severityNumber)
+	}
+}
@@ -0,0 +1,112 @@
+// Package otlp converts items to and from the OpenTelemetry logs data model, using the OTLP JSON encoding.
+package otlp
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// LogsData is the OTLP JSON representation of ExportLogsServiceRequest and LogsData.
+type LogsData struct {
+	ResourceLogs []ResourceLogs `json:"resourceLogs"`
+}
+
+type ResourceLogs struct {
+	Resource  *Resource   `json:"resource,omitempty"`
+	ScopeLogs []ScopeLogs `json:"scopeLogs"`
+	SchemaURL string      `json:"schemaUrl,omitempty"`
+}
+
+type Resource struct {
+	Attributes []KeyValue `json:"attributes,omitempty"`
+}
+
+type ScopeLogs struct {
+	Scope      *Scope      `json:"scope,omitempty"`
+	LogRecords []LogRecord `json:"logRecords"`
+	SchemaURL  string      `json:"schemaUrl,omitempty"`
+}
+
+type Scope struct {
+	Name       string     `json:"name,omitempty"`
+	Version    string     `json:"version,omitempty"`
+	Attributes []KeyValue `json:"attributes,omitempty"`
+}
+
+type LogRecord struct {
+	TimeUnixNano         Uint64     `json:"timeUnixNano,omitempty"`
+	ObservedTimeUnixNano Uint64     `json:"observedTimeUnixNano,omitempty"`
+	SeverityNumber       int        `json:"severityNumber,omitempty"`
+	SeverityText         string     `json:"severityText,omitempty"`
+	Body                 *AnyValue  `json:"body,omitempty"`
+	Attributes           []KeyValue `json:"attributes,omitempty"`
+	Flags                uint32     `json:"flags,omitempty"`
+	TraceID              string     `json:"traceId,omitempty"` // lowercase hex
+	SpanID               string     `json:"spanId,omitempty"`  // lowercase hex
+}
+
+type KeyValue struct {
+	Key   string   `json:"key"`
+	Value AnyValue `json:"value"`
+}
+
+// AnyValue holds one of its fields, like the protobuf oneof.
+type AnyValue struct {
+	StringValue *string      `json:"stringValue,omitempty"`
+	BoolValue   *bool        `json:"boolValue,omitempty"`
+	IntValue    *Int64       `json:"intValue,omitempty"`
+	DoubleValue *float64     `json:"doubleValue,omitempty"`
+	ArrayValue  *ArrayValue  `json:"arrayValue,omitempty"`
+	KvlistValue *KvlistValue `json:"kvlistValue,omitempty"`
+	BytesValue  []byte       `json:"bytesValue,omitempty"` // base64 in JSON
+}
+
+type ArrayValue struct {
+	Values []AnyValue `json:"values"`
+}
+
+type KvlistValue struct {
+	Values []KeyValue `json:"values"`
+}
+
+// Uint64 is encoded as a JSON string, as required by OTLP for 64-bit integers, and decoded from a string or a
+// number.
+type Uint64 uint64
+
+func (v Uint64) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strconv.FormatUint(uint64(v), 10))
+}
+
+func (v *Uint64) UnmarshalJSON(data []byte) error {
+	n, err := strconv.ParseUint(unquoteNumber(data), 10, 64)
+	if err != nil {
+		return err
+	}
+	*v = Uint64(n)
+	return nil
+}
+
+// Int64 is encoded as a JSON string, as required by OTLP for 64-bit integers, and decoded from a string or a
+// number.
+type Int64 int64
+
+func (v Int64) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strconv.FormatInt(int64(v), 10))
+}
+
+func (v *Int64) UnmarshalJSON(data []byte) error {
+	n, err := strconv.ParseInt(unquoteNumber(data), 10, 64)
+	if err != nil {
+		return err
+	}
+	*v = Int64(n)
+	return nil
+}
+
+func unquoteNumber(data []byte) string {
+	s := string(data)
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,207 @@
+package otlp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RangelReale/panyl/v2"
+)
+
+const (
+	DefaultBatchSize  = 512
+	DefaultMaxRetries = 3
+	DefaultBackoff    = 500 * time.Millisecond
+)
+
+// Output exports items to an OTLP/HTTP logs endpoint using the JSON encoding, in batches of DefaultBatchSize
+// items. The batch is also sent on OnFlush and OnClose.
+// Requests failing with the status codes considered retryable by OTLP (429, 502, 503 and 504) or network errors are
+// retried with exponential backoff. Errors after the retries are sent to the error callback, or logged to the
+// context slog logger.
+type Output struct {
+	url        string
+	client     *http.Client
+	header     http.Header
+	gzip       bool
+	batchSize  int
+	maxRetries int
+	backoff    time.Duration
+	onError    func(ctx context.Context, err error)
+
+	batch []*panyl.Item
+	m     sync.Mutex
+}
+
+var _ panyl.Output = (*Output)(nil)
+
+// NewOutput creates an Output exporting to the OTLP/HTTP endpoint at url, like "http://localhost:4318". The
+// "/v1/logs" path is added to the url.
+func NewOutput(url string, options ...OutputOption) *Output {
+	ret := &Output{
+		url:        strings.TrimSuffix(url, "/") + "/v1/logs",
+		client:     http.DefaultClient,
+		batchSize:  DefaultBatchSize,
+		maxRetries: DefaultMaxRetries,
+		backoff:    DefaultBackoff,
+	}
+	for _, opt := range options {
+		opt(ret)
+	}
+	return ret
+}
+
+type OutputOption func(*Output)
+
+// WithHTTPClient sets the HTTP client, http.DefaultClient by default.
+func WithHTTPClient(client *http.Client) OutputOption {
+	return func(o *Output) {
+		o.client = client
+	}
+}
+
+// WithHeader sets extra request headers, like authorization.
+func WithHeader(header http.Header) OutputOption {
+	return func(o *Output) {
+		o.header = header
+	}
+}
+
+// WithGzip compresses the requests with gzip.
+func WithGzip(gzip bool) OutputOption {
+	return func(o *Output) {
+		o.gzip = gzip
+	}
+}
+
+// WithBatchSize sets the amount of items sent in each request.
+func WithBatchSize(batchSize int) OutputOption {
+	return func(o *Output) {
+		o.batchSize = batchSize
+	}
+}
+
+// WithRetry sets the maximum amount of retries and the initial backoff, which doubles on each retry.
+func WithRetry(maxRetries int, backoff time.Duration) OutputOption {
+	return func(o *Output) {
+		o.maxRetries = maxRetries
+		o.backoff = backoff
+	}
+}
+
+// WithOnError sets a callback for errors sending the items.
+func WithOnError(onError func(ctx context.Context, err error)) OutputOption {
+	return func(o *Output) {
+		o.onError = onError
+	}
+}
+
+func (o *Output) OnItem(ctx context.Context, item *panyl.Item) bool {
+	o.m.Lock()
+	defer o.m.Unlock()
+
+	o.batch = append(o.batch, item)
+	if len(o.batch) >= o.batchSize {
+		o.flush(ctx)
+	}
+	return ctx.Err() == nil
+}
+
+func (o *Output) OnFlush(ctx context.Context) {
+	o.m.Lock()
+	defer o.m.Unlock()
+	o.flush(ctx)
+}
+
+func (o *Output) OnClose(ctx context.Context) {
+	o.OnFlush(ctx)
+}
+
+// flush sends the batch, retrying on failure.
+func (o *Output) flush(ctx context.Context) {
+	if len(o.batch) == 0 {
+		return
+	}
+	data := NewLogsData(o.batch, time.Now())
+	o.batch = nil
+
+	body, err := json.Marshal(data)
+	if err == nil && o.gzip {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err = w.Write(body); err == nil {
+			err = w.Close()
+		}
+		body = buf.Bytes()
+	}
+	if err != nil {
+		o.handleError(ctx, err)
+		return
+	}
+
+	backoff := o.backoff
+	for retry := 0; ; retry++ {
+		retryable, err := o.send(ctx, body)
+		if err == nil {
+			return
+		}
+		if !retryable || retry >= o.maxRetries || ctx.Err() != nil {
+			o.handleError(ctx, err)
+			return
+		}
+		select {
+		case <-ctx.Done():
+			o.handleError(ctx, err)
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (o *Output) send(ctx context.Context, body []byte) (retryable bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	for name, values := range o.header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if o.gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		switch resp.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout:
+			retryable = true
+		}
+		return retryable, fmt.Errorf("export request returned status %s: %s", resp.Status,
+			strings.TrimSpace(string(respBody)))
+	}
+	return false, nil
+}
+
+func (o *Output) handleError(ctx context.Context, err error) {
+	if o.onError != nil {
+		o.onError(ctx, err)
+		return
+	}
+	panyl.SLogFromContext(ctx).WarnContext(ctx, "error exporting items to OTLP", slog.Any("error", err))
+}
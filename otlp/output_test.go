@@ -0,0 +1,52 @@
+package otlp
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/RangelReale/panyl/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutput(t *testing.T) {
+	ctx := context.Background()
+
+	var requests []LogsData
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/logs", r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+		if len(requests) == 0 {
+			requests = append(requests, LogsData{})
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		gr, err := gzip.NewReader(r.Body)
+		assert.NoError(t, err)
+		var data LogsData
+		assert.NoError(t, json.NewDecoder(gr).Decode(&data))
+		requests = append(requests, data)
+	}))
+	defer server.Close()
+
+	o := NewOutput(server.URL, WithGzip(true), WithBatchSize(2), WithRetry(2, time.Millisecond))
+	for _, app := range []string{"api", "db", "api"} {
+		item := panyl.InitItem(panyl.WithInitLine("line"))
+		item.Metadata[panyl.MetadataApplication] = app
+		o.OnItem(ctx, item)
+	}
+	o.OnClose(ctx)
+
+	// the first request is retried
+	if assert.Len(t, requests, 3) {
+		assert.Len(t, requests[1].ResourceLogs, 2)
+		if assert.Len(t, requests[2].ResourceLogs, 1) {
+			assert.Len(t, requests[2].ResourceLogs[0].ScopeLogs[0].LogRecords, 1)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package output
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RangelReale/panyl/v2"
+)
+
+// Alert is a triggered alert rule.
+type Alert struct {
+	Rule    string
+	Time    time.Time // the timestamp of the item that triggered the alert
+	Message string
+	Group   string        // the group of a ThresholdRule with GroupBy, joined by "/"
+	Count   int           // the amount of items that triggered the alert
+	Items   []*panyl.Item // the items that triggered the alert
+}
+
+// AlertRule evaluates items, returning an Alert when it is triggered.
+// Rules are called in the item order, and must use the item timestamp instead of the wall clock.
+type AlertRule interface {
+	Evaluate(ctx context.Context, item *panyl.Item, ts time.Time) *Alert
+}
+
+// AlertNotifier sends the triggered alerts.
+type AlertNotifier interface {
+	Notify(ctx context.Context, alert *Alert) error
+}
+
+// AlertNotifierFunc is a helper to use AlertNotifier as a function.
+type AlertNotifierFunc func(ctx context.Context, alert *Alert) error
+
+func (f AlertNotifierFunc) Notify(ctx context.Context, alert *Alert) error {
+	return f(ctx, alert)
+}
+
+// AlertMatch selects items for an AlertRule.
+type AlertMatch func(item *panyl.Item) bool
+
+// MatchMinLevel matches items with level at least level, according to panyl.LevelOrder.
+func MatchMinLevel(level string) AlertMatch {
+	order := panyl.LevelOrder(level)
+	return func(item *panyl.Item) bool {
+		return panyl.LevelOrder(item.Metadata.StringValue(panyl.MetadataLevel)) >= order
+	}
+}
+
+// MatchMetadata matches items with the Metadata value name equal to value.
+func MatchMetadata(name, value string) AlertMatch {
+	return func(item *panyl.Item) bool {
+		return item.Metadata.StringValue(name) == value
+	}
+}
+
+// MatchAll matches items that match all the matches.
+func MatchAll(matches ...AlertMatch) AlertMatch {
+	return func(item *panyl.Item) bool {
+		for _, m := range matches {
+			if !m(item) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// ThresholdRule triggers when more than Count items selected by Match (all items if nil) are found within Window.
+// If GroupBy is set, the items are counted separately for each combination of these Metadata values.
+// After triggering the count starts again. Groups without items within the window are removed.
+type ThresholdRule struct {
+	Name    string
+	Match   AlertMatch
+	Count   int
+	Window  time.Duration
+	GroupBy []string
+
+	groups    map[string][]*panyl.Item
+	lastSweep time.Time // last time the expired groups were removed
+}
+
+var _ AlertRule = (*ThresholdRule)(nil)
+
+func (r *ThresholdRule) Evaluate(ctx context.Context, item *panyl.Item, ts time.Time) *Alert {
+	if r.Match != nil && !r.Match(item) {
+		return nil
+	}
+	if r.groups == nil {
+		r.groups = map[string][]*panyl.Item{}
+	}
+
+	var group []string
+	for _, name := range r.GroupBy {
+		group = append(group, item.Metadata.StringValue(name))
+	}
+	key := strings.Join(group, "/")
+
+	start := ts.Add(-r.Window)
+	r.sweep(ts, start)
+
+	// remove the items outside the window
+	items := r.groups[key]
+	i := 0
+	for i < len(items) && !alertItemTime(items[i]).After(start) {
+		i++
+	}
+	items = append(items[i:], item)
+
+	if len(items) <= r.Count {
+		r.groups[key] = items
+		return nil
+	}
+	delete(r.groups, key)
+	return &Alert{
+		Rule:    r.Name,
+		Time:    ts,
+		Message: fmt.Sprintf("%d items within %s", len(items), r.Window),
+		Group:   key,
+		Count:   len(items),
+		Items:   items,
+	}
+}
+
+// sweep removes the groups whose items are all before start, at most once per window.
+func (r *ThresholdRule) sweep(ts, start time.Time) {
+	if ts.Sub(r.lastSweep) < r.Window {
+		return
+	}
+	r.lastSweep = ts
+	for key, items := range r.groups {
+		if !alertItemTime(items[len(items)-1]).After(start) {
+			delete(r.groups, key)
+		}
+	}
+}
+
+// PatternRule triggers for each item selected by Match (all items if nil) whose message matches Pattern.
+// The message is read from MetadataMessage, or from the Line if not set.
+type PatternRule struct {
+	Name    string
+	Match   AlertMatch
+	Pattern *regexp.Regexp
+}
+
+var _ AlertRule = (*PatternRule)(nil)
+
+func (r *PatternRule) Evaluate(ctx context.Context, item *panyl.Item, ts time.Time) *Alert {
+	if r.Match != nil && !r.Match(item) {
+		return nil
+	}
+	message := item.Metadata.StringValue(panyl.MetadataMessage)
+	if message == "" {
+		message = item.Line
+	}
+	if !r.Pattern.MatchString(message) {
+		return nil
+	}
+	return &Alert{
+		Rule:    r.Name,
+		Time:    ts,
+		Message: message,
+		Count:   1,
+		Items:   []*panyl.Item{item},
+	}
+}
+
+// Alerting is an Output wrapper that evaluates alert rules for each item, sending the triggered alerts to the
+// notifiers. Notifiers are called synchronously, in the order they were added.
+// All items are also sent unchanged to the wrapped output, if not nil.
+type Alerting struct {
+	output    panyl.Output
+	rules     []AlertRule
+	notifiers []AlertNotifier
+	onError   func(ctx context.Context, alert *Alert, err error)
+	m         sync.Mutex
+}
+
+var _ panyl.Output = (*Alerting)(nil)
+
+// NewAlerting creates an Alerting wrapping output, which can be nil.
+func NewAlerting(output panyl.Output, options ...AlertingOption) *Alerting {
+	ret := &Alerting{
+		output: output,
+	}
+	for _, opt := range options {
+		opt(ret)
+	}
+	return ret
+}
+
+type AlertingOption func(*Alerting)
+
+// WithAlertRules adds rules to evaluate.
+func WithAlertRules(rules ...AlertRule) AlertingOption {
+	return func(a *Alerting) {
+		a.rules = append(a.rules, rules...)
+	}
+}
+
+// WithAlertNotifiers adds notifiers to send the alerts to.
+func WithAlertNotifiers(notifiers ...AlertNotifier) AlertingOption {
+	return func(a *Alerting) {
+		a.notifiers = append(a.notifiers, notifiers...)
+	}
+}
+
+// WithAlertOnError sets a callback for notifier errors. By default they are logged to the context slog logger.
+func WithAlertOnError(f func(ctx context.Context, alert *Alert, err error)) AlertingOption {
+	return func(a *Alerting) {
+		a.onError = f
+	}
+}
+
+func (a *Alerting) OnItem(ctx context.Context, item *panyl.Item) bool {
+	a.evaluate(ctx, item)
+	if a.output != nil {
+		return a.output.OnItem(ctx, item)
+	}
+	return true
+}
+
+func (a *Alerting) OnFlush(ctx context.Context) {
+	if a.output != nil {
+		a.output.OnFlush(ctx)
+	}
+}
+
+func (a *Alerting) OnClose(ctx context.Context) {
+	if a.output != nil {
+		a.output.OnClose(ctx)
+	}
+}
+
+func (a *Alerting) evaluate(ctx context.Context, item *panyl.Item) {
+	a.m.Lock()
+	defer a.m.Unlock()
+
+	ts := alertItemTime(item)
+	for _, rule := range a.rules {
+		alert := rule.Evaluate(ctx, item, ts)
+		if alert == nil {
+			continue
+		}
+		for _, notifier := range a.notifiers {
+			if err := notifier.Notify(ctx, alert); err != nil {
+				if a.onError != nil {
+					a.onError(ctx, alert, err)
+				} else {
+					panyl.SLogFromContext(ctx).WarnContext(ctx, "error sending alert",
+						slog.String("rule", alert.Rule), slog.Any("error", err))
+				}
+			}
+		}
+	}
+}
+
+func alertItemTime(item *panyl.Item) time.Time {
+	ts, _ := item.Metadata[panyl.MetadataTimestamp].(time.Time)
+	return ts
+}
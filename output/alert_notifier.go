@@ -0,0 +1,132 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/RangelReale/panyl/v2"
+)
+
+// AlertPayload is the JSON representation of an Alert sent by WebhookNotifier and ExecNotifier.
+type AlertPayload struct {
+	Rule    string             `json:"rule"`
+	Time    time.Time          `json:"time"`
+	Message string             `json:"message"`
+	Group   string             `json:"group,omitempty"`
+	Count   int                `json:"count"`
+	Items   []AlertPayloadItem `json:"items"`
+}
+
+type AlertPayloadItem struct {
+	LineNo    int            `json:"line_no"`
+	LineCount int            `json:"line_count"`
+	Metadata  panyl.MapValue `json:"metadata"`
+	Data      panyl.MapValue `json:"data,omitempty"`
+	Line      string         `json:"line,omitempty"`
+}
+
+// NewAlertPayload creates the JSON representation of the alert.
+func NewAlertPayload(alert *Alert) AlertPayload {
+	ret := AlertPayload{
+		Rule:    alert.Rule,
+		Time:    alert.Time,
+		Message: alert.Message,
+		Group:   alert.Group,
+		Count:   alert.Count,
+	}
+	for _, item := range alert.Items {
+		ret.Items = append(ret.Items, AlertPayloadItem{
+			LineNo:    item.LineNo,
+			LineCount: item.LineCount,
+			Metadata:  item.Metadata,
+			Data:      item.Data,
+			Line:      item.Line,
+		})
+	}
+	return ret
+}
+
+// WebhookNotifier sends alerts as a JSON AlertPayload in a POST request to URL.
+// Responses with status codes outside the 2xx range are returned as errors.
+type WebhookNotifier struct {
+	URL    string
+	Header http.Header  // extra request headers, like authorization
+	Client *http.Client // http.DefaultClient if nil
+}
+
+var _ AlertNotifier = (*WebhookNotifier)(nil)
+
+func (n *WebhookNotifier) Notify(ctx context.Context, alert *Alert) error {
+	body, err := json.Marshal(NewAlertPayload(alert))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for name, values := range n.Header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := n.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned status %s", resp.Status)
+	}
+	return nil
+}
+
+// ExecNotifier runs a command for each alert, with the JSON AlertPayload in the standard input and the
+// PANYL_ALERT_RULE, PANYL_ALERT_MESSAGE, PANYL_ALERT_GROUP and PANYL_ALERT_COUNT environment variables.
+type ExecNotifier struct {
+	Command string
+	Args    []string
+	Env     []string // extra environment variables, in the "key=value" format
+}
+
+var _ AlertNotifier = (*ExecNotifier)(nil)
+
+func (n *ExecNotifier) Notify(ctx context.Context, alert *Alert) error {
+	body, err := json.Marshal(NewAlertPayload(alert))
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, n.Command, n.Args...)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Env = append(os.Environ(), n.Env...)
+	cmd.Env = append(cmd.Env,
+		"PANYL_ALERT_RULE="+alert.Rule,
+		"PANYL_ALERT_MESSAGE="+alert.Message,
+		"PANYL_ALERT_GROUP="+alert.Group,
+		"PANYL_ALERT_COUNT="+strconv.Itoa(alert.Count),
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("error running alert command: %w (output: %s)", err, truncateOutput(out, 200))
+	}
+	return nil
+}
+
+func truncateOutput(out []byte, size int) string {
+	if len(out) > size {
+		return string(out[:size]) + "..."
+	}
+	return string(out)
+}
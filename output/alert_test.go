@@ -0,0 +1,145 @@
+package output
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/RangelReale/panyl/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func alertItem(ts time.Time, application, level, message string) *panyl.Item {
+	item := panyl.InitItem()
+	item.Metadata[panyl.MetadataTimestamp] = ts
+	item.Metadata[panyl.MetadataApplication] = application
+	item.Metadata[panyl.MetadataLevel] = level
+	item.Metadata[panyl.MetadataMessage] = message
+	return item
+}
+
+func TestAlerting(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	var alerts []*Alert
+	var output panyl.OutputArray
+	a := NewAlerting(&output,
+		WithAlertRules(
+			&ThresholdRule{
+				Name:    "errors",
+				Match:   MatchMinLevel(panyl.MetadataLevelERROR),
+				Count:   2,
+				Window:  time.Minute,
+				GroupBy: []string{panyl.MetadataApplication},
+			},
+			&PatternRule{
+				Name:    "oom",
+				Match:   MatchMetadata(panyl.MetadataApplication, "api"),
+				Pattern: regexp.MustCompile(`out of memory`),
+			},
+		),
+		WithAlertNotifiers(AlertNotifierFunc(func(ctx context.Context, alert *Alert) error {
+			alerts = append(alerts, alert)
+			return nil
+		})))
+
+	a.OnItem(ctx, alertItem(start, "api", "error", "failed"))
+	a.OnItem(ctx, alertItem(start.Add(10*time.Second), "db", "error", "failed"))
+	a.OnItem(ctx, alertItem(start.Add(20*time.Second), "api", "info", "ok"))
+	a.OnItem(ctx, alertItem(start.Add(30*time.Second), "api", "fatal", "failed"))
+	// the first api error is outside the window
+	a.OnItem(ctx, alertItem(start.Add(70*time.Second), "api", "error", "failed"))
+	assert.Len(t, alerts, 0)
+	a.OnItem(ctx, alertItem(start.Add(80*time.Second), "api", "error", "failed"))
+	if assert.Len(t, alerts, 1) {
+		assert.Equal(t, "errors", alerts[0].Rule)
+		assert.Equal(t, "api", alerts[0].Group)
+		assert.Equal(t, 3, alerts[0].Count)
+		assert.Equal(t, start.Add(80*time.Second), alerts[0].Time)
+	}
+	// the count starts again after triggering
+	a.OnItem(ctx, alertItem(start.Add(81*time.Second), "api", "error", "failed"))
+	assert.Len(t, alerts, 1)
+
+	a.OnItem(ctx, alertItem(start.Add(90*time.Second), "db", "info", "out of memory"))
+	a.OnItem(ctx, alertItem(start.Add(91*time.Second), "api", "info", "out of memory"))
+	if assert.Len(t, alerts, 2) {
+		assert.Equal(t, "oom", alerts[1].Rule)
+		assert.Equal(t, "out of memory", alerts[1].Message)
+	}
+
+	assert.Len(t, output.List, 9)
+}
+
+func TestThresholdRule_ExpiredGroups(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	rule := &ThresholdRule{
+		Count:   2,
+		Window:  time.Minute,
+		GroupBy: []string{panyl.MetadataApplication},
+	}
+	for i, application := range []string{"a", "b", "c"} {
+		rule.Evaluate(ctx, alertItem(start.Add(time.Duration(i)*time.Second), application, "error", "failed"),
+			start.Add(time.Duration(i)*time.Second))
+	}
+	assert.Len(t, rule.groups, 3)
+
+	// the groups without items within the window are removed
+	rule.Evaluate(ctx, alertItem(start.Add(61*time.Second), "d", "error", "failed"), start.Add(61*time.Second))
+	assert.Len(t, rule.groups, 2)
+	assert.NotContains(t, rule.groups, "a")
+	assert.NotContains(t, rule.groups, "b")
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var payload AlertPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "Bearer x", r.Header.Get("Authorization"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		if payload.Rule == "fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	n := &WebhookNotifier{URL: server.URL, Header: http.Header{"Authorization": []string{"Bearer x"}}}
+	err := n.Notify(context.Background(), &Alert{
+		Rule:  "errors",
+		Count: 1,
+		Items: []*panyl.Item{alertItem(time.Now(), "api", "error", "failed")},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "errors", payload.Rule)
+	if assert.Len(t, payload.Items, 1) {
+		assert.Equal(t, "api", payload.Items[0].Metadata.StringValue(panyl.MetadataApplication))
+	}
+
+	err = n.Notify(context.Background(), &Alert{Rule: "fail"})
+	assert.Error(t, err)
+}
+
+func TestExecNotifier(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "alert.json")
+	n := &ExecNotifier{Command: "sh", Args: []string{"-c", `cat > "$OUT"; echo "$PANYL_ALERT_RULE" >> "$OUT"`},
+		Env: []string{"OUT=" + out}}
+	err := n.Notify(context.Background(), &Alert{Rule: "errors", Message: "failed"})
+	assert.NoError(t, err)
+
+	data, err := os.ReadFile(out)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"message":"failed"`)
+	assert.Contains(t, string(data), "}errors\n")
+
+	n = &ExecNotifier{Command: "sh", Args: []string{"-c", "exit 1"}}
+	assert.Error(t, n.Notify(context.Background(), &Alert{Rule: "errors"}))
+}
@@ -0,0 +1,237 @@
+package output
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RangelReale/panyl/v2"
+)
+
+// Dedup is an Output wrapper that collapses repeated items, like the ones of crash loops. The first item is sent
+// to the wrapped output, and the following items with the same identity are counted instead. When the repetition
+// ends, an item with MetadataCreated, MetadataRepeatCount, MetadataRepeatFirst and MetadataRepeatLast and the
+// message "message repeated N times: <message>" is sent.
+//
+// By default only consecutive items are collapsed. Use WithDedupWindow to collapse all the items with the same
+// identity during a time window since the first one, measured using the item MetadataTimestamp.
+//
+// The identity of an item is defined by the values of the Metadata fields, by default MetadataMessage,
+// MetadataLevel and MetadataApplication. The message is normalized by collapsing spaces, and if MetadataMessage is
+// not set the item Line is used.
+type Dedup struct {
+	output    panyl.Output
+	fields    []string
+	normalize func(message string) string
+	window    time.Duration
+	maxOpen   int
+
+	last    *dedupGroup              // consecutive mode
+	open    map[string]*list.Element // window mode
+	order   *list.List               // of *dedupGroup, ordered by the seen time
+	current time.Time
+	m       sync.Mutex
+}
+
+var _ panyl.Output = (*Dedup)(nil)
+
+type dedupGroup struct {
+	key         string
+	item        *panyl.Item // the first item
+	count       int         // the amount of repeated items after the first one
+	first, last time.Time
+	lastLineNo  int
+	seen        time.Time
+}
+
+// NewDedup creates a Dedup wrapping output.
+func NewDedup(output panyl.Output, options ...DedupOption) *Dedup {
+	ret := &Dedup{
+		output:    output,
+		fields:    []string{panyl.MetadataMessage, panyl.MetadataLevel, panyl.MetadataApplication},
+		normalize: func(message string) string { return strings.Join(strings.Fields(message), " ") },
+		open:      map[string]*list.Element{},
+		order:     list.New(),
+	}
+	for _, opt := range options {
+		opt(ret)
+	}
+	return ret
+}
+
+type DedupOption func(*Dedup)
+
+// WithDedupFields sets the Metadata fields which define the identity of the items.
+func WithDedupFields(fields ...string) DedupOption {
+	return func(d *Dedup) {
+		d.fields = fields
+	}
+}
+
+// WithDedupNormalize sets the function used to normalize the message, for example util.MessageTemplate to
+// consider messages that only differ on numbers and ids as equal.
+func WithDedupNormalize(normalize func(message string) string) DedupOption {
+	return func(d *Dedup) {
+		d.normalize = normalize
+	}
+}
+
+// WithDedupWindow collapses items with the same identity during the window after the first one, even if they are
+// not consecutive.
+func WithDedupWindow(window time.Duration) DedupOption {
+	return func(d *Dedup) {
+		d.window = window
+	}
+}
+
+// WithDedupMaxOpen limits the amount of identities tracked when using WithDedupWindow, the oldest ones are ended
+// when it is exceeded.
+func WithDedupMaxOpen(maxOpen int) DedupOption {
+	return func(d *Dedup) {
+		d.maxOpen = maxOpen
+	}
+}
+
+func (d *Dedup) OnItem(ctx context.Context, item *panyl.Item) bool {
+	d.m.Lock()
+	defer d.m.Unlock()
+
+	ts, hasTime := item.Metadata[panyl.MetadataTimestamp].(time.Time)
+	if hasTime && ts.After(d.current) {
+		d.current = ts
+	}
+	key := d.key(item)
+
+	if d.window <= 0 {
+		if d.last != nil && d.last.key == key {
+			d.last.add(item, ts)
+			return true
+		}
+		d.endGroup(ctx, d.last)
+		d.last = newDedupGroup(key, item, ts)
+		return d.output.OnItem(ctx, item)
+	}
+
+	d.expire(ctx, false)
+	if e, ok := d.open[key]; ok {
+		e.Value.(*dedupGroup).add(item, ts)
+		return true
+	}
+	group := newDedupGroup(key, item, ts)
+	group.seen = d.current
+	d.open[key] = d.order.PushBack(group)
+	if d.maxOpen > 0 && d.order.Len() > d.maxOpen {
+		d.endGroup(ctx, d.removeGroup(d.order.Front()))
+	}
+	return d.output.OnItem(ctx, item)
+}
+
+func (d *Dedup) OnFlush(ctx context.Context) {
+	d.endAll(ctx)
+	d.output.OnFlush(ctx)
+}
+
+func (d *Dedup) OnClose(ctx context.Context) {
+	d.endAll(ctx)
+	d.output.OnClose(ctx)
+}
+
+func (d *Dedup) endAll(ctx context.Context) {
+	d.m.Lock()
+	defer d.m.Unlock()
+	d.endGroup(ctx, d.last)
+	d.last = nil
+	d.expire(ctx, true)
+}
+
+// expire ends the groups whose window ended, or all of them.
+func (d *Dedup) expire(ctx context.Context, all bool) {
+	for e := d.order.Front(); e != nil; e = d.order.Front() {
+		if !all && e.Value.(*dedupGroup).seen.Add(d.window).After(d.current) {
+			break
+		}
+		d.endGroup(ctx, d.removeGroup(e))
+	}
+}
+
+func (d *Dedup) removeGroup(e *list.Element) *dedupGroup {
+	group := e.Value.(*dedupGroup)
+	d.order.Remove(e)
+	delete(d.open, group.key)
+	return group
+}
+
+// endGroup sends the repeated item if the group had repetitions.
+func (d *Dedup) endGroup(ctx context.Context, group *dedupGroup) {
+	if group == nil || group.count == 0 {
+		return
+	}
+	d.output.OnItem(ctx, d.repeatedItem(group))
+}
+
+func (d *Dedup) repeatedItem(group *dedupGroup) *panyl.Item {
+	message := fmt.Sprintf("message repeated %d times: %s", group.count, dedupMessage(group.item))
+	item := panyl.InitItem(panyl.WithInitLineNo(group.lastLineNo), panyl.WithInitLine(message))
+	item.Metadata[panyl.MetadataCreated] = true
+	item.Metadata[panyl.MetadataMessage] = message
+	item.Metadata[panyl.MetadataRepeatCount] = group.count
+	for _, name := range []string{panyl.MetadataLevel, panyl.MetadataApplication, panyl.MetadataFormat} {
+		if value, ok := group.item.Metadata[name]; ok {
+			item.Metadata[name] = value
+		}
+	}
+	if !group.first.IsZero() {
+		item.Metadata[panyl.MetadataTimestamp] = group.last
+		item.Metadata[panyl.MetadataRepeatFirst] = group.first
+		item.Metadata[panyl.MetadataRepeatLast] = group.last
+	}
+	return item
+}
+
+func (d *Dedup) key(item *panyl.Item) string {
+	var b strings.Builder
+	for i, field := range d.fields {
+		if i > 0 {
+			b.WriteByte(0)
+		}
+		if field == panyl.MetadataMessage {
+			b.WriteString(d.normalize(dedupMessage(item)))
+		} else {
+			fmt.Fprint(&b, item.Metadata[field])
+		}
+	}
+	return b.String()
+}
+
+func dedupMessage(item *panyl.Item) string {
+	if message := item.Metadata.StringValue(panyl.MetadataMessage); message != "" {
+		return message
+	}
+	return item.Line
+}
+
+func newDedupGroup(key string, item *panyl.Item, ts time.Time) *dedupGroup {
+	return &dedupGroup{
+		key:        key,
+		item:       item,
+		first:      ts,
+		last:       ts,
+		lastLineNo: item.LineNo,
+	}
+}
+
+func (g *dedupGroup) add(item *panyl.Item, ts time.Time) {
+	g.count++
+	g.lastLineNo = item.LineNo
+	if !ts.IsZero() {
+		if g.first.IsZero() || ts.Before(g.first) {
+			g.first = ts
+		}
+		if ts.After(g.last) {
+			g.last = ts
+		}
+	}
+}
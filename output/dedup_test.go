@@ -0,0 +1,81 @@
+package output
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/RangelReale/panyl/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func dedupItem(lineNo int, ts time.Time, level, message string) *panyl.Item {
+	item := panyl.InitItem(panyl.WithInitLineNo(lineNo))
+	item.Metadata[panyl.MetadataTimestamp] = ts
+	item.Metadata[panyl.MetadataLevel] = level
+	item.Metadata[panyl.MetadataMessage] = message
+	return item
+}
+
+func TestDedup(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	var output panyl.OutputArray
+	d := NewDedup(&output)
+
+	d.OnItem(ctx, dedupItem(1, start, "error", "connection  failed"))
+	d.OnItem(ctx, dedupItem(2, start.Add(time.Second), "error", "connection failed"))
+	d.OnItem(ctx, dedupItem(3, start.Add(2*time.Second), "error", "connection failed"))
+	d.OnItem(ctx, dedupItem(4, start.Add(3*time.Second), "info", "connection failed"))
+	d.OnItem(ctx, dedupItem(5, start.Add(4*time.Second), "info", "restarting"))
+	d.OnFlush(ctx)
+
+	if assert.Len(t, output.List, 4) {
+		assert.Equal(t, 1, output.List[0].LineNo)
+
+		repeated := output.List[1]
+		assert.True(t, repeated.Metadata.BoolValue(panyl.MetadataCreated))
+		assert.Equal(t, "message repeated 2 times: connection  failed", repeated.Line)
+		assert.Equal(t, 2, repeated.Metadata.IntValue(panyl.MetadataRepeatCount))
+		assert.Equal(t, "error", repeated.Metadata.StringValue(panyl.MetadataLevel))
+		assert.Equal(t, start, repeated.Metadata[panyl.MetadataRepeatFirst])
+		assert.Equal(t, start.Add(2*time.Second), repeated.Metadata[panyl.MetadataRepeatLast])
+		assert.Equal(t, 3, repeated.LineNo)
+
+		assert.Equal(t, 4, output.List[2].LineNo)
+		assert.Equal(t, 5, output.List[3].LineNo)
+	}
+}
+
+func TestDedup_Window(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	var output panyl.OutputArray
+	d := NewDedup(&output, WithDedupWindow(10*time.Second), WithDedupFields(panyl.MetadataMessage))
+
+	d.OnItem(ctx, dedupItem(1, start, "error", "crash"))
+	d.OnItem(ctx, dedupItem(2, start.Add(time.Second), "info", "restarting"))
+	d.OnItem(ctx, dedupItem(3, start.Add(2*time.Second), "warn", "crash"))
+	d.OnItem(ctx, dedupItem(4, start.Add(3*time.Second), "info", "restarting"))
+	d.OnItem(ctx, dedupItem(5, start.Add(4*time.Second), "error", "crash"))
+	// the window of "crash" ends, but not of "restarting"
+	d.OnItem(ctx, dedupItem(6, start.Add(10*time.Second), "error", "crash"))
+	d.OnClose(ctx)
+
+	var lines []string
+	for _, item := range output.List {
+		lines = append(lines, item.Line)
+	}
+	assert.Equal(t, []string{
+		"",
+		"",
+		"message repeated 2 times: crash",
+		"",
+		"message repeated 1 times: restarting",
+	}, lines)
+	if assert.Len(t, output.List, 5) {
+		assert.Equal(t, 6, output.List[3].LineNo)
+	}
+}
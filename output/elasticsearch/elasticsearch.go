@@ -0,0 +1,406 @@
+// Package elasticsearch provides an Output sending items to Elasticsearch or OpenSearch using the bulk API.
+package elasticsearch
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RangelReale/panyl/v2"
+)
+
+const (
+	DefaultBatchSize  = 500
+	DefaultMaxRetries = 3
+	DefaultBackoff    = 500 * time.Millisecond
+	DefaultDataField  = "data"
+)
+
+// DefaultFieldMapping maps Metadata keys to ECS-like document fields. Dots create nested objects. It is copied
+// when an Output is created, so changing it only affects the outputs created after the change.
+var DefaultFieldMapping = map[string]string{
+	panyl.MetadataTimestamp:   "@timestamp",
+	panyl.MetadataMessage:     "message",
+	panyl.MetadataLevel:       "log.level",
+	panyl.MetadataApplication: "service.name",
+	panyl.MetadataFormat:      "labels.format",
+	panyl.MetadataCategory:    "labels.category",
+	panyl.MetadataTraceID:     "trace.id",
+	panyl.MetadataSpanID:      "span.id",
+	panyl.MetadataRequestID:   "http.request.id",
+}
+
+// IndexFunc returns the index of an item.
+type IndexFunc func(item *panyl.Item) string
+
+// DateIndex returns an IndexFunc naming indexes as "prefix-date", using the item MetadataTimestamp formatted with
+// dateLayout in UTC. If perApplication is true the index is "prefix-application-date", for items with
+// MetadataApplication.
+func DateIndex(prefix string, dateLayout string, perApplication bool) IndexFunc {
+	return func(item *panyl.Item) string {
+		var b strings.Builder
+		b.WriteString(prefix)
+		if perApplication {
+			if app := item.Metadata.StringValue(panyl.MetadataApplication); app != "" {
+				b.WriteString("-")
+				b.WriteString(strings.ToLower(app))
+			}
+		}
+		if ts, ok := item.Metadata[panyl.MetadataTimestamp].(time.Time); ok && dateLayout != "" {
+			b.WriteString("-")
+			b.WriteString(ts.UTC().Format(dateLayout))
+		}
+		return b.String()
+	}
+}
+
+// Output sends items to the _bulk endpoint of an Elasticsearch or OpenSearch cluster, in batches of
+// DefaultBatchSize items. The batch is also sent on OnFlush and OnClose.
+// Failed requests, and documents rejected with status 429 or 5xx, are retried with exponential backoff. Errors
+// after the retries are sent to the error callback, or logged to the context slog logger.
+type Output struct {
+	url        string
+	client     *http.Client
+	header     http.Header
+	index      IndexFunc
+	action     string
+	batchSize  int
+	maxRetries int
+	backoff    time.Duration
+	fields     map[string]string
+	fieldKeys  []string // the keys of fields, sorted by field name
+	dataField  string
+	document   func(item *panyl.Item) map[string]any
+	onError    func(ctx context.Context, err error)
+
+	batch []bulkDocument
+	m     sync.Mutex
+}
+
+var _ panyl.Output = (*Output)(nil)
+
+type bulkDocument struct {
+	index string
+	doc   []byte
+}
+
+// New creates an Output sending to the cluster at url, like "http://localhost:9200".
+func New(url string, options ...Option) *Output {
+	ret := &Output{
+		url:        strings.TrimSuffix(url, "/") + "/_bulk",
+		client:     http.DefaultClient,
+		index:      DateIndex("panyl", "2006.01.02", false),
+		action:     "index",
+		batchSize:  DefaultBatchSize,
+		maxRetries: DefaultMaxRetries,
+		backoff:    DefaultBackoff,
+		fields:     maps.Clone(DefaultFieldMapping),
+		dataField:  DefaultDataField,
+	}
+	for _, opt := range options {
+		opt(ret)
+	}
+	// apply the mappings in a fixed order, so overlapping fields always create the same document
+	ret.fieldKeys = slices.SortedFunc(maps.Keys(ret.fields), func(a, b string) int {
+		return cmp.Or(cmp.Compare(ret.fields[a], ret.fields[b]), cmp.Compare(a, b))
+	})
+	return ret
+}
+
+type Option func(*Output)
+
+// WithHTTPClient sets the HTTP client, http.DefaultClient by default.
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *Output) {
+		o.client = client
+	}
+}
+
+// WithHeader sets extra request headers, like authorization.
+func WithHeader(header http.Header) Option {
+	return func(o *Output) {
+		o.header = header
+	}
+}
+
+// WithIndex sets the function returning the index of each item, by default DateIndex("panyl", "2006.01.02", false).
+func WithIndex(index IndexFunc) Option {
+	return func(o *Output) {
+		o.index = index
+	}
+}
+
+// WithCreate uses the "create" bulk action instead of "index", required for data streams.
+func WithCreate(create bool) Option {
+	return func(o *Output) {
+		if create {
+			o.action = "create"
+		} else {
+			o.action = "index"
+		}
+	}
+}
+
+// WithBatchSize sets the amount of items sent in each request.
+func WithBatchSize(batchSize int) Option {
+	return func(o *Output) {
+		o.batchSize = batchSize
+	}
+}
+
+// WithRetry sets the maximum amount of retries and the initial backoff, which doubles on each retry.
+func WithRetry(maxRetries int, backoff time.Duration) Option {
+	return func(o *Output) {
+		o.maxRetries = maxRetries
+		o.backoff = backoff
+	}
+}
+
+// WithFieldMapping sets how Metadata keys are mapped to document fields, by default DefaultFieldMapping.
+// Dots in the field names create nested objects. The map is copied.
+// The mappings are applied after the Data, in the order of the field names, so a field replaces the Data values
+// with the same name, and a nested field like "log.level" replaces a "log" field which is not an object.
+func WithFieldMapping(fields map[string]string) Option {
+	return func(o *Output) {
+		o.fields = maps.Clone(fields)
+	}
+}
+
+// WithDataField sets the document field which receives the item Data, by default DefaultDataField. If empty, the
+// Data fields are added to the root of the document.
+func WithDataField(dataField string) Option {
+	return func(o *Output) {
+		o.dataField = dataField
+	}
+}
+
+// WithDocument sets a function to create the document of an item, replacing the field mapping.
+func WithDocument(document func(item *panyl.Item) map[string]any) Option {
+	return func(o *Output) {
+		o.document = document
+	}
+}
+
+// WithOnError sets a callback for errors sending the items.
+func WithOnError(onError func(ctx context.Context, err error)) Option {
+	return func(o *Output) {
+		o.onError = onError
+	}
+}
+
+func (o *Output) OnItem(ctx context.Context, item *panyl.Item) bool {
+	o.m.Lock()
+	defer o.m.Unlock()
+
+	doc, err := json.Marshal(o.itemDocument(item))
+	if err != nil {
+		o.handleError(ctx, fmt.Errorf("error encoding item of line %d: %w", item.LineNo, err))
+		return true
+	}
+	o.batch = append(o.batch, bulkDocument{index: o.index(item), doc: doc})
+	if len(o.batch) >= o.batchSize {
+		o.flush(ctx)
+	}
+	return ctx.Err() == nil
+}
+
+func (o *Output) OnFlush(ctx context.Context) {
+	o.m.Lock()
+	defer o.m.Unlock()
+	o.flush(ctx)
+}
+
+func (o *Output) OnClose(ctx context.Context) {
+	o.OnFlush(ctx)
+}
+
+// itemDocument returns the document sent for the item, using the configured field mapping.
+func (o *Output) itemDocument(item *panyl.Item) map[string]any {
+	if o.document != nil {
+		return o.document(item)
+	}
+
+	doc := map[string]any{}
+	if o.dataField == "" {
+		for k, v := range item.Data {
+			doc[k] = v
+		}
+	} else if len(item.Data) > 0 {
+		doc[o.dataField] = map[string]any(item.Data)
+	}
+	for _, name := range o.fieldKeys {
+		field := o.fields[name]
+		value, ok := item.Metadata[name]
+		if !ok {
+			continue
+		}
+		if ts, ok := value.(time.Time); ok {
+			value = ts.Format(time.RFC3339Nano)
+		}
+		setField(doc, field, value)
+	}
+	if field, ok := o.fields[panyl.MetadataMessage]; ok && !item.Metadata.HasValue(panyl.MetadataMessage) &&
+		item.Line != "" {
+		setField(doc, field, item.Line)
+	}
+	return doc
+}
+
+// setField sets a value in a document, creating nested objects for each dot in the field name.
+func setField(doc map[string]any, field string, value any) {
+	for {
+		before, after, found := strings.Cut(field, ".")
+		if !found {
+			doc[field] = value
+			return
+		}
+		// copy existing objects, which may be shared with the item Data
+		nested := map[string]any{}
+		if existing, ok := doc[before].(map[string]any); ok {
+			for k, v := range existing {
+				nested[k] = v
+			}
+		}
+		doc[before] = nested
+		doc, field = nested, after
+	}
+}
+
+// flush sends the batch, retrying the failed documents.
+func (o *Output) flush(ctx context.Context) {
+	batch := o.batch
+	o.batch = nil
+	backoff := o.backoff
+	for retry := 0; len(batch) > 0; retry++ {
+		failed, retryErr, err := o.send(ctx, batch)
+		if err != nil {
+			o.handleError(ctx, err)
+		}
+		if len(failed) == 0 {
+			return
+		}
+		if retry >= o.maxRetries || ctx.Err() != nil {
+			o.handleError(ctx, retryErr)
+			return
+		}
+		select {
+		case <-ctx.Done():
+			o.handleError(ctx, retryErr)
+			return
+		case <-time.After(backoff):
+		}
+		batch = failed
+		backoff *= 2
+	}
+}
+
+type bulkResponse struct {
+	Errors bool                          `json:"errors"`
+	Items  []map[string]bulkResponseItem `json:"items"`
+}
+
+type bulkResponseItem struct {
+	Status int             `json:"status"`
+	Error  json.RawMessage `json:"error"`
+}
+
+// send sends the documents, returning the ones that can be retried with their error, and the error of the ones
+// that can't.
+func (o *Output) send(ctx context.Context, batch []bulkDocument) (failed []bulkDocument, retryErr error, err error) {
+	var body bytes.Buffer
+	for _, doc := range batch {
+		action, err := json.Marshal(map[string]any{o.action: map[string]any{"_index": doc.index}})
+		if err != nil {
+			return nil, nil, err
+		}
+		body.Write(action)
+		body.WriteByte('\n')
+		body.Write(doc.doc)
+		body.WriteByte('\n')
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.url, &body)
+	if err != nil {
+		return nil, nil, err
+	}
+	for name, values := range o.header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return batch, err, nil
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return batch, err, nil
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		err = fmt.Errorf("bulk request returned status %s: %s", resp.Status, truncate(respBody, 200))
+		if isRetryableStatus(resp.StatusCode) {
+			return batch, err, nil
+		}
+		return nil, nil, err
+	}
+
+	var result bulkResponse
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, nil, fmt.Errorf("error decoding bulk response: %w", err)
+	}
+	if !result.Errors {
+		return nil, nil, nil
+	}
+
+	var retryErrs, errs []error
+	for i, ri := range result.Items {
+		if i >= len(batch) {
+			break
+		}
+		for _, item := range ri {
+			if item.Status >= 200 && item.Status <= 299 {
+				continue
+			}
+			itemErr := fmt.Errorf("document %d failed with status %d: %s", i, item.Status, truncate(item.Error, 200))
+			if isRetryableStatus(item.Status) {
+				failed = append(failed, batch[i])
+				retryErrs = append(retryErrs, itemErr)
+			} else {
+				errs = append(errs, itemErr)
+			}
+		}
+	}
+	return failed, errors.Join(retryErrs...), errors.Join(errs...)
+}
+
+func (o *Output) handleError(ctx context.Context, err error) {
+	if o.onError != nil {
+		o.onError(ctx, err)
+		return
+	}
+	panyl.SLogFromContext(ctx).WarnContext(ctx, "error sending items to elasticsearch", slog.Any("error", err))
+}
+
+func isRetryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+func truncate(data []byte, size int) string {
+	if len(data) > size {
+		return string(data[:size]) + "..."
+	}
+	return string(data)
+}
@@ -0,0 +1,204 @@
+package elasticsearch
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RangelReale/panyl/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+type bulkRequest struct {
+	actions []map[string]map[string]string
+	docs    []map[string]any
+}
+
+func bulkServer(t *testing.T, handler func(n int, req bulkRequest, w http.ResponseWriter)) (*httptest.Server,
+	*[]bulkRequest) {
+	var requests []bulkRequest
+	var m sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/_bulk", r.URL.Path)
+		assert.Equal(t, "application/x-ndjson", r.Header.Get("Content-Type"))
+
+		var req bulkRequest
+		scanner := bufio.NewScanner(r.Body)
+		for i := 0; scanner.Scan(); i++ {
+			if i%2 == 0 {
+				var action map[string]map[string]string
+				assert.NoError(t, json.Unmarshal(scanner.Bytes(), &action))
+				req.actions = append(req.actions, action)
+			} else {
+				var doc map[string]any
+				assert.NoError(t, json.Unmarshal(scanner.Bytes(), &doc))
+				req.docs = append(req.docs, doc)
+			}
+		}
+
+		m.Lock()
+		requests = append(requests, req)
+		n := len(requests)
+		m.Unlock()
+		handler(n, req, w)
+	}))
+	return server, &requests
+}
+
+func esItem(lineNo int, ts time.Time, application string) *panyl.Item {
+	item := panyl.InitItem(panyl.WithInitLineNo(lineNo))
+	item.Metadata[panyl.MetadataTimestamp] = ts
+	item.Metadata[panyl.MetadataApplication] = application
+	item.Metadata[panyl.MetadataLevel] = panyl.MetadataLevelERROR
+	item.Data["status"] = float64(500)
+	item.Line = "request failed"
+	return item
+}
+
+func TestOutput(t *testing.T) {
+	ctx := context.Background()
+	ts := time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)
+
+	server, requests := bulkServer(t, func(n int, req bulkRequest, w http.ResponseWriter) {
+		_, _ = w.Write([]byte(`{"errors":false,"items":[]}`))
+	})
+	defer server.Close()
+
+	o := New(server.URL, WithBatchSize(2), WithIndex(DateIndex("logs", "2006.01.02", true)))
+	o.OnItem(ctx, esItem(1, ts, "API"))
+	o.OnItem(ctx, esItem(2, ts.Add(24*time.Hour), ""))
+	o.OnItem(ctx, esItem(3, ts, "db"))
+	assert.Len(t, *requests, 1)
+	o.OnFlush(ctx)
+
+	if assert.Len(t, *requests, 2) {
+		req := (*requests)[0]
+		assert.Equal(t, "logs-api-2024.03.05", req.actions[0]["index"]["_index"])
+		assert.Equal(t, "logs-2024.03.06", req.actions[1]["index"]["_index"])
+		assert.Equal(t, map[string]any{
+			"@timestamp": "2024-03-05T10:00:00Z",
+			"message":    "request failed",
+			"log":        map[string]any{"level": "error"},
+			"service":    map[string]any{"name": "API"},
+			"data":       map[string]any{"status": float64(500)},
+		}, req.docs[0])
+
+		assert.Len(t, (*requests)[1].docs, 1)
+	}
+}
+
+func TestOutput_FieldMappingCopied(t *testing.T) {
+	ctx := context.Background()
+	ts := time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)
+
+	server, requests := bulkServer(t, func(n int, req bulkRequest, w http.ResponseWriter) {
+		_, _ = w.Write([]byte(`{"errors":false,"items":[]}`))
+	})
+	defer server.Close()
+
+	fields := map[string]string{panyl.MetadataApplication: "app"}
+	o := New(server.URL)
+	custom := New(server.URL, WithFieldMapping(fields))
+
+	// changing the maps after creating the outputs must not affect them
+	DefaultFieldMapping[panyl.MetadataLevel] = "severity"
+	defer func() {
+		DefaultFieldMapping[panyl.MetadataLevel] = "log.level"
+	}()
+	fields[panyl.MetadataApplication] = "changed"
+
+	o.OnItem(ctx, esItem(1, ts, "api"))
+	o.OnFlush(ctx)
+	custom.OnItem(ctx, esItem(2, ts, "api"))
+	custom.OnFlush(ctx)
+
+	if assert.Len(t, *requests, 2) {
+		assert.Equal(t, map[string]any{"level": "error"}, (*requests)[0].docs[0]["log"])
+		assert.NotContains(t, (*requests)[0].docs[0], "severity")
+		assert.Equal(t, "api", (*requests)[1].docs[0]["app"])
+		assert.NotContains(t, (*requests)[1].docs[0], "changed")
+	}
+}
+
+func TestOutput_OverlappingFields(t *testing.T) {
+	ts := time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)
+
+	// the document is the same however the field mapping is iterated
+	for range 20 {
+		o := New("http://localhost:9200", WithDataField(""), WithFieldMapping(map[string]string{
+			panyl.MetadataApplication: "log",
+			panyl.MetadataLevel:       "log.level",
+			panyl.MetadataTimestamp:   "status",
+		}))
+		assert.Equal(t, map[string]any{
+			"log":    map[string]any{"level": panyl.MetadataLevelERROR},
+			"status": "2024-03-05T10:00:00Z",
+		}, o.itemDocument(esItem(1, ts, "api")))
+	}
+}
+
+func TestOutput_Retry(t *testing.T) {
+	ctx := context.Background()
+	ts := time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)
+
+	server, requests := bulkServer(t, func(n int, req bulkRequest, w http.ResponseWriter) {
+		switch n {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			// the second document is rejected and the third one must be retried
+			_, _ = w.Write([]byte(`{"errors":true,"items":[{"index":{"status":201}},` +
+				`{"index":{"status":400,"error":{"type":"mapper_parsing_exception"}}},` +
+				`{"index":{"status":429,"error":{"type":"es_rejected_execution_exception"}}}]}`))
+		default:
+			_, _ = w.Write([]byte(`{"errors":false,"items":[{"index":{"status":201}}]}`))
+		}
+	})
+	defer server.Close()
+
+	var errs []error
+	o := New(server.URL, WithRetry(3, time.Millisecond), WithDataField(""),
+		WithOnError(func(ctx context.Context, err error) {
+			errs = append(errs, err)
+		}))
+	for i := 1; i <= 3; i++ {
+		o.OnItem(ctx, esItem(i, ts, "api"))
+	}
+	o.OnClose(ctx)
+
+	if assert.Len(t, *requests, 3) {
+		assert.Len(t, (*requests)[1].docs, 3)
+		if assert.Len(t, (*requests)[2].docs, 1) {
+			assert.Equal(t, float64(500), (*requests)[2].docs[0]["status"])
+		}
+	}
+	if assert.Len(t, errs, 1) {
+		assert.ErrorContains(t, errs[0], "mapper_parsing_exception")
+	}
+}
+
+func TestOutput_RetryExhausted(t *testing.T) {
+	ctx := context.Background()
+
+	server, requests := bulkServer(t, func(n int, req bulkRequest, w http.ResponseWriter) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	defer server.Close()
+
+	var errs []error
+	o := New(server.URL, WithRetry(2, time.Millisecond), WithOnError(func(ctx context.Context, err error) {
+		errs = append(errs, err)
+	}))
+	o.OnItem(ctx, esItem(1, time.Now(), "api"))
+	o.OnFlush(ctx)
+
+	assert.Len(t, *requests, 3)
+	if assert.Len(t, errs, 1) {
+		assert.ErrorContains(t, errs[0], "429")
+	}
+}
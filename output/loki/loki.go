@@ -0,0 +1,458 @@
+// Package loki provides an Output sending items to Grafana Loki using the push API.
+package loki
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RangelReale/panyl/v2"
+	"github.com/golang/snappy"
+)
+
+type Encoding int
+
+const (
+	EncodingJSON     Encoding = iota // application/json
+	EncodingProtobuf                 // application/x-protobuf, compressed with snappy
+)
+
+const (
+	DefaultBatchSize  = 1000
+	DefaultBatchBytes = 1024 * 1024
+	DefaultBatchWait  = time.Second
+	DefaultMaxRetries = 3
+	DefaultBackoff    = 500 * time.Millisecond
+
+	// DefaultJobLabel is added to streams that wouldn't have any label, as Loki requires at least one.
+	DefaultJobLabel      = "job"
+	DefaultJobLabelValue = "panyl"
+
+	// OtherLabelValue replaces the label values exceeding the limit set by WithMaxLabelValues.
+	OtherLabelValue = "other"
+)
+
+// DefaultLabels are the Metadata keys used as stream labels.
+var DefaultLabels = []string{panyl.MetadataApplication, panyl.MetadataLevel, panyl.MetadataFormat,
+	panyl.MetadataCategory}
+
+// Output sends items to the Loki push API, grouping them in streams by labels read from Metadata.
+// Items are sent in batches, when the batch reaches the size or bytes limits, or by a timer after the batch wait time
+// since the first item of the batch. The batch is also sent on OnFlush and OnClose. The entries of each stream are
+// sent sorted by timestamp, as Loki may reject out of order entries.
+// Failed requests are retried with exponential backoff, errors after the retries are sent to the error callback,
+// or logged to the context slog logger.
+type Output struct {
+	url            string
+	client         *http.Client
+	header         http.Header
+	labels         []string
+	staticLabels   map[string]string
+	maxLabelValues int
+	encoding       Encoding
+	batchSize      int
+	batchBytes     int
+	batchWait      time.Duration
+	maxRetries     int
+	backoff        time.Duration
+	line           func(item *panyl.Item) string
+	onError        func(ctx context.Context, err error)
+
+	streams     map[string]*stream
+	batchCount  int
+	batchLen    int
+	batchTimer  *time.Timer
+	batchID     int // incremented on each flush, so a timer of a batch already sent does nothing
+	labelValues map[string]map[string]struct{}
+	m           sync.Mutex
+}
+
+var _ panyl.Output = (*Output)(nil)
+
+type stream struct {
+	labels  map[string]string
+	entries []entry
+}
+
+type entry struct {
+	ts   time.Time
+	line string
+}
+
+// New creates an Output sending to the Loki server at url, like "http://localhost:3100".
+func New(url string, options ...Option) *Output {
+	ret := &Output{
+		url:         strings.TrimSuffix(url, "/") + "/loki/api/v1/push",
+		client:      http.DefaultClient,
+		labels:      DefaultLabels,
+		encoding:    EncodingJSON,
+		batchSize:   DefaultBatchSize,
+		batchBytes:  DefaultBatchBytes,
+		batchWait:   DefaultBatchWait,
+		maxRetries:  DefaultMaxRetries,
+		backoff:     DefaultBackoff,
+		line:        DefaultLine,
+		streams:     map[string]*stream{},
+		labelValues: map[string]map[string]struct{}{},
+	}
+	for _, opt := range options {
+		opt(ret)
+	}
+	return ret
+}
+
+type Option func(*Output)
+
+// WithHTTPClient sets the HTTP client, http.DefaultClient by default.
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *Output) {
+		o.client = client
+	}
+}
+
+// WithHeader sets extra request headers, like authorization.
+func WithHeader(header http.Header) Option {
+	return func(o *Output) {
+		o.header = header
+	}
+}
+
+// WithTenantID sets the X-Scope-OrgID header used by multi-tenant Loki servers.
+func WithTenantID(tenantID string) Option {
+	return func(o *Output) {
+		if o.header == nil {
+			o.header = http.Header{}
+		}
+		o.header.Set("X-Scope-OrgID", tenantID)
+	}
+}
+
+// WithLabels sets the Metadata keys used as stream labels, by default DefaultLabels. Keys not present in the item
+// are not added as labels. Characters not allowed in label names are replaced by "_".
+func WithLabels(labels ...string) Option {
+	return func(o *Output) {
+		o.labels = labels
+	}
+}
+
+// WithStaticLabels sets labels added to all streams, like the host name.
+func WithStaticLabels(labels map[string]string) Option {
+	return func(o *Output) {
+		o.staticLabels = labels
+	}
+}
+
+// WithMaxLabelValues limits the amount of distinct values of each label, to limit the streams cardinality.
+// After the limit new values are replaced by OtherLabelValue.
+func WithMaxLabelValues(maxLabelValues int) Option {
+	return func(o *Output) {
+		o.maxLabelValues = maxLabelValues
+	}
+}
+
+// WithEncoding sets the request encoding, EncodingJSON by default.
+func WithEncoding(encoding Encoding) Option {
+	return func(o *Output) {
+		o.encoding = encoding
+	}
+}
+
+// WithBatch sets the maximum amount of items and bytes of the log lines in a batch, and the time to wait since the
+// first item of the batch before sending it. Zero values disable each limit.
+func WithBatch(size int, bytes int, wait time.Duration) Option {
+	return func(o *Output) {
+		o.batchSize = size
+		o.batchBytes = bytes
+		o.batchWait = wait
+	}
+}
+
+// WithRetry sets the maximum amount of retries and the initial backoff, which doubles on each retry.
+func WithRetry(maxRetries int, backoff time.Duration) Option {
+	return func(o *Output) {
+		o.maxRetries = maxRetries
+		o.backoff = backoff
+	}
+}
+
+// WithLine sets the function returning the log line of an item, by default DefaultLine.
+func WithLine(line func(item *panyl.Item) string) Option {
+	return func(o *Output) {
+		o.line = line
+	}
+}
+
+// WithOnError sets a callback for errors sending the items.
+func WithOnError(onError func(ctx context.Context, err error)) Option {
+	return func(o *Output) {
+		o.onError = onError
+	}
+}
+
+// DefaultLine returns MetadataMessage, or the item Line if not set. If both are empty, it returns the item Data as
+// JSON.
+func DefaultLine(item *panyl.Item) string {
+	if message := item.Metadata.StringValue(panyl.MetadataMessage); message != "" {
+		return message
+	}
+	if item.Line != "" || len(item.Data) == 0 {
+		return item.Line
+	}
+	data, err := json.Marshal(item.Data)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func (o *Output) OnItem(ctx context.Context, item *panyl.Item) bool {
+	o.m.Lock()
+	defer o.m.Unlock()
+
+	labels := o.itemLabels(item)
+	key := labelsString(labels)
+	s, ok := o.streams[key]
+	if !ok {
+		s = &stream{labels: labels}
+		o.streams[key] = s
+	}
+
+	ts, ok := item.Metadata[panyl.MetadataTimestamp].(time.Time)
+	if !ok {
+		ts = time.Now()
+	}
+	line := o.line(item)
+	s.entries = append(s.entries, entry{ts: ts, line: line})
+	if o.batchCount == 0 && o.batchWait > 0 {
+		o.startBatchTimer(ctx)
+	}
+	o.batchCount++
+	o.batchLen += len(line)
+
+	if (o.batchSize > 0 && o.batchCount >= o.batchSize) || (o.batchBytes > 0 && o.batchLen >= o.batchBytes) {
+		o.flush(ctx)
+	}
+	return ctx.Err() == nil
+}
+
+// startBatchTimer starts the timer sending the batch after the batch wait time. The flush doesn't use the
+// cancellation of the context, as it may be called after the item which started the batch was processed.
+func (o *Output) startBatchTimer(ctx context.Context) {
+	flushCtx := context.WithoutCancel(ctx)
+	batchID := o.batchID
+	o.batchTimer = time.AfterFunc(o.batchWait, func() {
+		o.m.Lock()
+		defer o.m.Unlock()
+		if o.batchID == batchID {
+			o.flush(flushCtx)
+		}
+	})
+}
+
+func (o *Output) OnFlush(ctx context.Context) {
+	o.m.Lock()
+	defer o.m.Unlock()
+	o.flush(ctx)
+}
+
+func (o *Output) OnClose(ctx context.Context) {
+	// flushing also stops the batch timer
+	o.OnFlush(ctx)
+}
+
+func (o *Output) itemLabels(item *panyl.Item) map[string]string {
+	labels := make(map[string]string, len(o.labels)+len(o.staticLabels))
+	for name, value := range o.staticLabels {
+		labels[labelName(name)] = value
+	}
+	for _, name := range o.labels {
+		v, ok := item.Metadata[name]
+		if !ok {
+			continue
+		}
+		value := fmt.Sprint(v)
+		if value == "" {
+			continue
+		}
+		labels[labelName(name)] = o.limitLabelValue(name, value)
+	}
+	if len(labels) == 0 {
+		// Loki requires at least one label
+		labels[DefaultJobLabel] = DefaultJobLabelValue
+	}
+	return labels
+}
+
+// limitLabelValue returns OtherLabelValue if the value is new and the label reached the maximum amount of values.
+func (o *Output) limitLabelValue(name, value string) string {
+	if o.maxLabelValues <= 0 {
+		return value
+	}
+	values, ok := o.labelValues[name]
+	if !ok {
+		values = map[string]struct{}{}
+		o.labelValues[name] = values
+	}
+	if _, ok := values[value]; ok {
+		return value
+	}
+	if len(values) >= o.maxLabelValues {
+		return OtherLabelValue
+	}
+	values[value] = struct{}{}
+	return value
+}
+
+// flush sends the batch, retrying on failure.
+func (o *Output) flush(ctx context.Context) {
+	if o.batchTimer != nil {
+		o.batchTimer.Stop()
+		o.batchTimer = nil
+	}
+	o.batchID++
+	if o.batchCount == 0 {
+		return
+	}
+	streams := make([]*stream, 0, len(o.streams))
+	for _, s := range o.streams {
+		slices.SortStableFunc(s.entries, func(a, b entry) int {
+			return a.ts.Compare(b.ts)
+		})
+		streams = append(streams, s)
+	}
+	sort.Slice(streams, func(i, j int) bool {
+		return labelsString(streams[i].labels) < labelsString(streams[j].labels)
+	})
+	o.streams = map[string]*stream{}
+	o.batchCount = 0
+	o.batchLen = 0
+
+	var body []byte
+	var contentType string
+	var err error
+	switch o.encoding {
+	case EncodingProtobuf:
+		body, contentType = snappy.Encode(nil, encodeProtobuf(streams)), "application/x-protobuf"
+	default:
+		body, err = encodeJSON(streams)
+		contentType = "application/json"
+	}
+	if err != nil {
+		o.handleError(ctx, err)
+		return
+	}
+
+	backoff := o.backoff
+	for retry := 0; ; retry++ {
+		retryable, err := o.send(ctx, body, contentType)
+		if err == nil {
+			return
+		}
+		if !retryable || retry >= o.maxRetries || ctx.Err() != nil {
+			o.handleError(ctx, err)
+			return
+		}
+		select {
+		case <-ctx.Done():
+			o.handleError(ctx, err)
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (o *Output) send(ctx context.Context, body []byte, contentType string) (retryable bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	for name, values := range o.header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500,
+			fmt.Errorf("push request returned status %s: %s", resp.Status, strings.TrimSpace(string(respBody)))
+	}
+	return false, nil
+}
+
+func (o *Output) handleError(ctx context.Context, err error) {
+	if o.onError != nil {
+		o.onError(ctx, err)
+		return
+	}
+	panyl.SLogFromContext(ctx).WarnContext(ctx, "error sending items to loki", slog.Any("error", err))
+}
+
+type jsonPushRequest struct {
+	Streams []jsonStream `json:"streams"`
+}
+
+type jsonStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+func encodeJSON(streams []*stream) ([]byte, error) {
+	req := jsonPushRequest{Streams: make([]jsonStream, 0, len(streams))}
+	for _, s := range streams {
+		js := jsonStream{Stream: s.labels, Values: make([][2]string, 0, len(s.entries))}
+		for _, e := range s.entries {
+			js.Values = append(js.Values, [2]string{strconv.FormatInt(e.ts.UnixNano(), 10), e.line})
+		}
+		req.Streams = append(req.Streams, js)
+	}
+	return json.Marshal(req)
+}
+
+// labelsString returns the labels in the Prometheus format, like {app="api", level="error"}, sorted by name.
+func labelsString(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(name)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(labels[name]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// labelName replaces the characters not allowed in label names by "_".
+func labelName(name string) string {
+	b := []byte(name)
+	for i, c := range b {
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || (i > 0 && c >= '0' && c <= '9')) {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
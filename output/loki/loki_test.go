@@ -0,0 +1,214 @@
+package loki
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/RangelReale/panyl/v2"
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+)
+
+type pushRequest struct {
+	contentType string
+	tenantID    string
+	body        []byte
+}
+
+func pushServer(t *testing.T, status func(n int) int) (*httptest.Server, *[]pushRequest) {
+	var requests []pushRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/loki/api/v1/push", r.URL.Path)
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		requests = append(requests, pushRequest{
+			contentType: r.Header.Get("Content-Type"),
+			tenantID:    r.Header.Get("X-Scope-OrgID"),
+			body:        body,
+		})
+		if status != nil {
+			w.WriteHeader(status(len(requests)))
+		} else {
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	return server, &requests
+}
+
+func lokiItem(ts time.Time, application, level, message string) *panyl.Item {
+	item := panyl.InitItem()
+	item.Metadata[panyl.MetadataTimestamp] = ts
+	if application != "" {
+		item.Metadata[panyl.MetadataApplication] = application
+	}
+	item.Metadata[panyl.MetadataLevel] = level
+	item.Metadata[panyl.MetadataMessage] = message
+	return item
+}
+
+func TestOutput_JSON(t *testing.T) {
+	ctx := context.Background()
+	ts := time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)
+
+	server, requests := pushServer(t, nil)
+	defer server.Close()
+
+	o := New(server.URL, WithBatch(3, 0, 0), WithTenantID("team"), WithMaxLabelValues(2),
+		WithStaticLabels(map[string]string{"host": "h1"}))
+	o.OnItem(ctx, lokiItem(ts, "api", "info", "started"))
+	o.OnItem(ctx, lokiItem(ts.Add(time.Second), "db", "info", "ready"))
+	o.OnItem(ctx, lokiItem(ts.Add(2*time.Second), "api", "info", "request"))
+	o.OnItem(ctx, lokiItem(ts.Add(3*time.Second), "cache", "info", "ready"))
+	o.OnFlush(ctx)
+
+	if assert.Len(t, *requests, 2) {
+		assert.Equal(t, "application/json", (*requests)[0].contentType)
+		assert.Equal(t, "team", (*requests)[0].tenantID)
+
+		var req jsonPushRequest
+		assert.NoError(t, json.Unmarshal((*requests)[0].body, &req))
+		assert.Equal(t, []jsonStream{
+			{
+				Stream: map[string]string{"application": "api", "level": "info", "host": "h1"},
+				Values: [][2]string{{"1709632800000000000", "started"}, {"1709632802000000000", "request"}},
+			},
+			{
+				Stream: map[string]string{"application": "db", "level": "info", "host": "h1"},
+				Values: [][2]string{{"1709632801000000000", "ready"}},
+			},
+		}, req.Streams)
+
+		// the third application exceeds the label values limit
+		assert.NoError(t, json.Unmarshal((*requests)[1].body, &req))
+		if assert.Len(t, req.Streams, 1) {
+			assert.Equal(t, OtherLabelValue, req.Streams[0].Stream["application"])
+		}
+	}
+}
+
+func TestOutput_BatchWait(t *testing.T) {
+	ctx := context.Background()
+	ts := time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)
+
+	bodies := make(chan []byte, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		bodies <- body
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	o := New(server.URL, WithBatch(0, 0, 50*time.Millisecond))
+	o.OnItem(ctx, lokiItem(ts.Add(time.Second), "api", "info", "second"))
+	o.OnItem(ctx, lokiItem(ts, "api", "info", "first"))
+
+	// the batch is sent by the timer, without waiting for another item
+	select {
+	case body := <-bodies:
+		var req jsonPushRequest
+		assert.NoError(t, json.Unmarshal(body, &req))
+		if assert.Len(t, req.Streams, 1) {
+			// the entries are sorted by timestamp
+			assert.Equal(t, [][2]string{{"1709632800000000000", "first"}, {"1709632801000000000", "second"}},
+				req.Streams[0].Values)
+		}
+	case <-time.After(time.Second):
+		assert.Fail(t, "batch was not sent")
+	}
+
+	// the timer is stopped when the batch is sent before it
+	o.OnItem(ctx, lokiItem(ts, "api", "info", "third"))
+	o.OnClose(ctx)
+	assert.Len(t, bodies, 1)
+	time.Sleep(100 * time.Millisecond)
+	assert.Len(t, bodies, 1)
+}
+
+func TestOutput_Protobuf(t *testing.T) {
+	ctx := context.Background()
+	ts := time.Date(2024, 3, 5, 10, 0, 0, 500, time.UTC)
+
+	server, requests := pushServer(t, nil)
+	defer server.Close()
+
+	o := New(server.URL, WithEncoding(EncodingProtobuf), WithLabels(panyl.MetadataApplication))
+	o.OnItem(ctx, lokiItem(ts, "", "info", "no labels"))
+	o.OnClose(ctx)
+
+	if assert.Len(t, *requests, 1) {
+		assert.Equal(t, "application/x-protobuf", (*requests)[0].contentType)
+		data, err := snappy.Decode(nil, (*requests)[0].body)
+		assert.NoError(t, err)
+
+		streams := decodeFields(t, data)
+		if assert.Len(t, streams[1], 1) {
+			stream := decodeFields(t, streams[1][0].([]byte))
+			assert.Equal(t, `{job="panyl"}`, string(stream[1][0].([]byte)))
+			entry := decodeFields(t, stream[2][0].([]byte))
+			assert.Equal(t, "no labels", string(entry[2][0].([]byte)))
+			timestamp := decodeFields(t, entry[1][0].([]byte))
+			assert.Equal(t, uint64(ts.Unix()), timestamp[1][0])
+			assert.Equal(t, uint64(500), timestamp[2][0])
+		}
+	}
+}
+
+func TestOutput_Retry(t *testing.T) {
+	ctx := context.Background()
+
+	server, requests := pushServer(t, func(n int) int {
+		if n == 1 {
+			return http.StatusTooManyRequests
+		}
+		if n == 2 {
+			return http.StatusNoContent
+		}
+		return http.StatusBadRequest
+	})
+	defer server.Close()
+
+	var errs []error
+	o := New(server.URL, WithRetry(3, time.Millisecond), WithOnError(func(ctx context.Context, err error) {
+		errs = append(errs, err)
+	}))
+	o.OnItem(ctx, lokiItem(time.Now(), "api", "info", "first"))
+	o.OnFlush(ctx)
+	assert.Len(t, *requests, 2)
+	assert.Len(t, errs, 0)
+
+	// bad requests are not retried
+	o.OnItem(ctx, lokiItem(time.Now(), "api", "info", "second"))
+	o.OnFlush(ctx)
+	assert.Len(t, *requests, 3)
+	assert.Len(t, errs, 1)
+}
+
+// decodeFields decodes a protobuf message with only varint and bytes fields.
+func decodeFields(t *testing.T, data []byte) map[int][]any {
+	ret := map[int][]any{}
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		data = data[n:]
+		switch key & 7 {
+		case 0:
+			value, n := binary.Uvarint(data)
+			data = data[n:]
+			ret[int(key>>3)] = append(ret[int(key>>3)], value)
+		case 2:
+			size, n := binary.Uvarint(data)
+			data = data[n:]
+			ret[int(key>>3)] = append(ret[int(key>>3)], data[:size])
+			data = data[size:]
+		default:
+			t.Fatalf("unexpected wire type %d", key&7)
+		}
+	}
+	return ret
+}
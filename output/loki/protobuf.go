@@ -0,0 +1,50 @@
+package loki
+
+import (
+	"encoding/binary"
+)
+
+// encodeProtobuf encodes the streams as a logproto.PushRequest message:
+//
+//	message PushRequest { repeated StreamAdapter streams = 1; }
+//	message StreamAdapter { string labels = 1; repeated EntryAdapter entries = 2; }
+//	message EntryAdapter { google.protobuf.Timestamp timestamp = 1; string line = 2; }
+//	message Timestamp { int64 seconds = 1; int32 nanos = 2; }
+func encodeProtobuf(streams []*stream) []byte {
+	var req []byte
+	for _, s := range streams {
+		var sm []byte
+		sm = appendBytesField(sm, 1, []byte(labelsString(s.labels)))
+		for _, e := range s.entries {
+			var ts []byte
+			if seconds := e.ts.Unix(); seconds != 0 {
+				ts = appendVarintField(ts, 1, uint64(seconds))
+			}
+			if nanos := e.ts.Nanosecond(); nanos != 0 {
+				ts = appendVarintField(ts, 2, uint64(nanos))
+			}
+			var em []byte
+			em = appendBytesField(em, 1, ts)
+			em = appendBytesField(em, 2, []byte(e.line))
+			sm = appendBytesField(sm, 2, em)
+		}
+		req = appendBytesField(req, 1, sm)
+	}
+	return req
+}
+
+const (
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+func appendVarintField(b []byte, field int, value uint64) []byte {
+	b = binary.AppendUvarint(b, uint64(field<<3|wireVarint))
+	return binary.AppendUvarint(b, value)
+}
+
+func appendBytesField(b []byte, field int, value []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(field<<3|wireBytes))
+	b = binary.AppendUvarint(b, uint64(len(value)))
+	return append(b, value...)
+}
@@ -0,0 +1,269 @@
+package parquet
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/RangelReale/panyl/v2"
+)
+
+// ColumnType is the type of a column in the Parquet file. All columns are optional.
+type ColumnType int
+
+const (
+	// ColumnString is a UTF-8 BYTE_ARRAY column. Values which are not strings are converted with fmt, and maps and
+	// slices are encoded as JSON.
+	ColumnString ColumnType = iota
+	// ColumnInt64 is an INT64 column. Floats are only accepted if they have no fraction, and values outside of the
+	// int64 range are not accepted.
+	ColumnInt64
+	// ColumnDouble is a DOUBLE column.
+	ColumnDouble
+	// ColumnBool is a BOOLEAN column.
+	ColumnBool
+	// ColumnTimestamp is an INT64 column with microseconds since the Unix epoch in UTC. Strings are parsed as
+	// time.RFC3339Nano.
+	ColumnTimestamp
+)
+
+// Column is a column of the Parquet file.
+type Column struct {
+	Name  string
+	Type  ColumnType
+	Value func(item *panyl.Item) any // returns nil for no value
+}
+
+// MetadataColumn returns a column with the value of a Metadata key.
+func MetadataColumn(name string, key string, columnType ColumnType) Column {
+	return Column{Name: name, Type: columnType, Value: func(item *panyl.Item) any {
+		return item.Metadata[key]
+	}}
+}
+
+// DataColumn returns a column with the value of a Data field. Dots in the field name access nested maps.
+func DataColumn(name string, field string, columnType ColumnType) Column {
+	return Column{Name: name, Type: columnType, Value: func(item *panyl.Item) any {
+		var value any = map[string]any(item.Data)
+		for _, part := range strings.Split(field, ".") {
+			m, ok := asMap(value)
+			if !ok {
+				return nil
+			}
+			value = m[part]
+		}
+		return value
+	}}
+}
+
+// DefaultColumns are the columns written for each item, besides the inferred Data columns.
+var DefaultColumns = []Column{
+	MetadataColumn("timestamp", panyl.MetadataTimestamp, ColumnTimestamp),
+	MetadataColumn("level", panyl.MetadataLevel, ColumnString),
+	MetadataColumn("application", panyl.MetadataApplication, ColumnString),
+	MetadataColumn("format", panyl.MetadataFormat, ColumnString),
+	MetadataColumn("category", panyl.MetadataCategory, ColumnString),
+	{Name: "message", Type: ColumnString, Value: func(item *panyl.Item) any {
+		if message := item.Metadata.StringValue(panyl.MetadataMessage); message != "" {
+			return message
+		}
+		if item.Line != "" {
+			return item.Line
+		}
+		return nil
+	}},
+	{Name: "line_no", Type: ColumnInt64, Value: func(item *panyl.Item) any { return item.LineNo }},
+	{Name: "line_count", Type: ColumnInt64, Value: func(item *panyl.Item) any { return item.LineCount }},
+}
+
+const (
+	// DataColumnPrefix is the prefix of the inferred Data columns.
+	DataColumnPrefix = "data_"
+	// ExtraColumn is the JSON column with the Data fields which are not in the inferred columns.
+	ExtraColumn = "data_extra"
+)
+
+// flattenData returns the Data fields with nested maps flattened, joining the names with underscores, which unlike
+// dots don't need quoting in most query engines. Fields whose flattened names collide, like "a_b" and "b" nested in
+// "a", are returned in extra with the names joined with dots instead, so the result doesn't depend on the map order.
+func flattenData(data map[string]any) (flat map[string]any, extra map[string]any) {
+	type field struct {
+		path  string
+		value any
+	}
+	fields := map[string][]field{}
+	var flatten func(name, path string, m map[string]any)
+	flatten = func(name, path string, m map[string]any) {
+		for k, v := range m {
+			if nested, ok := asMap(v); ok && len(nested) > 0 {
+				flatten(name+k+"_", path+k+".", nested)
+				continue
+			}
+			if v != nil {
+				fields[name+k] = append(fields[name+k], field{path: path + k, value: v})
+			}
+		}
+	}
+	flatten("", "", data)
+
+	flat = make(map[string]any, len(fields))
+	for name, values := range fields {
+		if len(values) == 1 {
+			flat[name] = values[0].value
+			continue
+		}
+		if extra == nil {
+			extra = map[string]any{}
+		}
+		for _, f := range values {
+			extra[f.path] = f.value
+		}
+	}
+	return flat, extra
+}
+
+// inferColumns returns the columns of the flattened Data fields of the rows, sorted by name, up to max columns.
+// Fields with values of different types are strings, except integers and floats which are doubles.
+func inferColumns(rows []map[string]any, max int) []Column {
+	types := map[string]ColumnType{}
+	for _, row := range rows {
+		for name, value := range row {
+			valueType := valueColumnType(value)
+			if current, ok := types[name]; ok && current != valueType {
+				if isNumeric(current) && isNumeric(valueType) {
+					valueType = ColumnDouble
+				} else {
+					valueType = ColumnString
+				}
+			}
+			types[name] = valueType
+		}
+	}
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) > max {
+		names = names[:max]
+	}
+
+	ret := make([]Column, 0, len(names))
+	for _, name := range names {
+		ret = append(ret, Column{Name: DataColumnPrefix + name, Type: types[name]})
+	}
+	return ret
+}
+
+func isNumeric(columnType ColumnType) bool {
+	return columnType == ColumnInt64 || columnType == ColumnDouble
+}
+
+// valueColumnType returns the column type of a value.
+func valueColumnType(value any) ColumnType {
+	switch value.(type) {
+	case bool:
+		return ColumnBool
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return ColumnInt64
+	case float32, float64:
+		return ColumnDouble
+	case time.Time:
+		return ColumnTimestamp
+	}
+	return ColumnString
+}
+
+// convertValue converts a value to the column type, returning false if it is not possible.
+func convertValue(value any, columnType ColumnType) (any, bool) {
+	switch columnType {
+	case ColumnString:
+		switch v := value.(type) {
+		case string:
+			return v, true
+		case time.Time:
+			return v.Format(time.RFC3339Nano), true
+		case map[string]any, panyl.MapValue, []any:
+			b, err := json.Marshal(v)
+			if err != nil {
+				return nil, false
+			}
+			return string(b), true
+		}
+		return fmt.Sprint(value), true
+	case ColumnInt64:
+		switch v := value.(type) {
+		case int:
+			return int64(v), true
+		case int8:
+			return int64(v), true
+		case int16:
+			return int64(v), true
+		case int32:
+			return int64(v), true
+		case int64:
+			return v, true
+		case uint:
+			if uint64(v) <= math.MaxInt64 {
+				return int64(v), true
+			}
+		case uint8:
+			return int64(v), true
+		case uint16:
+			return int64(v), true
+		case uint32:
+			return int64(v), true
+		case uint64:
+			if v <= math.MaxInt64 {
+				return int64(v), true
+			}
+		case float64:
+			// -2^63 and 2^63 are exact as floats, the conversion of values outside the range is undefined
+			if v == math.Trunc(v) && v >= math.MinInt64 && v < math.MaxInt64 {
+				return int64(v), true
+			}
+		}
+	case ColumnDouble:
+		switch v := value.(type) {
+		case float64:
+			return v, true
+		case float32:
+			return float64(v), true
+		case uint:
+			return float64(v), true
+		case uint64:
+			return float64(v), true
+		default:
+			if i, ok := convertValue(value, ColumnInt64); ok {
+				return float64(i.(int64)), true
+			}
+		}
+	case ColumnBool:
+		if v, ok := value.(bool); ok {
+			return v, true
+		}
+	case ColumnTimestamp:
+		switch v := value.(type) {
+		case time.Time:
+			return v.UnixMicro(), true
+		case string:
+			if ts, err := time.Parse(time.RFC3339Nano, v); err == nil {
+				return ts.UnixMicro(), true
+			}
+		}
+	}
+	return nil, false
+}
+
+func asMap(value any) (map[string]any, bool) {
+	switch v := value.(type) {
+	case map[string]any:
+		return v, true
+	case panyl.MapValue:
+		return v, true
+	}
+	return nil, false
+}
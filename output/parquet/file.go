@@ -0,0 +1,303 @@
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"github.com/golang/snappy"
+)
+
+// Compression is the compression codec of the pages.
+type Compression int
+
+// The values are the Parquet CompressionCodec values.
+const (
+	CompressionNone   Compression = 0
+	CompressionSnappy Compression = 1
+	CompressionGzip   Compression = 2
+)
+
+var magic = []byte("PAR1")
+
+// Parquet physical types, converted types, repetition and encodings.
+const (
+	typeBoolean   = 0
+	typeInt64     = 2
+	typeDouble    = 5
+	typeByteArray = 6
+
+	convertedUTF8            = 0
+	convertedTimestampMicros = 10
+
+	repetitionOptional = 1
+
+	encodingPlain = 0
+	encodingRLE   = 3
+
+	pageTypeData = 0
+)
+
+// fileWriter writes a Parquet file with one data page for each column chunk. The file is written with a ".tmp"
+// suffix, and renamed when it is closed.
+type fileWriter struct {
+	f         *os.File
+	path      string
+	columns   []Column
+	data      map[string]int // index of the inferred Data columns, by the flattened field name
+	extra     int            // index of ExtraColumn, or -1
+	offset    int64
+	numRows   int64
+	rowGroups []rowGroupMeta
+	created   time.Time
+}
+
+type rowGroupMeta struct {
+	columns       []columnChunkMeta
+	numRows       int64
+	totalByteSize int64
+}
+
+type columnChunkMeta struct {
+	offset           int64
+	numValues        int64
+	uncompressedSize int64
+	compressedSize   int64
+}
+
+func createFile(path string, columns []Column) (*fileWriter, error) {
+	f, err := os.Create(path + ".tmp")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Write(magic); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return &fileWriter{
+		f:       f,
+		path:    path,
+		columns: columns,
+		data:    map[string]int{},
+		extra:   -1,
+		offset:  int64(len(magic)),
+		created: time.Now(),
+	}, nil
+}
+
+// writeRowGroup writes a row group with the values of each column, nil meaning no value.
+func (w *fileWriter) writeRowGroup(values [][]any, numRows int, compression Compression) error {
+	rg := rowGroupMeta{numRows: int64(numRows)}
+	for i, column := range w.columns {
+		page := encodePage(column.Type, values[i])
+		compressed, err := compress(page, compression)
+		if err != nil {
+			return err
+		}
+
+		h := newThriftWriter()
+		h.i32(1, pageTypeData)
+		h.i32(2, int32(len(page)))
+		h.i32(3, int32(len(compressed)))
+		h.structBegin(5) // DataPageHeader
+		h.i32(1, int32(numRows))
+		h.i32(2, encodingPlain)
+		h.i32(3, encodingRLE)
+		h.i32(4, encodingRLE)
+		h.structEnd()
+		header := h.finish()
+
+		chunk := columnChunkMeta{
+			offset:           w.offset,
+			numValues:        int64(numRows),
+			uncompressedSize: int64(len(header) + len(page)),
+			compressedSize:   int64(len(header) + len(compressed)),
+		}
+		if err := w.write(header, compressed); err != nil {
+			return err
+		}
+		rg.columns = append(rg.columns, chunk)
+		rg.totalByteSize += chunk.uncompressedSize
+	}
+	w.rowGroups = append(w.rowGroups, rg)
+	w.numRows += int64(numRows)
+	return nil
+}
+
+// close writes the footer and renames the file.
+func (w *fileWriter) close(compression Compression) error {
+	footer := w.footer(compression)
+	size := binary.LittleEndian.AppendUint32(nil, uint32(len(footer)))
+	err := w.write(footer, size, magic)
+	if closeErr := w.f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(w.path+".tmp", w.path)
+}
+
+// abort closes and removes the file after an error.
+func (w *fileWriter) abort() {
+	_ = w.f.Close()
+	_ = os.Remove(w.path + ".tmp")
+}
+
+func (w *fileWriter) write(data ...[]byte) error {
+	for _, d := range data {
+		n, err := w.f.Write(d)
+		w.offset += int64(n)
+		if err != nil {
+			return fmt.Errorf("error writing %s: %w", w.path, err)
+		}
+	}
+	return nil
+}
+
+// footer encodes the FileMetaData.
+func (w *fileWriter) footer(compression Compression) []byte {
+	t := newThriftWriter()
+	t.i32(1, 1) // version
+
+	t.listBegin(2, thriftStruct, len(w.columns)+1) // schema
+	t.structBegin(0)
+	t.string(4, "schema")
+	t.i32(5, int32(len(w.columns)))
+	t.structEnd()
+	for _, column := range w.columns {
+		physicalType, convertedType := columnTypes(column.Type)
+		t.structBegin(0)
+		t.i32(1, physicalType)
+		t.i32(3, repetitionOptional)
+		t.string(4, column.Name)
+		if convertedType >= 0 {
+			t.i32(6, convertedType)
+		}
+		t.structEnd()
+	}
+
+	t.i64(3, w.numRows)
+
+	t.listBegin(4, thriftStruct, len(w.rowGroups))
+	for _, rg := range w.rowGroups {
+		t.structBegin(0)
+		t.listBegin(1, thriftStruct, len(rg.columns))
+		for i, chunk := range rg.columns {
+			physicalType, _ := columnTypes(w.columns[i].Type)
+			t.structBegin(0) // ColumnChunk
+			t.i64(2, chunk.offset)
+			t.structBegin(3) // ColumnMetaData
+			t.i32(1, physicalType)
+			t.i32List(2, []int32{encodingPlain, encodingRLE})
+			t.stringList(3, []string{w.columns[i].Name})
+			t.i32(4, int32(compression))
+			t.i64(5, chunk.numValues)
+			t.i64(6, chunk.uncompressedSize)
+			t.i64(7, chunk.compressedSize)
+			t.i64(9, chunk.offset)
+			t.structEnd()
+			t.structEnd()
+		}
+		t.i64(2, rg.totalByteSize)
+		t.i64(3, rg.numRows)
+		t.structEnd()
+	}
+
+	t.string(6, "panyl")
+	return t.finish()
+}
+
+// columnTypes returns the physical and converted types of a column type, -1 meaning no converted type.
+func columnTypes(columnType ColumnType) (int32, int32) {
+	switch columnType {
+	case ColumnInt64:
+		return typeInt64, -1
+	case ColumnDouble:
+		return typeDouble, -1
+	case ColumnBool:
+		return typeBoolean, -1
+	case ColumnTimestamp:
+		return typeInt64, convertedTimestampMicros
+	}
+	return typeByteArray, convertedUTF8
+}
+
+// encodePage encodes the content of a data page: the definition levels, as the columns are optional, and the
+// non-nil values using the plain encoding.
+func encodePage(columnType ColumnType, values []any) []byte {
+	levels := encodeLevels(values)
+	page := binary.LittleEndian.AppendUint32(nil, uint32(len(levels)))
+	page = append(page, levels...)
+
+	var bits byte
+	var nbits int
+	for _, value := range values {
+		switch v := value.(type) {
+		case nil:
+		case string:
+			page = binary.LittleEndian.AppendUint32(page, uint32(len(v)))
+			page = append(page, v...)
+		case int64:
+			page = binary.LittleEndian.AppendUint64(page, uint64(v))
+		case float64:
+			page = binary.LittleEndian.AppendUint64(page, math.Float64bits(v))
+		case bool:
+			// bit-packed, least significant bit first
+			if v {
+				bits |= 1 << nbits
+			}
+			if nbits++; nbits == 8 {
+				page = append(page, bits)
+				bits, nbits = 0, 0
+			}
+		}
+	}
+	if columnType == ColumnBool && nbits > 0 {
+		page = append(page, bits)
+	}
+	return page
+}
+
+// encodeLevels encodes the definition levels, 1 for values and 0 for nil, using RLE runs of the
+// RLE/bit-packing hybrid encoding with bit width 1.
+func encodeLevels(values []any) []byte {
+	var ret []byte
+	for i := 0; i < len(values); {
+		defined := values[i] != nil
+		j := i + 1
+		for j < len(values) && (values[j] != nil) == defined {
+			j++
+		}
+		ret = binary.AppendUvarint(ret, uint64(j-i)<<1)
+		if defined {
+			ret = append(ret, 1)
+		} else {
+			ret = append(ret, 0)
+		}
+		i = j
+	}
+	return ret
+}
+
+func compress(data []byte, compression Compression) ([]byte, error) {
+	switch compression {
+	case CompressionSnappy:
+		return snappy.Encode(nil, data), nil
+	case CompressionGzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	return data, nil
+}
@@ -0,0 +1,319 @@
+// Package parquet provides an Output writing items to Parquet files, for loading into analytics engines like DuckDB
+// and Spark.
+package parquet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/RangelReale/panyl/v2"
+)
+
+const (
+	DefaultRowGroupSize   = 10000
+	DefaultMaxFileSize    = 128 * 1024 * 1024
+	DefaultMaxDataColumns = 256
+)
+
+// DefaultFileName names the files as "panyl-<UTC creation time>-<sequence>.parquet".
+func DefaultFileName(created time.Time, sequence int) string {
+	return fmt.Sprintf("panyl-%s-%04d.parquet", created.UTC().Format("20060102-150405"), sequence)
+}
+
+// Output writes items to Parquet files in a directory. Each file has the DefaultColumns, or the ones set with
+// WithColumns, and columns for the flattened Data fields, named with DataColumnPrefix. The Data columns and their
+// types are inferred from the items of the first row group of each file, and the fields which are not in them, have
+// values of a different type, or whose flattened names collide, are written as JSON in ExtraColumn.
+//
+// The items are buffered in memory, and written as a row group every DefaultRowGroupSize items, on OnFlush and on
+// OnClose. A new file is started after the maximum file size is reached, checked when a row group is written. When
+// the maximum file age is reached, a timer writes the buffered items and closes the file, even if no items are
+// received. Files are written with a ".tmp" suffix, which is removed when they are complete.
+type Output struct {
+	dir            string
+	fileName       func(created time.Time, sequence int) string
+	columns        []Column
+	inferData      bool
+	maxDataColumns int
+	rowGroupSize   int
+	maxFileSize    int64
+	maxFileAge     time.Duration
+	compression    Compression
+	onError        func(ctx context.Context, err error)
+
+	rows     []row
+	file     *fileWriter
+	ageTimer *time.Timer
+	sequence int
+	m        sync.Mutex
+}
+
+var _ panyl.Output = (*Output)(nil)
+
+// row is an item converted to the column values.
+type row struct {
+	values []any          // values of the configured columns
+	data   map[string]any // flattened Data fields
+	extra  map[string]any // Data fields always written in the extra column
+}
+
+// New creates an Output writing files to dir.
+func New(dir string, options ...Option) *Output {
+	ret := &Output{
+		dir:            dir,
+		fileName:       DefaultFileName,
+		columns:        DefaultColumns,
+		inferData:      true,
+		maxDataColumns: DefaultMaxDataColumns,
+		rowGroupSize:   DefaultRowGroupSize,
+		maxFileSize:    DefaultMaxFileSize,
+		compression:    CompressionSnappy,
+	}
+	for _, opt := range options {
+		opt(ret)
+	}
+	return ret
+}
+
+type Option func(*Output)
+
+// WithFileName sets the function returning the name of each file in the directory, by default DefaultFileName.
+// The sequence starts at 1.
+func WithFileName(fileName func(created time.Time, sequence int) string) Option {
+	return func(o *Output) {
+		o.fileName = fileName
+	}
+}
+
+// WithColumns sets the columns written for each item, by default DefaultColumns.
+func WithColumns(columns ...Column) Option {
+	return func(o *Output) {
+		o.columns = columns
+	}
+}
+
+// WithInferData sets whether columns are inferred for the Data fields, true by default. If false, only the
+// configured columns are written.
+func WithInferData(inferData bool) Option {
+	return func(o *Output) {
+		o.inferData = inferData
+	}
+}
+
+// WithMaxDataColumns sets the maximum amount of inferred Data columns, by default DefaultMaxDataColumns.
+func WithMaxDataColumns(maxDataColumns int) Option {
+	return func(o *Output) {
+		o.maxDataColumns = maxDataColumns
+	}
+}
+
+// WithRowGroupSize sets the amount of items of each row group, by default DefaultRowGroupSize.
+func WithRowGroupSize(rowGroupSize int) Option {
+	return func(o *Output) {
+		o.rowGroupSize = rowGroupSize
+	}
+}
+
+// WithMaxFileSize sets the size after which a new file is started, by default DefaultMaxFileSize. Zero means no
+// limit.
+func WithMaxFileSize(maxFileSize int64) Option {
+	return func(o *Output) {
+		o.maxFileSize = maxFileSize
+	}
+}
+
+// WithMaxFileAge sets the time after the creation of a file after which a new one is started. Zero, the default,
+// means no limit.
+func WithMaxFileAge(maxFileAge time.Duration) Option {
+	return func(o *Output) {
+		o.maxFileAge = maxFileAge
+	}
+}
+
+// WithCompression sets the compression of the pages, by default CompressionSnappy.
+func WithCompression(compression Compression) Option {
+	return func(o *Output) {
+		o.compression = compression
+	}
+}
+
+// WithOnError sets a callback for errors writing the files.
+func WithOnError(onError func(ctx context.Context, err error)) Option {
+	return func(o *Output) {
+		o.onError = onError
+	}
+}
+
+func (o *Output) OnItem(ctx context.Context, item *panyl.Item) bool {
+	o.m.Lock()
+	defer o.m.Unlock()
+
+	r := row{values: make([]any, len(o.columns))}
+	for i, column := range o.columns {
+		r.values[i] = column.Value(item)
+	}
+	if o.inferData {
+		r.data, r.extra = flattenData(item.Data)
+	}
+	o.rows = append(o.rows, r)
+	if len(o.rows) >= o.rowGroupSize {
+		o.writeRowGroup(ctx)
+	}
+	return true
+}
+
+func (o *Output) OnFlush(ctx context.Context) {
+	o.m.Lock()
+	defer o.m.Unlock()
+	o.writeRowGroup(ctx)
+}
+
+func (o *Output) OnClose(ctx context.Context) {
+	o.m.Lock()
+	defer o.m.Unlock()
+	o.writeRowGroup(ctx)
+	o.closeFile(ctx)
+}
+
+// writeRowGroup writes the buffered rows, creating a file if needed, and closes the file if it reached the
+// maximum size or age.
+func (o *Output) writeRowGroup(ctx context.Context) {
+	if len(o.rows) == 0 {
+		return
+	}
+	rows := o.rows
+	o.rows = nil
+
+	if o.file == nil {
+		if err := o.createFile(ctx, rows); err != nil {
+			o.handleError(ctx, err)
+			return
+		}
+	}
+
+	if err := o.file.writeRowGroup(o.columnValues(rows), len(rows), o.compression); err != nil {
+		o.handleError(ctx, err)
+		o.file.abort()
+		o.file = nil
+		return
+	}
+
+	if (o.maxFileSize > 0 && o.file.offset >= o.maxFileSize) ||
+		(o.maxFileAge > 0 && time.Since(o.file.created) >= o.maxFileAge) {
+		o.closeFile(ctx)
+	}
+}
+
+// createFile creates a file, inferring the Data columns from the rows.
+func (o *Output) createFile(ctx context.Context, rows []row) error {
+	columns := append([]Column{}, o.columns...)
+	data := map[string]int{}
+	extra := -1
+	if o.inferData {
+		flattened := make([]map[string]any, len(rows))
+		for i, r := range rows {
+			flattened[i] = r.data
+		}
+		names := map[string]bool{ExtraColumn: true}
+		for _, column := range columns {
+			names[column.Name] = true
+		}
+		for _, column := range inferColumns(flattened, o.maxDataColumns) {
+			// fields with the same name as other columns are written in the extra column
+			if names[column.Name] {
+				continue
+			}
+			data[column.Name[len(DataColumnPrefix):]] = len(columns)
+			columns = append(columns, column)
+		}
+		extra = len(columns)
+		columns = append(columns, Column{Name: ExtraColumn, Type: ColumnString})
+	}
+
+	o.sequence++
+	file, err := createFile(filepath.Join(o.dir, o.fileName(time.Now(), o.sequence)), columns)
+	if err != nil {
+		return err
+	}
+	file.data, file.extra = data, extra
+	o.file = file
+
+	if o.maxFileAge > 0 {
+		// the timer doesn't use the cancellation of the context, as it runs after the item was processed
+		timerCtx := context.WithoutCancel(ctx)
+		o.ageTimer = time.AfterFunc(o.maxFileAge, func() {
+			o.m.Lock()
+			defer o.m.Unlock()
+			if o.file == file {
+				o.writeRowGroup(timerCtx)
+				o.closeFile(timerCtx)
+			}
+		})
+	}
+	return nil
+}
+
+// columnValues returns the values of each column of the rows, converted to the column types.
+func (o *Output) columnValues(rows []row) [][]any {
+	values := make([][]any, len(o.file.columns))
+	for i := range values {
+		values[i] = make([]any, len(rows))
+	}
+	for r, rw := range rows {
+		for i, value := range rw.values {
+			if value != nil {
+				values[i][r], _ = convertValue(value, o.file.columns[i].Type)
+			}
+		}
+		if o.file.extra < 0 {
+			continue
+		}
+		extra := maps.Clone(rw.extra)
+		if extra == nil {
+			extra = map[string]any{}
+		}
+		for name, value := range rw.data {
+			if i, ok := o.file.data[name]; ok {
+				if v, ok := convertValue(value, o.file.columns[i].Type); ok {
+					values[i][r] = v
+					continue
+				}
+			}
+			extra[name] = value
+		}
+		if len(extra) > 0 {
+			if b, err := json.Marshal(extra); err == nil {
+				values[o.file.extra][r] = string(b)
+			}
+		}
+	}
+	return values
+}
+
+func (o *Output) closeFile(ctx context.Context) {
+	if o.ageTimer != nil {
+		o.ageTimer.Stop()
+		o.ageTimer = nil
+	}
+	if o.file == nil {
+		return
+	}
+	if err := o.file.close(o.compression); err != nil {
+		o.handleError(ctx, err)
+	}
+	o.file = nil
+}
+
+func (o *Output) handleError(ctx context.Context, err error) {
+	if o.onError != nil {
+		o.onError(ctx, err)
+		return
+	}
+	panyl.SLogFromContext(ctx).WarnContext(ctx, "error writing parquet file", slog.Any("error", err))
+}
@@ -0,0 +1,472 @@
+package parquet
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/RangelReale/panyl/v2"
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutput(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	ts := time.Date(2024, 3, 5, 10, 0, 0, 123456000, time.UTC)
+
+	o := New(dir, WithRowGroupSize(2), WithColumns(DefaultColumns[:3]...))
+	for i, data := range []map[string]any{
+		{"user": map[string]any{"id": 10, "name": "ana"}, "ok": true},
+		{"user": map[string]any{"id": 11.5}, "ok": false},
+		{"user": map[string]any{"id": "x"}, "other": []any{"a"}},
+	} {
+		item := panyl.InitItem(panyl.WithInitLineNo(i + 1))
+		if i != 1 {
+			item.Metadata[panyl.MetadataTimestamp] = ts
+		}
+		item.Metadata[panyl.MetadataLevel] = "info"
+		item.Data.Merge(data)
+		o.OnItem(ctx, item)
+	}
+	o.OnClose(ctx)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	assert.NoError(t, err)
+	if !assert.Len(t, files, 1) || !assert.Regexp(t, `panyl-\d{8}-\d{6}-0001\.parquet$`, files[0]) {
+		return
+	}
+
+	columns, rowGroups := readParquet(t, files[0])
+	assert.Equal(t, []string{"timestamp", "level", "application", "data_ok", "data_user_id", "data_user_name",
+		"data_extra"}, columns)
+	if assert.Len(t, rowGroups, 2) {
+		assert.Equal(t, [][]any{
+			{ts.UnixMicro(), nil},
+			{"info", "info"},
+			{nil, nil},
+			{true, false},
+			{10.0, 11.5},
+			{"ana", nil},
+			{nil, nil},
+		}, rowGroups[0])
+		assert.Equal(t, [][]any{
+			{ts.UnixMicro()},
+			{"info"},
+			{nil},
+			{nil},
+			{nil},
+			{nil},
+			{`{"other":["a"],"user_id":"x"}`},
+		}, rowGroups[1])
+	}
+}
+
+func TestOutput_Rolling(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	sequence := 0
+	o := New(dir, WithRowGroupSize(1), WithMaxFileSize(10), WithCompression(CompressionGzip),
+		WithFileName(func(created time.Time, seq int) string {
+			sequence = seq
+			return fmt.Sprintf("file-%d.parquet", seq)
+		}))
+	for i := 0; i < 3; i++ {
+		o.OnItem(ctx, panyl.InitItem(panyl.WithInitLine("line")))
+		if i == 1 {
+			// a .tmp file is not written as the file is closed after each row group
+			tmp, _ := filepath.Glob(filepath.Join(dir, "*.tmp"))
+			assert.Empty(t, tmp)
+		}
+	}
+	o.OnClose(ctx)
+	assert.Equal(t, 3, sequence)
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.parquet"))
+	assert.Len(t, files, 3)
+}
+
+func TestOutput_FlattenCollision(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	o := New(dir, WithColumns())
+	item := panyl.InitItem()
+	item.Data.Merge(map[string]any{"a_b": 1, "a": map[string]any{"b": 2, "c": 3}})
+	o.OnItem(ctx, item)
+	o.OnClose(ctx)
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.parquet"))
+	if !assert.Len(t, files, 1) {
+		return
+	}
+	// the colliding fields are written in the extra column, with the names joined with dots
+	columns, rowGroups := readParquet(t, files[0])
+	assert.Equal(t, []string{"data_a_c", "data_extra"}, columns)
+	if assert.Len(t, rowGroups, 1) {
+		assert.Equal(t, [][]any{{int64(3)}, {`{"a.b":2,"a_b":1}`}}, rowGroups[0])
+	}
+}
+
+func TestConvertValue_Int64(t *testing.T) {
+	for _, test := range []struct {
+		value    any
+		expected any
+		ok       bool
+	}{
+		{int(-5), int64(-5), true},
+		{uint64(math.MaxInt64), int64(math.MaxInt64), true},
+		{uint64(math.MaxInt64 + 1), nil, false},
+		{uint(math.MaxUint64), nil, false},
+		{float64(1 << 62), int64(1 << 62), true},
+		{float64(-(1 << 63)), int64(math.MinInt64), true},
+		{float64(1 << 63), nil, false},
+		{-1e19, nil, false},
+		{1.5, nil, false},
+		{math.NaN(), nil, false},
+		{math.Inf(1), nil, false},
+	} {
+		value, ok := convertValue(test.value, ColumnInt64)
+		assert.Equal(t, test.ok, ok, "%v", test.value)
+		assert.Equal(t, test.expected, value, "%v", test.value)
+	}
+
+	// unsigned values outside the int64 range are still accepted as doubles
+	value, ok := convertValue(uint64(math.MaxUint64), ColumnDouble)
+	assert.True(t, ok)
+	assert.Equal(t, float64(math.MaxUint64), value)
+}
+
+var updateGolden = flag.Bool("update", false, "update the golden files in testdata")
+
+// goldenItems writes the items of testdata/golden.parquet.
+func goldenItems(ctx context.Context, o *Output) {
+	ts := time.Date(2024, 3, 5, 10, 0, 0, 123456000, time.UTC)
+	for i, data := range []map[string]any{
+		{"user": map[string]any{"id": 10, "name": "ana"}, "ok": true},
+		{"user": map[string]any{"id": 11.5}, "ok": false},
+		{"user": map[string]any{"id": "x"}, "other": []any{"a"}},
+	} {
+		item := panyl.InitItem(panyl.WithInitLineNo(i + 1))
+		if i != 1 {
+			item.Metadata[panyl.MetadataTimestamp] = ts.Add(time.Duration(i) * time.Second)
+		}
+		item.Metadata[panyl.MetadataLevel] = "info"
+		item.Data.Merge(data)
+		o.OnItem(ctx, item)
+	}
+	o.OnClose(ctx)
+}
+
+// goldenColumns are the values of testdata/golden.parquet, with the timestamps in microseconds.
+var goldenColumns = map[string][]any{
+	"timestamp":      {1709632800123456.0, nil, 1709632802123456.0},
+	"level":          {"info", "info", "info"},
+	"application":    {nil, nil, nil},
+	"data_ok":        {true, false, nil},
+	"data_other":     {nil, nil, `["a"]`},
+	"data_user_id":   {"10", "11.5", "x"},
+	"data_user_name": {"ana", nil, nil},
+	"data_extra":     {nil, nil, nil},
+}
+
+func TestOutput_Golden(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	o := New(dir, WithColumns(DefaultColumns[:3]...), WithFileName(func(created time.Time, sequence int) string {
+		return "golden.parquet"
+	}))
+	goldenItems(ctx, o)
+
+	data, err := os.ReadFile(filepath.Join(dir, "golden.parquet"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	golden := filepath.Join("testdata", "golden.parquet")
+	if *updateGolden {
+		assert.NoError(t, os.WriteFile(golden, data, 0o644))
+	}
+	expected, err := os.ReadFile(golden)
+	if assert.NoError(t, err) {
+		assert.Equal(t, expected, data, "run the tests with -update and check the new file with an independent reader")
+	}
+
+	// the values expected from the independent readers
+	assert.Equal(t, goldenColumns, decodedColumns(t, golden))
+}
+
+// decodedColumns returns the values of each column of a file decoded by readParquet, with integers as float64 like
+// decoded from JSON.
+func decodedColumns(t *testing.T, path string) map[string][]any {
+	names, rowGroups := readParquet(t, path)
+	columns := map[string][]any{}
+	for i, name := range names {
+		for _, rg := range rowGroups {
+			for _, value := range rg[i] {
+				if v, ok := value.(int64); ok {
+					value = float64(v)
+				}
+				columns[name] = append(columns[name], value)
+			}
+		}
+	}
+	return columns
+}
+
+// independentReaderEnv makes TestOutput_IndependentReader fail instead of skipping when no reader is available. It
+// is set in CI, so the files are always checked by a reader which doesn't share the assumptions of the writer.
+const independentReaderEnv = "PANYL_PARQUET_READER_REQUIRED"
+
+// TestOutput_IndependentReader reads testdata/golden.parquet, and files written with each compression and with
+// multiple row groups, with pyarrow or the DuckDB CLI.
+func TestOutput_IndependentReader(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	golden, err := filepath.Abs(filepath.Join("testdata", "golden.parquet"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	if columns, ok := readIndependent(t, golden); ok {
+		assert.Equal(t, goldenColumns, columns)
+	}
+
+	for name, options := range map[string][]Option{
+		"none":       {WithCompression(CompressionNone)},
+		"snappy":     {WithCompression(CompressionSnappy)},
+		"gzip":       {WithCompression(CompressionGzip)},
+		"row-groups": {WithRowGroupSize(2)},
+	} {
+		path := filepath.Join(dir, name+".parquet")
+		o := New(dir, append(options, WithColumns(DefaultColumns[:3]...),
+			WithFileName(func(created time.Time, sequence int) string {
+				return name + ".parquet"
+			}))...)
+		goldenItems(ctx, o)
+
+		if columns, ok := readIndependent(t, path); ok {
+			assert.Equal(t, decodedColumns(t, path), columns, name)
+		}
+	}
+}
+
+// readIndependent reads the values of each column of a file with pyarrow or the DuckDB CLI, with the timestamps in
+// microseconds. If none is available the test is skipped, or fails if independentReaderEnv is set.
+func readIndependent(t *testing.T, path string) (map[string][]any, bool) {
+	var columns map[string][]any
+	if exec.Command("python3", "-c", "import pyarrow").Run() == nil {
+		out, err := exec.Command("python3", "-c", pyarrowReadScript, path).Output()
+		if !assert.NoError(t, err, path) || !assert.NoError(t, json.Unmarshal(out, &columns)) {
+			return nil, false
+		}
+	} else if _, err := exec.LookPath("duckdb"); err == nil {
+		out, err := exec.Command("duckdb", "-json", "-c",
+			fmt.Sprintf("SELECT epoch_us(timestamp) AS timestamp, * EXCLUDE (timestamp) FROM read_parquet('%s')",
+				path)).Output()
+		var rows []map[string]any
+		if !assert.NoError(t, err, path) || !assert.NoError(t, json.Unmarshal(out, &rows)) {
+			return nil, false
+		}
+		columns = map[string][]any{}
+		for _, row := range rows {
+			for name, value := range row {
+				columns[name] = append(columns[name], value)
+			}
+		}
+	} else if os.Getenv(independentReaderEnv) != "" {
+		t.Fatal("no independent Parquet reader available, install pyarrow or the DuckDB CLI")
+	} else {
+		t.Skip("no independent Parquet reader available, install pyarrow or the DuckDB CLI")
+	}
+	return columns, true
+}
+
+const pyarrowReadScript = `
+import json, sys
+import pyarrow.parquet as pq
+
+table = pq.read_table(sys.argv[1])
+columns = {}
+for name in table.column_names:
+    column = table.column(name)
+    if str(column.type).startswith("timestamp"):
+        column = column.cast("int64")
+    columns[name] = column.to_pylist()
+print(json.dumps(columns))
+`
+
+func TestOutput_MaxFileAge(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	o := New(dir, WithRowGroupSize(10), WithMaxFileAge(50*time.Millisecond))
+	o.OnItem(ctx, panyl.InitItem(panyl.WithInitLine("line")))
+	o.OnFlush(ctx)
+	o.OnItem(ctx, panyl.InitItem(panyl.WithInitLine("line")))
+
+	// the buffered item is written and the file closed by the timer, without other calls
+	assert.Eventually(t, func() bool {
+		files, _ := filepath.Glob(filepath.Join(dir, "*.parquet"))
+		tmp, _ := filepath.Glob(filepath.Join(dir, "*.tmp"))
+		return len(files) == 1 && len(tmp) == 0
+	}, time.Second, 10*time.Millisecond)
+	files, _ := filepath.Glob(filepath.Join(dir, "*.parquet"))
+	if _, rowGroups := readParquet(t, files[0]); assert.Len(t, rowGroups, 2) {
+		assert.Len(t, rowGroups[1][0], 1)
+	}
+
+	o.OnItem(ctx, panyl.InitItem(panyl.WithInitLine("line")))
+	o.OnClose(ctx)
+	files, _ = filepath.Glob(filepath.Join(dir, "*.parquet"))
+	assert.Len(t, files, 2)
+}
+
+// readParquet reads the column names and the values of each row group of a file written by Output.
+func readParquet(t *testing.T, path string) ([]string, [][][]any) {
+	data, err := os.ReadFile(path)
+	if !assert.NoError(t, err) {
+		return nil, nil
+	}
+	if !assert.Equal(t, "PAR1", string(data[:4])) || !assert.Equal(t, "PAR1", string(data[len(data)-4:])) {
+		return nil, nil
+	}
+	footerSize := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	footer, _ := readThriftStruct(data[len(data)-8-footerSize:])
+
+	var names []string
+	var types []int64
+	for _, element := range footer[2].([]any)[1:] {
+		e := element.(map[int16]any)
+		names = append(names, e[4].(string))
+		types = append(types, e[1].(int64))
+	}
+
+	var rowGroups [][][]any
+	for _, rg := range footer[4].([]any) {
+		var columns [][]any
+		for i, chunk := range rg.(map[int16]any)[1].([]any) {
+			meta := chunk.(map[int16]any)[3].(map[int16]any)
+			offset := meta[9].(int64)
+			header, size := readThriftStruct(data[offset:])
+			page := data[int(offset)+size : int(offset)+size+int(header[3].(int64))]
+			if meta[4].(int64) == int64(CompressionSnappy) {
+				page, err = snappy.Decode(nil, page)
+				assert.NoError(t, err)
+			}
+			numValues := int(header[5].(map[int16]any)[1].(int64))
+			columns = append(columns, decodePage(page, types[i], numValues))
+		}
+		rowGroups = append(rowGroups, columns)
+	}
+	return names, rowGroups
+}
+
+// decodePage decodes a page written by encodePage.
+func decodePage(page []byte, physicalType int64, numValues int) []any {
+	levelsSize := int(binary.LittleEndian.Uint32(page))
+	levels := page[4 : 4+levelsSize]
+	values := page[4+levelsSize:]
+
+	var defined []bool
+	for len(levels) > 0 {
+		header, n := binary.Uvarint(levels)
+		for i := 0; i < int(header>>1); i++ {
+			defined = append(defined, levels[n] == 1)
+		}
+		levels = levels[n+1:]
+	}
+
+	ret := make([]any, numValues)
+	bit := 0
+	for i := 0; i < numValues; i++ {
+		if !defined[i] {
+			continue
+		}
+		switch physicalType {
+		case typeByteArray:
+			size := int(binary.LittleEndian.Uint32(values))
+			ret[i] = string(values[4 : 4+size])
+			values = values[4+size:]
+		case typeInt64:
+			ret[i] = int64(binary.LittleEndian.Uint64(values))
+			values = values[8:]
+		case typeDouble:
+			ret[i] = math.Float64frombits(binary.LittleEndian.Uint64(values))
+			values = values[8:]
+		case typeBoolean:
+			ret[i] = values[bit/8]&(1<<(bit%8)) != 0
+			bit++
+		}
+	}
+	return ret
+}
+
+// readThriftStruct decodes a thrift compact struct, returning it and its size. Integers are returned as int64,
+// binaries as string, lists as []any and structs as map[int16]any.
+func readThriftStruct(data []byte) (map[int16]any, int) {
+	r := bytes.NewReader(data)
+	ret := readThriftFields(r)
+	return ret, len(data) - r.Len()
+}
+
+func readThriftFields(r *bytes.Reader) map[int16]any {
+	ret := map[int16]any{}
+	var last int16
+	for {
+		b, _ := r.ReadByte()
+		if b == 0 {
+			return ret
+		}
+		fieldType := b & 0x0f
+		if delta := int16(b >> 4); delta != 0 {
+			last += delta
+		} else {
+			id, _ := binary.ReadVarint(r)
+			last = int16(id)
+		}
+		ret[last] = readThriftValue(r, fieldType)
+	}
+}
+
+func readThriftValue(r *bytes.Reader, valueType byte) any {
+	switch valueType {
+	case thriftBoolTrue:
+		return true
+	case thriftBoolFalse:
+		return false
+	case thriftI32, thriftI64:
+		v, _ := binary.ReadVarint(r)
+		return v
+	case thriftBinary:
+		size, _ := binary.ReadUvarint(r)
+		b := make([]byte, size)
+		_, _ = r.Read(b)
+		return string(b)
+	case thriftList:
+		b, _ := r.ReadByte()
+		size := int(b >> 4)
+		if size == 15 {
+			s, _ := binary.ReadUvarint(r)
+			size = int(s)
+		}
+		list := make([]any, size)
+		for i := range list {
+			list[i] = readThriftValue(r, b&0x0f)
+		}
+		return list
+	case thriftStruct:
+		return readThriftFields(r)
+	}
+	panic("unsupported thrift type")
+}
@@ -0,0 +1,47 @@
+package output
+
+import (
+	"context"
+	"sync"
+
+	"github.com/RangelReale/panyl/v2"
+)
+
+// Shared is an Output wrapper allowing an output to be used by many Jobs at the same time, like the ones of a
+// server. The calls to the wrapped output are serialized, and OnClose only flushes it, as each Job closes its output
+// when it finishes. Call Close to close the wrapped output.
+type Shared struct {
+	output panyl.Output
+	m      sync.Mutex
+}
+
+var _ panyl.Output = (*Shared)(nil)
+
+// NewShared creates a Shared wrapping output.
+func NewShared(output panyl.Output) *Shared {
+	return &Shared{output: output}
+}
+
+func (s *Shared) OnItem(ctx context.Context, item *panyl.Item) bool {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return s.output.OnItem(ctx, item)
+}
+
+func (s *Shared) OnFlush(ctx context.Context) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.output.OnFlush(ctx)
+}
+
+// OnClose flushes the wrapped output.
+func (s *Shared) OnClose(ctx context.Context) {
+	s.OnFlush(ctx)
+}
+
+// Close closes the wrapped output.
+func (s *Shared) Close(ctx context.Context) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.output.OnClose(ctx)
+}
@@ -0,0 +1,52 @@
+package output
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/RangelReale/panyl/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShared(t *testing.T) {
+	ctx := context.Background()
+	res := &closeOutputTest{}
+	shared := NewShared(res)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			job := panyl.NewJob(panyl.NewProcessor(), shared)
+			for j := 0; j < 10; j++ {
+				assert.NoError(t, job.ProcessLine(ctx, "line"))
+			}
+			assert.NoError(t, job.Finish(ctx))
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, res.List, 100)
+	// each Job flushes the output, and OnClose also flushes it
+	assert.Equal(t, 20, res.flushed)
+	assert.False(t, res.closed)
+
+	shared.Close(ctx)
+	assert.True(t, res.closed)
+}
+
+type closeOutputTest struct {
+	panyl.OutputArray
+	flushed int
+	closed  bool
+}
+
+func (o *closeOutputTest) OnFlush(ctx context.Context) {
+	o.flushed++
+}
+
+func (o *closeOutputTest) OnClose(ctx context.Context) {
+	o.closed = true
+}
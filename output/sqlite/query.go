@@ -0,0 +1,173 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/RangelReale/panyl/v2"
+)
+
+// Filter selects the items returned by Query. Fields with the zero value are not used.
+type Filter struct {
+	From, To     time.Time // the timestamp range, To is exclusive
+	Levels       []string  // compared case-insensitively
+	Applications []string
+	Contains     string // text contained in the message or the line
+	Where        string // an extra SQL condition, with Args as its parameters
+	Args         []any
+	Limit        int
+	Descending   bool // return the newest items first
+}
+
+// Query returns the items of the table matching the filter, ordered by timestamp and id.
+func Query(ctx context.Context, db *sql.DB, table string, filter Filter) ([]*panyl.Item, error) {
+	var where []string
+	var args []any
+	if !filter.From.IsZero() {
+		where = append(where, "timestamp >= ?")
+		args = append(args, filter.From.UTC().Format(TimestampLayout))
+	}
+	if !filter.To.IsZero() {
+		where = append(where, "timestamp < ?")
+		args = append(args, filter.To.UTC().Format(TimestampLayout))
+	}
+	if len(filter.Levels) > 0 {
+		// levels are stored in lowercase, comparing the column directly allows using the index
+		where = append(where, "level IN ("+placeholders(len(filter.Levels))+")")
+		for _, level := range filter.Levels {
+			args = append(args, strings.ToLower(level))
+		}
+	}
+	if len(filter.Applications) > 0 {
+		where = append(where, "application IN ("+placeholders(len(filter.Applications))+")")
+		for _, application := range filter.Applications {
+			args = append(args, application)
+		}
+	}
+	if filter.Contains != "" {
+		where = append(where, "(instr(message, ?) > 0 OR instr(line, ?) > 0)")
+		args = append(args, filter.Contains, filter.Contains)
+	}
+	if filter.Where != "" {
+		where = append(where, "("+filter.Where+")")
+		args = append(args, filter.Args...)
+	}
+
+	query := "SELECT * FROM " + quoteIdentifier(table)
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	if filter.Descending {
+		query += " ORDER BY timestamp DESC, id DESC"
+	} else {
+		query += " ORDER BY timestamp, id"
+	}
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", filter.Limit)
+	}
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return ScanItems(rows)
+}
+
+// ScanItems converts rows selected from a table created by CreateSchema to items, allowing custom queries. Only
+// the known columns are used, so the query may select a subset of them.
+func ScanItems(rows *sql.Rows) ([]*panyl.Item, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []*panyl.Item
+	for rows.Next() {
+		values := make([]any, len(columns))
+		for i := range values {
+			values[i] = new(any)
+		}
+		if err := rows.Scan(values...); err != nil {
+			return nil, err
+		}
+
+		item := panyl.InitItem()
+		for i, column := range columns {
+			if err := setColumn(item, column, *values[i].(*any)); err != nil {
+				return nil, fmt.Errorf("error reading column %s: %w", column, err)
+			}
+		}
+		ret = append(ret, item)
+	}
+	return ret, rows.Err()
+}
+
+// setColumn sets the value of a column in the item.
+func setColumn(item *panyl.Item, column string, value any) error {
+	if value == nil {
+		return nil
+	}
+	switch column {
+	case "timestamp":
+		ts, err := time.Parse(TimestampLayout, asString(value))
+		if err != nil {
+			return err
+		}
+		item.Metadata[panyl.MetadataTimestamp] = ts
+	case "level":
+		item.Metadata[panyl.MetadataLevel] = asString(value)
+	case "application":
+		item.Metadata[panyl.MetadataApplication] = asString(value)
+	case "format":
+		item.Metadata[panyl.MetadataFormat] = asString(value)
+	case "category":
+		item.Metadata[panyl.MetadataCategory] = asString(value)
+	case "message":
+		item.Metadata[panyl.MetadataMessage] = asString(value)
+	case "line_no":
+		if n, ok := value.(int64); ok {
+			item.LineNo = int(n)
+		}
+	case "line_count":
+		if n, ok := value.(int64); ok {
+			item.LineCount = int(n)
+		}
+	case "line":
+		item.Line = asString(value)
+	case "source":
+		item.Source = asString(value)
+	case "metadata":
+		var metadata map[string]any
+		if err := json.Unmarshal([]byte(asString(value)), &metadata); err != nil {
+			return err
+		}
+		item.Metadata.Merge(metadata)
+	case "data":
+		var data map[string]any
+		if err := json.Unmarshal([]byte(asString(value)), &data); err != nil {
+			return err
+		}
+		item.Data.Merge(data)
+	}
+	return nil
+}
+
+// asString returns a TEXT value, which drivers may return as string or []byte.
+func asString(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	return fmt.Sprint(value)
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
@@ -0,0 +1,261 @@
+// Package sqlite provides an Output storing items in a SQLite database, and helpers to query them back, as a local
+// queryable store of parsed logs.
+//
+// The package uses database/sql and doesn't import a driver, import one like github.com/mattn/go-sqlite3 or
+// modernc.org/sqlite.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RangelReale/panyl/v2"
+)
+
+const (
+	DefaultTable     = "items"
+	DefaultBatchSize = 1000
+)
+
+// TimestampLayout is the layout of the timestamp column, in UTC. It sorts as text, and is understood by the SQLite
+// date and time functions.
+const TimestampLayout = "2006-01-02T15:04:05.000000Z"
+
+// metadataColumns are the Metadata keys stored in their own columns.
+var metadataColumns = map[string]bool{
+	panyl.MetadataTimestamp:   true,
+	panyl.MetadataLevel:       true,
+	panyl.MetadataApplication: true,
+	panyl.MetadataFormat:      true,
+	panyl.MetadataCategory:    true,
+	panyl.MetadataMessage:     true,
+}
+
+// CreateSchema creates the table and its indexes, if they don't exist. The table has these columns:
+//   - id: INTEGER PRIMARY KEY
+//   - timestamp: TEXT, MetadataTimestamp formatted with TimestampLayout
+//   - level: TEXT, the MetadataLevel value in lowercase, indexed with the timestamp
+//   - application, format, category, message: TEXT, the Metadata values
+//   - line_no, line_count: INTEGER, the line range of the item
+//   - line, source: TEXT
+//   - metadata: TEXT, the other Metadata values as JSON
+//   - data: TEXT, the Data as JSON, which can be queried using the SQLite JSON functions
+func CreateSchema(ctx context.Context, db *sql.DB, table string) error {
+	t := quoteIdentifier(table)
+	statements := []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+			id INTEGER PRIMARY KEY,
+			timestamp TEXT,
+			level TEXT,
+			application TEXT,
+			format TEXT,
+			category TEXT,
+			message TEXT,
+			line_no INTEGER,
+			line_count INTEGER,
+			line TEXT,
+			source TEXT,
+			metadata TEXT,
+			data TEXT
+		)`, t),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s ON %s (timestamp)`, quoteIdentifier(table+"_timestamp"), t),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s ON %s (level, timestamp)`, quoteIdentifier(table+"_level"), t),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s ON %s (application, timestamp)`,
+			quoteIdentifier(table+"_application"), t),
+	}
+	for _, statement := range statements {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("error creating schema: %w", err)
+		}
+	}
+	return nil
+}
+
+// Output stores items in a SQLite table, created with CreateSchema on the first write. The items are buffered and
+// inserted in a transaction every DefaultBatchSize items, on OnFlush and on OnClose. The database is not closed
+// by OnClose.
+// Items whose Metadata or Data can't be encoded as JSON are skipped, and the error is reported.
+// Errors are sent to the error callback, or logged to the context slog logger.
+type Output struct {
+	db        *sql.DB
+	table     string
+	batchSize int
+	onError   func(ctx context.Context, err error)
+
+	schemaCreated bool
+	batch         []*panyl.Item
+	m             sync.Mutex
+}
+
+var _ panyl.Output = (*Output)(nil)
+
+// New creates an Output storing items in db.
+func New(db *sql.DB, options ...Option) *Output {
+	ret := &Output{
+		db:        db,
+		table:     DefaultTable,
+		batchSize: DefaultBatchSize,
+	}
+	for _, opt := range options {
+		opt(ret)
+	}
+	return ret
+}
+
+type Option func(*Output)
+
+// WithTable sets the table name, by default DefaultTable.
+func WithTable(table string) Option {
+	return func(o *Output) {
+		o.table = table
+	}
+}
+
+// WithBatchSize sets the amount of items inserted in each transaction.
+func WithBatchSize(batchSize int) Option {
+	return func(o *Output) {
+		o.batchSize = batchSize
+	}
+}
+
+// WithOnError sets a callback for errors storing the items.
+func WithOnError(onError func(ctx context.Context, err error)) Option {
+	return func(o *Output) {
+		o.onError = onError
+	}
+}
+
+func (o *Output) OnItem(ctx context.Context, item *panyl.Item) bool {
+	o.m.Lock()
+	defer o.m.Unlock()
+
+	o.batch = append(o.batch, item)
+	if len(o.batch) >= o.batchSize {
+		o.flush(ctx)
+	}
+	return ctx.Err() == nil
+}
+
+func (o *Output) OnFlush(ctx context.Context) {
+	o.m.Lock()
+	defer o.m.Unlock()
+	o.flush(ctx)
+}
+
+func (o *Output) OnClose(ctx context.Context) {
+	o.OnFlush(ctx)
+}
+
+// flush inserts the batch in a transaction.
+func (o *Output) flush(ctx context.Context) {
+	if len(o.batch) == 0 {
+		return
+	}
+	batch := o.batch
+	o.batch = nil
+
+	if !o.schemaCreated {
+		if err := CreateSchema(ctx, o.db, o.table); err != nil {
+			o.handleError(ctx, err)
+			return
+		}
+		o.schemaCreated = true
+	}
+	if err := o.insert(ctx, batch); err != nil {
+		o.handleError(ctx, fmt.Errorf("error inserting %d items: %w", len(batch), err))
+	}
+}
+
+func (o *Output) insert(ctx context.Context, batch []*panyl.Item) error {
+	tx, err := o.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	stmt, err := tx.PrepareContext(ctx, fmt.Sprintf(`INSERT INTO %s (timestamp, level, application, format, category,
+		message, line_no, line_count, line, source, metadata, data) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		quoteIdentifier(o.table)))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, item := range batch {
+		var timestamp any
+		if ts, ok := item.Metadata[panyl.MetadataTimestamp].(time.Time); ok {
+			timestamp = ts.UTC().Format(TimestampLayout)
+		}
+		metadata := map[string]any{}
+		for k, v := range item.Metadata {
+			if !metadataColumns[k] {
+				metadata[k] = v
+			}
+		}
+		// items which can't be encoded are skipped, so they don't prevent storing the rest of the batch
+		metadataJSON, err := jsonColumn(metadata)
+		if err != nil {
+			o.handleError(ctx, fmt.Errorf("error encoding metadata of line %d: %w", item.LineNo, err))
+			continue
+		}
+		dataJSON, err := jsonColumn(item.Data)
+		if err != nil {
+			o.handleError(ctx, fmt.Errorf("error encoding data of line %d: %w", item.LineNo, err))
+			continue
+		}
+
+		_, err = stmt.ExecContext(ctx, timestamp,
+			nullString(strings.ToLower(item.Metadata.StringValue(panyl.MetadataLevel))),
+			nullString(item.Metadata.StringValue(panyl.MetadataApplication)),
+			nullString(item.Metadata.StringValue(panyl.MetadataFormat)),
+			nullString(item.Metadata.StringValue(panyl.MetadataCategory)),
+			nullString(item.Metadata.StringValue(panyl.MetadataMessage)),
+			item.LineNo, item.LineCount,
+			nullString(item.Line), nullString(item.Source),
+			metadataJSON, dataJSON)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (o *Output) handleError(ctx context.Context, err error) {
+	if o.onError != nil {
+		o.onError(ctx, err)
+		return
+	}
+	panyl.SLogFromContext(ctx).WarnContext(ctx, "error storing items in sqlite", slog.Any("error", err))
+}
+
+// jsonColumn encodes a map as JSON, or returns nil if it is empty.
+func jsonColumn(m map[string]any) (any, error) {
+	if len(m) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func nullString(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// quoteIdentifier quotes a SQL identifier.
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
@@ -0,0 +1,4 @@
+// Package sqlitetest tests the sqlite package using the github.com/mattn/go-sqlite3 driver.
+//
+// It is a separate module, so the main module doesn't depend on the cgo driver. Run the tests from this directory.
+package sqlitetest
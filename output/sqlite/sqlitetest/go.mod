@@ -0,0 +1,17 @@
+module github.com/RangelReale/panyl/v2/output/sqlite/sqlitetest
+
+go 1.23
+
+require (
+	github.com/RangelReale/panyl/v2 v2.0.0
+	github.com/mattn/go-sqlite3 v1.14.33
+	github.com/stretchr/testify v1.7.1
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c // indirect
+)
+
+replace github.com/RangelReale/panyl/v2 => ../../..
@@ -0,0 +1,135 @@
+//go:build cgo
+
+package sqlitetest
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/RangelReale/panyl/v2"
+	"github.com/RangelReale/panyl/v2/output/sqlite"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutput(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "items.db"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+
+	ts := time.Date(2024, 3, 5, 10, 0, 0, 123456000, time.UTC)
+	o := sqlite.New(db, sqlite.WithBatchSize(2))
+	for i, level := range []string{"INFO", "error", "warn"} {
+		item := panyl.InitItem(panyl.WithInitLineNo(i*2+1), panyl.WithInitLineCount(2),
+			panyl.WithInitLine("line"), panyl.WithInitSource("source"))
+		item.Metadata[panyl.MetadataTimestamp] = ts.Add(time.Duration(i) * time.Minute)
+		item.Metadata[panyl.MetadataLevel] = level
+		item.Metadata[panyl.MetadataApplication] = "api"
+		item.Metadata[panyl.MetadataMessage] = "request failed"
+		item.Metadata[panyl.MetadataTraceID] = "abc"
+		item.Data["status"] = 500 + i
+		item.Data["user"] = map[string]any{"name": "ana"}
+		o.OnItem(ctx, item)
+	}
+
+	// the first batch was already inserted
+	items, err := sqlite.Query(ctx, db, sqlite.DefaultTable, sqlite.Filter{})
+	assert.NoError(t, err)
+	assert.Len(t, items, 2)
+
+	o.OnClose(ctx)
+
+	items, err = sqlite.Query(ctx, db, sqlite.DefaultTable, sqlite.Filter{})
+	assert.NoError(t, err)
+	if assert.Len(t, items, 3) {
+		item := items[0]
+		assert.Equal(t, 1, item.LineNo)
+		assert.Equal(t, 2, item.LineCount)
+		assert.Equal(t, "line", item.Line)
+		assert.Equal(t, "source", item.Source)
+		assert.Equal(t, panyl.MapValue{
+			panyl.MetadataTimestamp:   ts,
+			panyl.MetadataLevel:       "info",
+			panyl.MetadataApplication: "api",
+			panyl.MetadataMessage:     "request failed",
+			panyl.MetadataTraceID:     "abc",
+		}, item.Metadata)
+		assert.Equal(t, panyl.MapValue{
+			"status": float64(500),
+			"user":   map[string]any{"name": "ana"},
+		}, item.Data)
+	}
+
+	items, err = sqlite.Query(ctx, db, sqlite.DefaultTable, sqlite.Filter{
+		From:       ts.Add(time.Second),
+		Levels:     []string{"ERROR", "warn"},
+		Contains:   "failed",
+		Where:      "json_extract(data, '$.status') >= ?",
+		Args:       []any{501},
+		Limit:      1,
+		Descending: true,
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, items, 1) {
+		assert.Equal(t, "warn", items[0].Metadata[panyl.MetadataLevel])
+	}
+
+	// the level filter uses the index
+	var detail string
+	var id, parent, notUsed int
+	err = db.QueryRowContext(ctx, "EXPLAIN QUERY PLAN SELECT * FROM items WHERE level IN (?, ?)", "error", "warn").
+		Scan(&id, &parent, &notUsed, &detail)
+	if assert.NoError(t, err) {
+		assert.Contains(t, detail, "items_level")
+	}
+
+	// custom queries
+	rows, err := db.QueryContext(ctx, "SELECT level, data FROM items WHERE application = ? ORDER BY id", "api")
+	if assert.NoError(t, err) {
+		items, err = sqlite.ScanItems(rows)
+		_ = rows.Close()
+		assert.NoError(t, err)
+		if assert.Len(t, items, 3) {
+			assert.Equal(t, "error", items[1].Metadata[panyl.MetadataLevel])
+			assert.Equal(t, float64(501), items[1].Data["status"])
+		}
+	}
+}
+
+func TestOutput_InvalidItem(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "items.db"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+
+	var errs []error
+	o := sqlite.New(db, sqlite.WithOnError(func(ctx context.Context, err error) {
+		errs = append(errs, err)
+	}))
+	for i, value := range []any{1, math.NaN(), make(chan int), 2} {
+		item := panyl.InitItem(panyl.WithInitLineNo(i + 1))
+		item.Data["value"] = value
+		o.OnItem(ctx, item)
+	}
+	o.OnClose(ctx)
+
+	// only the items which can't be encoded are skipped
+	assert.Len(t, errs, 2)
+	items, err := sqlite.Query(ctx, db, sqlite.DefaultTable, sqlite.Filter{})
+	assert.NoError(t, err)
+	if assert.Len(t, items, 2) {
+		assert.Equal(t, 1, items[0].LineNo)
+		assert.Equal(t, 4, items[1].LineNo)
+	}
+}
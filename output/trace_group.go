@@ -0,0 +1,218 @@
+package output
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/RangelReale/panyl/v2"
+)
+
+const DefaultTraceGroupWindow = 30 * time.Second
+
+// TraceBundle is a group of items with the same trace or request id.
+type TraceBundle struct {
+	Key          string // the Metadata key used to group, like panyl.MetadataTraceID
+	ID           string
+	Items        []*panyl.Item
+	Start        time.Time // the first item timestamp
+	End          time.Time // the last item timestamp
+	Applications []string  // the MetadataApplication of the items, in order of appearance
+	Dropped      int       // the amount of items not stored in Items because of the max items limit
+
+	seen time.Time // the latest timestamp seen by the group when the bundle was last updated
+}
+
+// Duration returns the duration between the first and the last item.
+func (b *TraceBundle) Duration() time.Duration {
+	return b.End.Sub(b.Start)
+}
+
+// TraceGroup is an Output wrapper that groups items by their trace id, calling a callback with the bundle of items
+// of each trace after no new items of the trace were seen for the time window. Time is measured using the item
+// MetadataTimestamp, so it works for historical files, and all open bundles are emitted on OnClose. OnFlush doesn't
+// emit open bundles, so when used by many Jobs through Shared, a Job finishing doesn't emit the bundles of the others.
+// All items are also sent unchanged to the wrapped output, if not nil.
+// Use the postprocess.Trace plugin to extract the trace ids.
+type TraceGroup struct {
+	output   panyl.Output
+	onBundle func(ctx context.Context, bundle *TraceBundle)
+	window   time.Duration
+	keys     []string
+	maxItems int
+	maxOpen  int
+
+	open    map[traceGroupKey]*list.Element
+	order   *list.List // of *TraceBundle, ordered by the seen time
+	current time.Time
+	m       sync.Mutex
+}
+
+var _ panyl.Output = (*TraceGroup)(nil)
+
+type traceGroupKey struct {
+	key, id string
+}
+
+// NewTraceGroup creates a TraceGroup wrapping output, which can be nil.
+func NewTraceGroup(output panyl.Output, onBundle func(ctx context.Context, bundle *TraceBundle),
+	options ...TraceGroupOption) *TraceGroup {
+	ret := &TraceGroup{
+		output:   output,
+		onBundle: onBundle,
+		window:   DefaultTraceGroupWindow,
+		keys:     []string{panyl.MetadataTraceID, panyl.MetadataRequestID},
+		open:     map[traceGroupKey]*list.Element{},
+		order:    list.New(),
+	}
+	for _, opt := range options {
+		opt(ret)
+	}
+	return ret
+}
+
+type TraceGroupOption func(*TraceGroup)
+
+// WithTraceGroupWindow sets the time after the last item of a trace when the bundle is emitted.
+func WithTraceGroupWindow(window time.Duration) TraceGroupOption {
+	return func(g *TraceGroup) {
+		g.window = window
+	}
+}
+
+// WithTraceGroupKeys sets the Metadata keys used to group items, the first one present in the item is used.
+// The default is panyl.MetadataTraceID then panyl.MetadataRequestID.
+func WithTraceGroupKeys(keys ...string) TraceGroupOption {
+	return func(g *TraceGroup) {
+		g.keys = keys
+	}
+}
+
+// WithTraceGroupMaxItems limits the amount of items stored in each bundle, the other ones are only counted.
+func WithTraceGroupMaxItems(maxItems int) TraceGroupOption {
+	return func(g *TraceGroup) {
+		g.maxItems = maxItems
+	}
+}
+
+// WithTraceGroupMaxOpen limits the amount of open bundles, the oldest ones are emitted when it is exceeded.
+func WithTraceGroupMaxOpen(maxOpen int) TraceGroupOption {
+	return func(g *TraceGroup) {
+		g.maxOpen = maxOpen
+	}
+}
+
+func (g *TraceGroup) OnItem(ctx context.Context, item *panyl.Item) bool {
+	emit := g.add(item)
+	g.emit(ctx, emit)
+	if g.output != nil {
+		return g.output.OnItem(ctx, item)
+	}
+	return true
+}
+
+func (g *TraceGroup) OnFlush(ctx context.Context) {
+	g.emit(ctx, g.expire(false))
+	if g.output != nil {
+		g.output.OnFlush(ctx)
+	}
+}
+
+func (g *TraceGroup) OnClose(ctx context.Context) {
+	g.emit(ctx, g.expire(true))
+	if g.output != nil {
+		g.output.OnClose(ctx)
+	}
+}
+
+// add adds the item to its bundle, returning the bundles that expired.
+func (g *TraceGroup) add(item *panyl.Item) []*TraceBundle {
+	g.m.Lock()
+	defer g.m.Unlock()
+
+	ts, hasTime := item.Metadata[panyl.MetadataTimestamp].(time.Time)
+	if hasTime && ts.After(g.current) {
+		g.current = ts
+	}
+
+	var key traceGroupKey
+	for _, k := range g.keys {
+		if id := item.Metadata.StringValue(k); id != "" {
+			key = traceGroupKey{key: k, id: id}
+			break
+		}
+	}
+
+	if key.id != "" {
+		var bundle *TraceBundle
+		if e, ok := g.open[key]; ok {
+			bundle = e.Value.(*TraceBundle)
+			g.order.MoveToBack(e)
+		} else {
+			bundle = &TraceBundle{Key: key.key, ID: key.id}
+			g.open[key] = g.order.PushBack(bundle)
+		}
+		bundle.seen = g.current
+
+		if g.maxItems <= 0 || len(bundle.Items) < g.maxItems {
+			bundle.Items = append(bundle.Items, item)
+		} else {
+			bundle.Dropped++
+		}
+		if hasTime {
+			if bundle.Start.IsZero() || ts.Before(bundle.Start) {
+				bundle.Start = ts
+			}
+			if ts.After(bundle.End) {
+				bundle.End = ts
+			}
+		}
+		if app := item.Metadata.StringValue(panyl.MetadataApplication); app != "" {
+			found := false
+			for _, a := range bundle.Applications {
+				if a == app {
+					found = true
+					break
+				}
+			}
+			if !found {
+				bundle.Applications = append(bundle.Applications, app)
+			}
+		}
+	}
+
+	return g.expireLocked(false)
+}
+
+func (g *TraceGroup) expire(all bool) []*TraceBundle {
+	g.m.Lock()
+	defer g.m.Unlock()
+	return g.expireLocked(all)
+}
+
+// expireLocked removes the bundles which weren't updated in the time window, or all of them.
+// Bundles are ordered by the seen time, so it stops on the first one that is still open.
+func (g *TraceGroup) expireLocked(all bool) []*TraceBundle {
+	var ret []*TraceBundle
+	for e := g.order.Front(); e != nil; e = g.order.Front() {
+		bundle := e.Value.(*TraceBundle)
+		if !all && bundle.seen.Add(g.window).After(g.current) &&
+			(g.maxOpen <= 0 || g.order.Len() <= g.maxOpen) {
+			break
+		}
+		g.order.Remove(e)
+		delete(g.open, traceGroupKey{key: bundle.Key, id: bundle.ID})
+		ret = append(ret, bundle)
+	}
+	return ret
+}
+
+func (g *TraceGroup) emit(ctx context.Context, bundles []*TraceBundle) {
+	if g.onBundle == nil {
+		return
+	}
+	for _, bundle := range bundles {
+		g.onBundle(ctx, bundle)
+	}
+}
@@ -0,0 +1,107 @@
+package output
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/RangelReale/panyl/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func traceItem(lineNo int, ts time.Time, application, traceID string) *panyl.Item {
+	item := panyl.InitItem(panyl.WithInitLineNo(lineNo))
+	item.Metadata[panyl.MetadataTimestamp] = ts
+	item.Metadata[panyl.MetadataApplication] = application
+	if traceID != "" {
+		item.Metadata[panyl.MetadataTraceID] = traceID
+	}
+	return item
+}
+
+func TestTraceGroup(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	var bundles []*TraceBundle
+	var output panyl.OutputArray
+	g := NewTraceGroup(&output, func(ctx context.Context, bundle *TraceBundle) {
+		bundles = append(bundles, bundle)
+	}, WithTraceGroupWindow(10*time.Second))
+
+	g.OnItem(ctx, traceItem(1, start, "api", "t1"))
+	g.OnItem(ctx, traceItem(2, start.Add(time.Second), "db", "t2"))
+	g.OnItem(ctx, traceItem(3, start.Add(2*time.Second), "db", "t1"))
+	g.OnItem(ctx, traceItem(4, start.Add(3*time.Second), "api", ""))
+	g.OnItem(ctx, traceItem(5, start.Add(4*time.Second), "api", "t1"))
+	assert.Len(t, bundles, 0)
+
+	// t2 expires 10 seconds after its last item, t1 is still open
+	g.OnItem(ctx, traceItem(6, start.Add(12*time.Second), "api", ""))
+	if assert.Len(t, bundles, 1) {
+		assert.Equal(t, "t2", bundles[0].ID)
+		assert.Equal(t, panyl.MetadataTraceID, bundles[0].Key)
+		assert.Len(t, bundles[0].Items, 1)
+	}
+
+	// open bundles are only emitted on close
+	g.OnFlush(ctx)
+	assert.Len(t, bundles, 1)
+	g.OnClose(ctx)
+	if assert.Len(t, bundles, 2) {
+		b := bundles[1]
+		assert.Equal(t, "t1", b.ID)
+		assert.Len(t, b.Items, 3)
+		assert.Equal(t, 4*time.Second, b.Duration())
+		assert.Equal(t, []string{"api", "db"}, b.Applications)
+	}
+
+	// all items are sent to the wrapped output
+	assert.Len(t, output.List, 6)
+}
+
+func TestTraceGroup_Shared(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	var bundles []*TraceBundle
+	shared := NewShared(NewTraceGroup(nil, func(ctx context.Context, bundle *TraceBundle) {
+		bundles = append(bundles, bundle)
+	}))
+
+	// a job finishing doesn't emit the bundles still open of the other jobs
+	shared.OnItem(ctx, traceItem(1, start, "api", "t1"))
+	shared.OnItem(ctx, traceItem(1, start, "db", "t2"))
+	shared.OnFlush(ctx)
+	shared.OnClose(ctx)
+	assert.Len(t, bundles, 0)
+
+	shared.Close(ctx)
+	assert.Len(t, bundles, 2)
+}
+
+func TestTraceGroup_Limits(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	var bundles []*TraceBundle
+	g := NewTraceGroup(nil, func(ctx context.Context, bundle *TraceBundle) {
+		bundles = append(bundles, bundle)
+	}, WithTraceGroupMaxItems(2), WithTraceGroupMaxOpen(1))
+
+	g.OnItem(ctx, traceItem(1, start, "api", "t1"))
+	g.OnItem(ctx, traceItem(2, start, "api", "t1"))
+	g.OnItem(ctx, traceItem(3, start, "api", "t1"))
+	assert.Len(t, bundles, 0)
+
+	// opening a second bundle emits the oldest one
+	g.OnItem(ctx, traceItem(4, start, "api", "t2"))
+	if assert.Len(t, bundles, 1) {
+		assert.Equal(t, "t1", bundles[0].ID)
+		assert.Len(t, bundles[0].Items, 2)
+		assert.Equal(t, 1, bundles[0].Dropped)
+	}
+
+	g.OnClose(ctx)
+	assert.Len(t, bundles, 2)
+}
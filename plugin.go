@@ -25,6 +25,7 @@ type PluginMetadata interface {
 // PluginStructure allows extracting structure from a line, for example, JSON or XML.
 // The full text must be a complete structure, partial match should not be supported.
 // You should take in account the lines Metdatada/Data and apply them to the item at your convenience.
+// The Job reuses the lines merged into the item, so don't keep references to them or to their Metadata/Data maps.
 type PluginStructure interface {
 	Plugin
 	ExtractStructure(ctx context.Context, lines ItemLines, item *Item) (bool, error)
@@ -33,11 +34,42 @@ type PluginStructure interface {
 // PluginParse allows parsing data from a line, for example, an Apache log format, a Ruby log format, etc.
 // The full text must be completely parsed, partial match should not be supported.
 // You should take in account the lines Metadata/Data and apply them to the item at your convenience.
+// The Job reuses the lines merged into the item, so don't keep references to them or to their Metadata/Data maps.
 type PluginParse interface {
 	Plugin
 	ExtractParse(ctx context.Context, lines ItemLines, item *Item) (bool, error)
 }
 
+// PluginBoundary is an optional interface for PluginStructure and PluginParse plugins to tell whether a line can be
+// the first or the last line of the data they extract. The Job only calls the plugin with lists of lines that
+// satisfy both checks, avoiding calling it for every suffix of the backlog.
+// Both functions are called frequently so they must be cheap, like checking the first or last character of the line.
+type PluginBoundary interface {
+	CanStart(ctx context.Context, item *Item) bool
+	CanEnd(ctx context.Context, item *Item) bool
+}
+
+// PluginIncomplete is an optional interface for PluginStructure plugins to tell whether a line starts a structure
+// which is not complete yet, like a JSON object which was not closed. It is only used when MaxIncompleteBacklogLines
+// is set, and not in reverse mode.
+// While the structure is incomplete its lines are reserved for it: they are not checked by other plugins, so inner
+// structures like the objects of a JSON array don't match before the outer structure is complete, and when the
+// backlog exceeds MaxBacklogLines they are kept in the backlog up to MaxIncompleteBacklogLines.
+// The reservation is dropped as soon as the IncompleteScanner reports a line which completes the structure or can't
+// be part of it. If the lines don't match as a structure, the lines after the first one are processed again.
+type PluginIncomplete interface {
+	// StartIncomplete returns an IncompleteScanner if the line starts a structure which is not complete yet, or
+	// nil otherwise.
+	StartIncomplete(ctx context.Context, item *Item) IncompleteScanner
+}
+
+// IncompleteScanner checks the lines following the start of an incomplete structure, one line at a time.
+type IncompleteScanner interface {
+	// ScanLine returns whether the structure is still incomplete after the line. It returns false if the line
+	// completes the structure or can't be part of it, and is not called again after that.
+	ScanLine(ctx context.Context, item *Item) bool
+}
+
 // PluginSequence allows checking if 2 processes breaks a sequence, for example, if they belong to different
 // applications, given it is possible to detect this.
 type PluginSequence interface {
@@ -51,6 +83,8 @@ type PluginSequence interface {
 // The topLines result states how many lines were processed, and they will be removed from future calls.
 // The plugin can be called multiple times for the same set of lines, so don't try to detect more if you
 // find a line that don't match, you will be called again after the unmatched line.
+// The Job reuses the top lines after a successful call, so don't keep references to them or to their Metadata/Data
+// maps.
 type PluginConsolidate interface {
 	Plugin
 	Consolidate(ctx context.Context, lines ItemLines, item *Item) (_ bool, topLines int, _ error)
@@ -74,6 +108,12 @@ type PluginCreate interface {
 	CreateAfter(ctx context.Context, item *Item) ([]*Item, error)
 }
 
+// PluginCreateFinish is an optional interface for PluginCreate plugins to create items when the Job finishes, after
+// the lines left in the backlog were output, like a summary of the last items.
+type PluginCreateFinish interface {
+	CreateFinish(ctx context.Context) ([]*Item, error)
+}
+
 // PluginPostProcess is called right before the data is returned to the user, so it allows to do any final
 // post-processing on the data.
 // Order determines in which order post process plugins execute, lower execute first than higher.
@@ -84,3 +124,11 @@ type PluginPostProcess interface {
 	PostProcessOrder() int
 	PostProcess(ctx context.Context, item *Item) (bool, error)
 }
+
+// PluginConcurrencySafe can be implemented by PluginClean and PluginMetadata plugins to declare whether they are
+// safe to be called concurrently from multiple goroutines. When using WithConcurrency, the safe plugins are called
+// on a worker pool, until the first plugin that is not safe, which is called in order with all the plugins after it.
+// Plugins that don't implement this interface are considered unsafe.
+type PluginConcurrencySafe interface {
+	IsConcurrencySafe() bool
+}
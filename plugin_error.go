@@ -0,0 +1,123 @@
+package panyl
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// PluginPhase is the processing phase where a plugin was called.
+type PluginPhase string
+
+const (
+	PluginPhaseClean       PluginPhase = "clean"
+	PluginPhaseMetadata    PluginPhase = "metadata"
+	PluginPhaseStructure   PluginPhase = "structure"
+	PluginPhaseParse       PluginPhase = "parse"
+	PluginPhaseSequence    PluginPhase = "sequence"
+	PluginPhaseConsolidate PluginPhase = "consolidate"
+	PluginPhaseParseFormat PluginPhase = "parse_format"
+	PluginPhasePostProcess PluginPhase = "post_process"
+	PluginPhaseCreate      PluginPhase = "create"
+)
+
+// ErrorPolicy determines what a Job does when a plugin returns an error.
+type ErrorPolicy int
+
+const (
+	// ErrorPolicyFailFast aborts the Job returning the plugin error as a *PluginError. This is the default.
+	ErrorPolicyFailFast ErrorPolicy = iota
+	// ErrorPolicySkipPlugin ignores the failing plugin for the current line and continues processing.
+	// Plugin panics are recovered.
+	ErrorPolicySkipPlugin
+	// ErrorPolicyMetadata works like ErrorPolicySkipPlugin, but also adds the error message to the
+	// MetadataErrors list of the item being processed. Only the first error of each plugin and phase is added to
+	// an item, as the structure and parse plugins are called once for each backlog candidate.
+	ErrorPolicyMetadata
+)
+
+// PluginError is an error returned or a panic raised by a plugin.
+type PluginError struct {
+	Plugin Plugin
+	Phase  PluginPhase
+	LineNo int
+	Err    error
+}
+
+func (e *PluginError) Error() string {
+	return fmt.Sprintf("%s at line %d: %s", e.source(), e.LineNo, e.Err)
+}
+
+// source returns the start of the error message, identifying the plugin and the phase.
+func (e *PluginError) source() string {
+	return fmt.Sprintf("plugin %T error in phase '%s'", e.Plugin, e.Phase)
+}
+
+func (e *PluginError) Unwrap() error {
+	return e.Err
+}
+
+// callPlugin calls a plugin function applying the Job ErrorPolicy. If skip is returned as true, the
+// plugin failed and its result must be ignored.
+func (p *Job) callPlugin(ctx context.Context, plugin Plugin, phase PluginPhase, item *Item,
+	f func() error) (skip bool, err error) {
+	if p.ErrorPolicy != ErrorPolicyFailFast {
+		defer func() {
+			if r := recover(); r != nil {
+				skip, err = p.handlePluginError(ctx, plugin, phase, item, fmt.Errorf("plugin panic: %v", r))
+			}
+		}()
+	}
+	if err := f(); err != nil {
+		return p.handlePluginError(ctx, plugin, phase, item, err)
+	}
+	return false, nil
+}
+
+func (p *Job) handlePluginError(ctx context.Context, plugin Plugin, phase PluginPhase, item *Item,
+	err error) (bool, error) {
+	perr := &PluginError{
+		Plugin: plugin,
+		Phase:  phase,
+		Err:    err,
+	}
+	if item != nil {
+		perr.LineNo = item.LineNo
+	} else {
+		perr.LineNo = p.lineno
+	}
+
+	if p.OnPluginError != nil {
+		p.OnPluginError(ctx, perr)
+	}
+
+	switch p.ErrorPolicy {
+	case ErrorPolicySkipPlugin:
+	case ErrorPolicyMetadata:
+		if item != nil {
+			addItemPluginError(item, perr.source(), perr.Error())
+		}
+	default:
+		return false, perr
+	}
+
+	SLogFromContext(ctx).WarnContext(ctx, "plugin error ignored",
+		slog.String("plugin", pluginTypeName(plugin)),
+		slog.String("phase", string(phase)),
+		slog.Int("line", perr.LineNo),
+		slog.Any("error", err))
+
+	return true, nil
+}
+
+// addItemPluginError adds an error message to the MetadataErrors of the item, if it doesn't have an error from the
+// same source yet.
+func addItemPluginError(item *Item, source string, message string) {
+	for _, e := range item.Metadata.ListValue(MetadataErrors) {
+		if strings.HasPrefix(e, source+" ") {
+			return
+		}
+	}
+	item.Metadata.ListValueAdd(MetadataErrors, message)
+}
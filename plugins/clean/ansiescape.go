@@ -13,6 +13,7 @@ type AnsiEscape struct {
 }
 
 var _ panyl.PluginClean = AnsiEscape{}
+var _ panyl.PluginConcurrencySafe = AnsiEscape{}
 
 func (m AnsiEscape) Clean(ctx context.Context, item *panyl.Item) (bool, error) {
 	if ok, cl := util.AnsiEscapeString(item.Line); ok {
@@ -23,4 +24,8 @@ func (m AnsiEscape) Clean(ctx context.Context, item *panyl.Item) (bool, error) {
 	return false, nil
 }
 
+func (m AnsiEscape) IsConcurrencySafe() bool {
+	return true
+}
+
 func (m AnsiEscape) IsPanylPlugin() {}
@@ -14,6 +14,7 @@ type ForceApplication struct {
 
 var _ panyl.PluginMetadata = ForceApplication{}
 var _ panyl.PluginSequence = ForceApplication{}
+var _ panyl.PluginConcurrencySafe = ForceApplication{}
 
 func (m ForceApplication) ExtractMetadata(ctx context.Context, item *panyl.Item) (bool, error) {
 	if _, ok := item.Metadata[panyl.MetadataApplication]; !ok {
@@ -27,4 +28,8 @@ func (m ForceApplication) BlockSequence(ctx context.Context, lastp, item *panyl.
 	return lastp.Metadata.StringValue(panyl.MetadataApplication) != item.Metadata.StringValue(panyl.MetadataApplication)
 }
 
+func (m ForceApplication) IsConcurrencySafe() bool {
+	return true
+}
+
 func (m ForceApplication) IsPanylPlugin() {}
@@ -0,0 +1,189 @@
+package postprocess
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/RangelReale/panyl/v2"
+	"github.com/RangelReale/panyl/v2/util"
+)
+
+// Pattern sets MetadataPattern with the template of the item message, with its variable tokens masked by
+// util.MessageTemplate, and MetadataPatternID with a stable id of the template.
+// It also counts the items of each pattern by level, use TopPatterns to get a summary of the kinds of messages
+// found by all the jobs, or JobFinished to get the summary of each job. Skipped items are not counted.
+// The message is read from MetadataMessage, or from the Line if not set. Items without a message are ignored.
+type Pattern struct {
+	MaxPatterns int // maximum amount of patterns to count, 0 means no limit
+
+	patterns  map[string]*PatternStat
+	jobs      map[*panyl.Job]map[string]*PatternStat // counts of each job, only kept if JobFinished is used
+	trackJobs bool
+	m         sync.Mutex
+}
+
+var _ panyl.PluginPostProcess = (*Pattern)(nil)
+
+// PatternStat is the count of items of a pattern.
+type PatternStat struct {
+	ID      string
+	Pattern string
+	Example string         // the message of the first item of the pattern
+	Count   int            // the amount of items
+	Levels  map[string]int // the amount of items by lowercase MetadataLevel, items without a level are counted as ""
+}
+
+func NewPattern() *Pattern {
+	return &Pattern{
+		patterns: map[string]*PatternStat{},
+	}
+}
+
+func (m *Pattern) PostProcessOrder() int {
+	return panyl.PostProcessOrderDefault
+}
+
+func (m *Pattern) PostProcess(ctx context.Context, item *panyl.Item) (bool, error) {
+	message := item.Metadata.StringValue(panyl.MetadataMessage)
+	if message == "" {
+		message = item.Line
+	}
+	if message == "" {
+		return false, nil
+	}
+
+	pattern := util.MessageTemplate(message)
+	id := util.TemplateID(pattern)
+	item.Metadata[panyl.MetadataPattern] = pattern
+	item.Metadata[panyl.MetadataPatternID] = id
+
+	if !item.Metadata.BoolValue(panyl.MetadataSkip) {
+		m.count(ctx, panyl.JobFromContext(ctx), id, pattern, message,
+			strings.ToLower(item.Metadata.StringValue(panyl.MetadataLevel)))
+	}
+	return true, nil
+}
+
+func (m *Pattern) count(ctx context.Context, job *panyl.Job, id, pattern, message, level string) {
+	m.m.Lock()
+	defer m.m.Unlock()
+
+	if m.patterns == nil {
+		m.patterns = map[string]*PatternStat{}
+	}
+	m.countPattern(m.patterns, id, pattern, message, level)
+
+	if m.trackJobs && job != nil {
+		patterns, ok := m.jobs[job]
+		if !ok {
+			patterns = map[string]*PatternStat{}
+			m.jobs[job] = patterns
+			// the job context is cancelled when the job ends, so the counts of jobs which failed before the
+			// JobFinished callback are also removed
+			context.AfterFunc(ctx, func() {
+				m.m.Lock()
+				defer m.m.Unlock()
+				delete(m.jobs, job)
+			})
+		}
+		m.countPattern(patterns, id, pattern, message, level)
+	}
+}
+
+func (m *Pattern) countPattern(patterns map[string]*PatternStat, id, pattern, message, level string) {
+	stat, ok := patterns[id]
+	if !ok {
+		if m.MaxPatterns > 0 && len(patterns) >= m.MaxPatterns {
+			return
+		}
+		stat = &PatternStat{
+			ID:      id,
+			Pattern: pattern,
+			Example: message,
+			Levels:  map[string]int{},
+		}
+		patterns[id] = stat
+	}
+	stat.Count++
+	stat.Levels[level]++
+}
+
+// TopPatterns returns the limit patterns with the most items, or all if limit is 0. If level is not empty, only
+// the items of this level are considered, ignoring case.
+func (m *Pattern) TopPatterns(limit int, level string) []PatternStat {
+	m.m.Lock()
+	defer m.m.Unlock()
+	return topPatterns(m.patterns, limit, level)
+}
+
+func topPatterns(patterns map[string]*PatternStat, limit int, level string) []PatternStat {
+	level = strings.ToLower(level)
+	count := func(stat *PatternStat) int {
+		if level == "" {
+			return stat.Count
+		}
+		return stat.Levels[level]
+	}
+
+	var ret []PatternStat
+	for _, stat := range patterns {
+		if count(stat) == 0 {
+			continue
+		}
+		s := *stat
+		s.Levels = make(map[string]int, len(stat.Levels))
+		for l, c := range stat.Levels {
+			s.Levels[l] = c
+		}
+		ret = append(ret, s)
+	}
+
+	slices.SortFunc(ret, func(a, b PatternStat) int {
+		if ca, cb := count(&a), count(&b); ca != cb {
+			return cb - ca
+		}
+		if a.Pattern < b.Pattern {
+			return -1
+		} else if a.Pattern > b.Pattern {
+			return 1
+		}
+		return 0
+	})
+	if limit > 0 && len(ret) > limit {
+		ret = ret[:limit]
+	}
+	return ret
+}
+
+// Reset clears the pattern counts of all the jobs, used by TopPatterns.
+func (m *Pattern) Reset() {
+	m.m.Lock()
+	defer m.m.Unlock()
+	m.patterns = map[string]*PatternStat{}
+}
+
+// JobFinished returns a function to be used with panyl.WithOnJobFinished, calling f with the TopPatterns of the
+// items of the job. The counts are kept separately for each job, so concurrent jobs don't mix, and are removed when
+// the job finishes. The counts used by TopPatterns are not changed.
+// The job is read from the context using panyl.JobFromContext.
+func (m *Pattern) JobFinished(limit int, level string,
+	f func(ctx context.Context, job *panyl.Job, patterns []PatternStat) error) func(context.Context, *panyl.Job) error {
+	m.m.Lock()
+	m.trackJobs = true
+	if m.jobs == nil {
+		m.jobs = map[*panyl.Job]map[string]*PatternStat{}
+	}
+	m.m.Unlock()
+
+	return func(ctx context.Context, job *panyl.Job) error {
+		m.m.Lock()
+		patterns := topPatterns(m.jobs[job], limit, level)
+		delete(m.jobs, job)
+		m.m.Unlock()
+		return f(ctx, job, patterns)
+	}
+}
+
+func (m *Pattern) IsPanylPlugin() {}
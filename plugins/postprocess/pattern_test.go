@@ -0,0 +1,175 @@
+package postprocess
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RangelReale/panyl/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPattern(t *testing.T) {
+	ctx := context.Background()
+	plugin := NewPattern()
+
+	for _, test := range []struct {
+		message, level string
+	}{
+		{`user 10 logged in from 10.0.0.1`, "info"},
+		{`user 22 logged in from 192.168.0.20`, "info"},
+		{`connection 550e8400-e29b-41d4-a716-446655440000 failed: "timeout"`, "error"},
+		{`connection 6ba7b810-9dad-11d1-80b4-00c04fd430c8 failed: "refused"`, "error"},
+		{`connection 6ba7b811-9dad-11d1-80b4-00c04fd430c8 failed: "refused"`, "warn"},
+		{`connection 6ba7b812-9dad-11d1-80b4-00c04fd430c8 failed: "reset"`, "ERROR"},
+		{`user 5 logged in from 10.0.0.2`, "info"},
+		{`user 10 logged in from 10.0.0.1`, "info"},
+		{`user 7 logged in from 10.0.0.3`, "INFO"},
+	} {
+		item := panyl.InitItem()
+		item.Metadata[panyl.MetadataMessage] = test.message
+		item.Metadata[panyl.MetadataLevel] = test.level
+		_, err := plugin.PostProcess(ctx, item)
+		assert.NoError(t, err)
+	}
+
+	top := plugin.TopPatterns(0, "")
+	if assert.Len(t, top, 2) {
+		assert.Equal(t, "user <NUM> logged in from <IP>", top[0].Pattern)
+		assert.Equal(t, 5, top[0].Count)
+		// levels are counted ignoring case
+		assert.Equal(t, map[string]int{"info": 5}, top[0].Levels)
+		assert.Equal(t, `user 10 logged in from 10.0.0.1`, top[0].Example)
+		assert.Equal(t, "connection <UUID> failed: <STR>", top[1].Pattern)
+		assert.Equal(t, map[string]int{"error": 3, "warn": 1}, top[1].Levels)
+	}
+
+	top = plugin.TopPatterns(1, "Error")
+	if assert.Len(t, top, 1) {
+		assert.Equal(t, "connection <UUID> failed: <STR>", top[0].Pattern)
+	}
+}
+
+func TestPattern_Quotes(t *testing.T) {
+	ctx := context.Background()
+	plugin := NewPattern()
+
+	for _, test := range []struct {
+		message, pattern string
+	}{
+		{`user 'bob' not found`, `user <STR> not found`},
+		{`user 'it\'s me' not found`, `user <STR> not found`},
+		// apostrophes are not quotes
+		{`can't connect to the database, don't retry`, `can't connect to the database, don't retry`},
+		{`can't open the file, don't retry`, `can't open the file, don't retry`},
+		{`the users' names are 'a1' and 'b2'`, `the users' names are <STR> and <STR>`},
+	} {
+		item := panyl.InitItem()
+		item.Metadata[panyl.MetadataMessage] = test.message
+		_, err := plugin.PostProcess(ctx, item)
+		assert.NoError(t, err)
+		assert.Equal(t, test.pattern, item.Metadata.StringValue(panyl.MetadataPattern), test.message)
+	}
+}
+
+func TestPattern_JobFinished(t *testing.T) {
+	ctx := context.Background()
+	plugin := NewPattern()
+
+	var patterns []PatternStat
+	p := panyl.NewProcessor(panyl.WithPlugins(plugin),
+		panyl.WithOnJobFinished(plugin.JobFinished(10, "", func(ctx context.Context, job *panyl.Job, p []PatternStat) error {
+			patterns = p
+			return nil
+		})))
+
+	res := &panyl.OutputArray{}
+	err := p.Process(ctx, strings.NewReader("request took 10ms\nrequest took 250ms\nstarting"), res)
+	assert.NoError(t, err)
+
+	if assert.Len(t, res.List, 3) {
+		assert.Equal(t, "request took <*>", res.List[0].Metadata.StringValue(panyl.MetadataPattern))
+		assert.Equal(t, res.List[0].Metadata.StringValue(panyl.MetadataPatternID),
+			res.List[1].Metadata.StringValue(panyl.MetadataPatternID))
+	}
+	if assert.Len(t, patterns, 2) {
+		assert.Equal(t, 2, patterns[0].Count)
+		assert.Equal(t, "starting", patterns[1].Pattern)
+	}
+	// the counts of all the jobs are kept
+	assert.Len(t, plugin.TopPatterns(0, ""), 2)
+}
+
+func TestPattern_JobFailed(t *testing.T) {
+	ctx := context.Background()
+	plugin := NewPattern()
+
+	called := false
+	p := panyl.NewProcessor(panyl.WithPlugins(plugin, errorMetadataPlugin{}),
+		panyl.WithOnJobFinished(plugin.JobFinished(10, "", func(ctx context.Context, job *panyl.Job, p []PatternStat) error {
+			called = true
+			return nil
+		})))
+
+	err := p.Process(ctx, strings.NewReader("line 1\nline 2\nfail\n"), &panyl.OutputArray{})
+	assert.ErrorIs(t, err, errTest)
+	assert.False(t, called)
+
+	// the counts of the job are removed even without calling the callback
+	assert.Eventually(t, func() bool {
+		plugin.m.Lock()
+		defer plugin.m.Unlock()
+		return len(plugin.jobs) == 0
+	}, time.Second, time.Millisecond)
+}
+
+var errTest = errors.New("test error")
+
+// errorMetadataPlugin fails on lines equal to "fail".
+type errorMetadataPlugin struct{}
+
+func (e errorMetadataPlugin) IsPanylPlugin() {}
+
+func (e errorMetadataPlugin) ExtractMetadata(ctx context.Context, item *panyl.Item) (bool, error) {
+	if item.Line == "fail" {
+		return false, errTest
+	}
+	return false, nil
+}
+
+func TestPattern_JobFinishedConcurrent(t *testing.T) {
+	ctx := context.Background()
+	plugin := NewPattern()
+
+	var m sync.Mutex
+	patterns := map[string][]PatternStat{}
+	p := panyl.NewProcessor(panyl.WithPlugins(plugin),
+		panyl.WithOnJobFinished(plugin.JobFinished(0, "", func(ctx context.Context, job *panyl.Job, p []PatternStat) error {
+			m.Lock()
+			defer m.Unlock()
+			patterns[p[0].Example] = p
+			return nil
+		})))
+
+	var wg sync.WaitGroup
+	for _, message := range []string{"request took 10ms", "user 1 logged in"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data := strings.Repeat(message+"\n", 100)
+			assert.NoError(t, p.Process(ctx, strings.NewReader(data), &panyl.OutputArray{}))
+		}()
+	}
+	wg.Wait()
+
+	// each job only has its own patterns
+	for _, message := range []string{"request took 10ms", "user 1 logged in"} {
+		if assert.Len(t, patterns[message], 1) {
+			assert.Equal(t, 100, patterns[message][0].Count)
+		}
+	}
+	assert.Len(t, plugin.TopPatterns(0, ""), 2)
+}
@@ -0,0 +1,311 @@
+package postprocess
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/RangelReale/panyl/v2"
+	"github.com/RangelReale/panyl/v2/util"
+)
+
+type SampleMode int
+
+const (
+	SampleModeRate           SampleMode = iota // keep a fraction of the items
+	SampleModeFirstPerSecond                   // keep the first items of each second
+	SampleModeTokenBucket                      // keep items while there are tokens, which are refilled at a rate
+)
+
+// Sample drops items by setting MetadataSkip, to reduce the volume of noisy sources. Only items with a level up to
+// MaxLevel are dropped, by default MetadataLevelDEBUG, items with an unknown level are considered as
+// MetadataLevelINFO. Created items are never dropped.
+// The sampling state is kept for each value returned by Key, like SampleKeyApplication or SampleKeyPattern, or for
+// all items if it is nil.
+// Time is measured using the item MetadataTimestamp, so it works for historical files. Items without a timestamp use
+// the last one found.
+// The state of keys not seen for KeyTimeout, by default DefaultSampleKeyTimeout, is removed, so a key seen again is
+// sampled as a new key. In SampleModeTokenBucket the state is kept at least until the bucket would be full again.
+// If SummaryInterval is set, it also creates an item with MetadataSampleDropped before the next kept item, at most
+// once per interval, reporting the amount of items dropped by the Job since the last report. The items dropped since
+// the last report are also reported when the Job finishes.
+type Sample struct {
+	Mode            SampleMode
+	Rate            float64 // SampleModeRate: fraction of the items to keep. SampleModeTokenBucket: tokens per second
+	Limit           int     // SampleModeFirstPerSecond: items per second. SampleModeTokenBucket: the bucket size
+	MaxLevel        string
+	Key             func(item *panyl.Item) string
+	KeyTimeout      time.Duration
+	SummaryInterval time.Duration
+
+	keys      map[string]*sampleState
+	lastSweep time.Time // last time the idle keys were removed
+	lastTime  time.Time
+	dropped   int // total of dropped items
+	jobs      map[*panyl.Job]*sampleJob
+	m         sync.Mutex
+}
+
+// DefaultSampleKeyTimeout is the default Sample.KeyTimeout.
+const DefaultSampleKeyTimeout = 5 * time.Minute
+
+var _ panyl.PluginPostProcess = (*Sample)(nil)
+var _ panyl.PluginCreate = (*Sample)(nil)
+var _ panyl.PluginCreateFinish = (*Sample)(nil)
+
+type sampleState struct {
+	count  int       // SampleModeRate: items seen. SampleModeFirstPerSecond: items in the second
+	second time.Time // SampleModeFirstPerSecond: the current second
+	tokens float64   // SampleModeTokenBucket: available tokens
+	last   time.Time // SampleModeTokenBucket: time of the last refill
+	seen   time.Time // time of the last item
+}
+
+// sampleJob is the summary state of a Job.
+type sampleJob struct {
+	pending     int // dropped items since the last summary
+	lastSummary time.Time
+}
+
+// NewSampleRate creates a Sample keeping the rate fraction of the items, between 0 and 1. The selection is
+// deterministic, for a rate of 0.1 the first item and every 10th item after it are kept.
+func NewSampleRate(rate float64) *Sample {
+	return &Sample{
+		Mode: SampleModeRate,
+		Rate: rate,
+	}
+}
+
+// NewSampleFirstPerSecond creates a Sample keeping the first limit items of each second.
+func NewSampleFirstPerSecond(limit int) *Sample {
+	return &Sample{
+		Mode:  SampleModeFirstPerSecond,
+		Limit: limit,
+	}
+}
+
+// NewSampleTokenBucket creates a Sample keeping items while there are tokens in a bucket of size burst, which is
+// refilled with rate tokens per second.
+func NewSampleTokenBucket(rate float64, burst int) *Sample {
+	return &Sample{
+		Mode:  SampleModeTokenBucket,
+		Rate:  rate,
+		Limit: burst,
+	}
+}
+
+// SampleKeyApplication samples each MetadataApplication separately.
+func SampleKeyApplication(item *panyl.Item) string {
+	return item.Metadata.StringValue(panyl.MetadataApplication)
+}
+
+// SampleKeyPattern samples each message pattern separately, using MetadataPatternID if set by the Pattern plugin.
+func SampleKeyPattern(item *panyl.Item) string {
+	if id := item.Metadata.StringValue(panyl.MetadataPatternID); id != "" {
+		return id
+	}
+	message := item.Metadata.StringValue(panyl.MetadataMessage)
+	if message == "" {
+		message = item.Line
+	}
+	return util.TemplateID(util.MessageTemplate(message))
+}
+
+func (m *Sample) PostProcessOrder() int {
+	// run last so the other plugins can set the level and the pattern
+	return panyl.PostProcessOrderLast
+}
+
+func (m *Sample) PostProcess(ctx context.Context, item *panyl.Item) (bool, error) {
+	if item.Metadata.BoolValue(panyl.MetadataCreated) || item.Metadata.BoolValue(panyl.MetadataSkip) {
+		return false, nil
+	}
+
+	maxLevel := panyl.LevelOrder(m.MaxLevel)
+	if maxLevel == panyl.LevelOrderUnknown {
+		maxLevel = panyl.LevelOrderDebug
+	}
+	level := panyl.LevelOrder(item.Metadata.StringValue(panyl.MetadataLevel))
+	if level == panyl.LevelOrderUnknown {
+		level = panyl.LevelOrderInfo
+	}
+
+	key := ""
+	if m.Key != nil {
+		key = m.Key(item)
+	}
+
+	m.m.Lock()
+	defer m.m.Unlock()
+
+	ts, ok := item.Metadata[panyl.MetadataTimestamp].(time.Time)
+	if ok {
+		m.lastTime = ts
+	} else {
+		ts = m.lastTime
+	}
+
+	if level > maxLevel || m.keep(key, ts) {
+		return false, nil
+	}
+	item.Metadata[panyl.MetadataSkip] = true
+	m.dropped++
+	if m.SummaryInterval > 0 {
+		m.job(ctx).pending++
+	}
+	return true, nil
+}
+
+// job returns the summary state of the Job of the context.
+func (m *Sample) job(ctx context.Context) *sampleJob {
+	job := panyl.JobFromContext(ctx)
+	if m.jobs == nil {
+		m.jobs = map[*panyl.Job]*sampleJob{}
+	}
+	state, ok := m.jobs[job]
+	if !ok {
+		state = &sampleJob{}
+		m.jobs[job] = state
+		if job != nil {
+			// the job context is cancelled when the job ends
+			context.AfterFunc(ctx, func() {
+				m.m.Lock()
+				defer m.m.Unlock()
+				delete(m.jobs, job)
+			})
+		}
+	}
+	return state
+}
+
+// keep returns whether the item should be kept, updating the state of the key.
+func (m *Sample) keep(key string, ts time.Time) bool {
+	if m.keys == nil {
+		m.keys = map[string]*sampleState{}
+	}
+	m.sweep(ts)
+	state, ok := m.keys[key]
+	if !ok {
+		state = &sampleState{
+			tokens: float64(m.Limit),
+			last:   ts,
+		}
+		m.keys[key] = state
+	}
+	if ts.After(state.seen) {
+		state.seen = ts
+	}
+
+	switch m.Mode {
+	case SampleModeRate:
+		// keep when the integer part of the expected amount of kept items changes
+		keep := math.Floor(float64(state.count)*m.Rate) != math.Floor(float64(state.count-1)*m.Rate)
+		state.count++
+		return keep
+	case SampleModeFirstPerSecond:
+		second := ts.Truncate(time.Second)
+		if !second.Equal(state.second) {
+			state.second = second
+			state.count = 0
+		}
+		state.count++
+		return state.count <= m.Limit
+	case SampleModeTokenBucket:
+		if elapsed := ts.Sub(state.last); elapsed > 0 {
+			state.tokens = math.Min(float64(m.Limit), state.tokens+elapsed.Seconds()*m.Rate)
+			state.last = ts
+		}
+		if state.tokens >= 1 {
+			state.tokens--
+			return true
+		}
+		return false
+	}
+	return true
+}
+
+// sweep removes the state of the keys which were not seen for the key timeout, at most once per timeout.
+func (m *Sample) sweep(ts time.Time) {
+	timeout := m.KeyTimeout
+	if timeout <= 0 {
+		timeout = DefaultSampleKeyTimeout
+	}
+	if m.Mode == SampleModeTokenBucket && m.Rate > 0 {
+		// keep the state until the bucket is full again, so removing it doesn't change the result
+		timeout = max(timeout, time.Duration(float64(m.Limit)/m.Rate*float64(time.Second)))
+	}
+	if ts.Sub(m.lastSweep) < timeout {
+		return
+	}
+	m.lastSweep = ts
+	for key, state := range m.keys {
+		if ts.Sub(state.seen) >= timeout {
+			delete(m.keys, key)
+		}
+	}
+}
+
+// Dropped returns the total amount of dropped items.
+func (m *Sample) Dropped() int {
+	m.m.Lock()
+	defer m.m.Unlock()
+	return m.dropped
+}
+
+func (m *Sample) CreateBefore(ctx context.Context, item *panyl.Item) ([]*panyl.Item, error) {
+	if m.SummaryInterval <= 0 || item.Metadata.BoolValue(panyl.MetadataCreated) {
+		return nil, nil
+	}
+
+	m.m.Lock()
+	defer m.m.Unlock()
+
+	state := m.job(ctx)
+	if state.pending == 0 {
+		return nil, nil
+	}
+	ts, ok := item.Metadata[panyl.MetadataTimestamp].(time.Time)
+	if !ok {
+		ts = m.lastTime
+	}
+	if !state.lastSummary.IsZero() && ts.Sub(state.lastSummary) < m.SummaryInterval {
+		return nil, nil
+	}
+	return []*panyl.Item{m.summary(state, item.LineNo, ts)}, nil
+}
+
+func (m *Sample) CreateAfter(ctx context.Context, item *panyl.Item) ([]*panyl.Item, error) {
+	return nil, nil
+}
+
+// CreateFinish reports the items dropped by the Job since the last report.
+func (m *Sample) CreateFinish(ctx context.Context) ([]*panyl.Item, error) {
+	if m.SummaryInterval <= 0 {
+		return nil, nil
+	}
+
+	m.m.Lock()
+	defer m.m.Unlock()
+
+	state := m.job(ctx)
+	if state.pending == 0 {
+		return nil, nil
+	}
+	return []*panyl.Item{m.summary(state, 0, m.lastTime)}, nil
+}
+
+// summary creates the item reporting the pending dropped items of the Job.
+func (m *Sample) summary(state *sampleJob, lineNo int, ts time.Time) *panyl.Item {
+	message := fmt.Sprintf("%d items dropped by sampling", state.pending)
+	summary := panyl.InitItem(panyl.WithInitLineNo(lineNo), panyl.WithInitLine(message))
+	summary.Metadata[panyl.MetadataMessage] = message
+	summary.Metadata[panyl.MetadataSampleDropped] = state.pending
+	summary.Metadata[panyl.MetadataTimestamp] = ts
+	state.pending = 0
+	state.lastSummary = ts
+	return summary
+}
+
+func (m *Sample) IsPanylPlugin() {}
@@ -0,0 +1,155 @@
+package postprocess
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RangelReale/panyl/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func sampleItems(t *testing.T, plugin *Sample, start time.Time, offsets []time.Duration, level string) []bool {
+	var kept []bool
+	for _, offset := range offsets {
+		item := panyl.InitItem()
+		item.Metadata[panyl.MetadataTimestamp] = start.Add(offset)
+		item.Metadata[panyl.MetadataLevel] = level
+		_, err := plugin.PostProcess(context.Background(), item)
+		assert.NoError(t, err)
+		kept = append(kept, !item.Metadata.BoolValue(panyl.MetadataSkip))
+	}
+	return kept
+}
+
+func TestSample(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	ms := time.Millisecond
+
+	t.Run("rate", func(t *testing.T) {
+		plugin := NewSampleRate(0.25)
+		kept := sampleItems(t, plugin, start, make([]time.Duration, 9), panyl.MetadataLevelDEBUG)
+		assert.Equal(t, []bool{true, false, false, false, true, false, false, false, true}, kept)
+		assert.Equal(t, 6, plugin.Dropped())
+	})
+
+	t.Run("first per second", func(t *testing.T) {
+		plugin := NewSampleFirstPerSecond(2)
+		kept := sampleItems(t, plugin, start, []time.Duration{0, 100 * ms, 200 * ms, 900 * ms, 1000 * ms, 1100 * ms},
+			panyl.MetadataLevelDEBUG)
+		assert.Equal(t, []bool{true, true, false, false, true, true}, kept)
+	})
+
+	t.Run("token bucket", func(t *testing.T) {
+		plugin := NewSampleTokenBucket(2, 2)
+		kept := sampleItems(t, plugin, start, []time.Duration{0, 0, 0, 250 * ms, 500 * ms, 500 * ms, 2000 * ms,
+			2000 * ms, 2000 * ms}, panyl.MetadataLevelTRACE)
+		assert.Equal(t, []bool{true, true, false, false, true, false, true, true, false}, kept)
+	})
+
+	t.Run("max level", func(t *testing.T) {
+		plugin := NewSampleRate(0)
+		assert.Equal(t, []bool{false}, sampleItems(t, plugin, start, []time.Duration{0}, panyl.MetadataLevelDEBUG))
+		assert.Equal(t, []bool{true}, sampleItems(t, plugin, start, []time.Duration{0}, panyl.MetadataLevelINFO))
+		assert.Equal(t, []bool{true}, sampleItems(t, plugin, start, []time.Duration{0}, ""))
+
+		plugin.MaxLevel = panyl.MetadataLevelWARNING
+		assert.Equal(t, []bool{false}, sampleItems(t, plugin, start, []time.Duration{0}, "warning"))
+		assert.Equal(t, []bool{true}, sampleItems(t, plugin, start, []time.Duration{0}, panyl.MetadataLevelERROR))
+	})
+
+	t.Run("key", func(t *testing.T) {
+		plugin := NewSampleFirstPerSecond(1)
+		plugin.Key = SampleKeyPattern
+		var kept []bool
+		for _, message := range []string{"user 1 logged in", "user 2 logged in", "disk full"} {
+			item := panyl.InitItem()
+			item.Metadata[panyl.MetadataTimestamp] = start
+			item.Metadata[panyl.MetadataLevel] = panyl.MetadataLevelDEBUG
+			item.Metadata[panyl.MetadataMessage] = message
+			_, err := plugin.PostProcess(context.Background(), item)
+			assert.NoError(t, err)
+			kept = append(kept, !item.Metadata.BoolValue(panyl.MetadataSkip))
+		}
+		assert.Equal(t, []bool{true, false, true}, kept)
+	})
+}
+
+func TestSample_Summary(t *testing.T) {
+	ctx := context.Background()
+
+	plugin := NewSampleRate(0.5)
+	plugin.MaxLevel = panyl.MetadataLevelINFO
+	plugin.SummaryInterval = time.Minute
+
+	p := panyl.NewProcessor(panyl.WithPlugins(plugin, &lineTimestampPluginTest{}))
+	res := &panyl.OutputArray{}
+	err := p.Process(ctx, strings.NewReader("1\n2\n3\n4\n5\n70\n80\n90\n100\n"), res)
+	assert.NoError(t, err)
+
+	var lines []string
+	for _, item := range res.List {
+		lines = append(lines, item.Line)
+	}
+	// the items dropped after the last report are reported when the job finishes
+	assert.Equal(t, []string{"1", "1 items dropped by sampling", "3", "5", "2 items dropped by sampling", "80",
+		"100", "1 items dropped by sampling"}, lines)
+	assert.Equal(t, 2, res.List[4].Metadata.IntValue(panyl.MetadataSampleDropped))
+	assert.True(t, res.List[4].Metadata.BoolValue(panyl.MetadataCreated))
+	assert.True(t, res.List[7].Metadata.BoolValue(panyl.MetadataCreated))
+	assert.Equal(t, 4, plugin.Dropped())
+	// the state of the job is removed when it ends
+	assert.Eventually(t, func() bool {
+		plugin.m.Lock()
+		defer plugin.m.Unlock()
+		return len(plugin.jobs) == 0
+	}, time.Second, time.Millisecond)
+}
+
+func TestSample_KeyTimeout(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	plugin := NewSampleRate(0.5)
+	plugin.Key = func(item *panyl.Item) string {
+		return item.Line
+	}
+	plugin.KeyTimeout = time.Minute
+	keys := func(offset time.Duration, keys ...string) int {
+		for _, key := range keys {
+			item := panyl.InitItem(panyl.WithInitLine(key))
+			item.Metadata[panyl.MetadataTimestamp] = start.Add(offset)
+			item.Metadata[panyl.MetadataLevel] = panyl.MetadataLevelDEBUG
+			_, err := plugin.PostProcess(context.Background(), item)
+			assert.NoError(t, err)
+		}
+		return len(plugin.keys)
+	}
+
+	assert.Equal(t, 3, keys(0, "a", "b", "c"))
+	assert.Equal(t, 3, keys(30*time.Second, "a"))
+	// b and c were not seen for the timeout
+	assert.Equal(t, 2, keys(70*time.Second, "d"))
+	assert.Equal(t, 1, keys(10*time.Minute, "e"))
+}
+
+// lineTimestampPluginTest parses each line as an item with the line number as the amount of seconds of the timestamp.
+type lineTimestampPluginTest struct {
+}
+
+func (p *lineTimestampPluginTest) ExtractParse(ctx context.Context, lines panyl.ItemLines, item *panyl.Item) (bool, error) {
+	if len(lines) != 1 {
+		return false, nil
+	}
+	seconds, err := strconv.Atoi(lines[0].Line)
+	if err != nil {
+		return false, nil
+	}
+	item.Line = lines[0].Line
+	item.Metadata[panyl.MetadataTimestamp] = time.Date(2024, 1, 1, 10, 0, seconds, 0, time.UTC)
+	item.Metadata[panyl.MetadataLevel] = panyl.MetadataLevelDEBUG
+	return true, nil
+}
+
+func (p *lineTimestampPluginTest) IsPanylPlugin() {}
@@ -0,0 +1,286 @@
+package postprocess
+
+import (
+	"context"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/RangelReale/panyl/v2"
+)
+
+// Default Data keys used by Trace. Keys are compared ignoring case and the "_", "-" and "." separators, so
+// "trace_id" also matches "traceId", "TraceID" and "trace.id".
+var (
+	DefaultTraceParentKeys  = []string{"traceparent"}
+	DefaultTraceB3Keys      = []string{"b3"}
+	DefaultTraceIDKeys      = []string{"trace_id", "x-b3-traceid"}
+	DefaultSpanIDKeys       = []string{"span_id", "x-b3-spanid"}
+	DefaultParentSpanIDKeys = []string{"parent_span_id", "parent_id", "x-b3-parentspanid"}
+	DefaultRequestIDKeys    = []string{"request_id", "x-request-id", "req_id", "correlation_id", "x-correlation-id"}
+)
+
+// Trace extracts trace and request correlation ids into MetadataTraceID, MetadataSpanID, MetadataParentSpanID and
+// MetadataRequestID. The ids are read from W3C traceparent and B3 headers, or from separate keys, found in the item
+// Data, including nested maps like {"trace": {"id": "..."}} or {"headers": {"traceparent": "..."}}.
+// If SearchMessage is true, "key=value" pairs and bare traceparent headers in the message are also checked.
+// Trace and span ids are normalized to lowercase hex, with 64-bit trace ids padded to 128 bits.
+// Metadata values that are already set are not changed.
+type Trace struct {
+	TraceParentKeys  []string // DefaultTraceParentKeys if nil
+	B3Keys           []string // DefaultTraceB3Keys if nil
+	TraceIDKeys      []string // DefaultTraceIDKeys if nil
+	SpanIDKeys       []string // DefaultSpanIDKeys if nil
+	ParentSpanIDKeys []string // DefaultParentSpanIDKeys if nil
+	RequestIDKeys    []string // DefaultRequestIDKeys if nil
+	SearchMessage    bool     // also search MetadataMessage, or the Line if not set
+}
+
+var _ panyl.PluginPostProcess = Trace{}
+
+// traceDataMaxDepth is the maximum depth of nested maps searched for keys.
+const traceDataMaxDepth = 3
+
+var (
+	traceParentRegex = regexp.MustCompile(`\b[0-9a-fA-F]{2}-[0-9a-fA-F]{32}-[0-9a-fA-F]{16}-[0-9a-fA-F]{2}\b`)
+	traceKeyValueRe  = regexp.MustCompile(`([A-Za-z][\w.-]*)["']?\s*[=:]\s*["']?([\w.-]+)`)
+)
+
+func (m Trace) PostProcessOrder() int {
+	// run first so other post process plugins can use the ids
+	return panyl.PostProcessOrderFirst
+}
+
+func (m Trace) PostProcess(ctx context.Context, item *panyl.Item) (bool, error) {
+	ids := traceIDs{}
+	m.fromData(&ids, item.Data, "", 0)
+	if m.SearchMessage {
+		m.fromMessage(&ids, item)
+	}
+
+	changed := false
+	for _, v := range []struct {
+		name, value string
+	}{
+		{panyl.MetadataTraceID, ids.traceID},
+		{panyl.MetadataSpanID, ids.spanID},
+		{panyl.MetadataParentSpanID, ids.parentSpanID},
+		{panyl.MetadataRequestID, ids.requestID},
+	} {
+		if v.value != "" && !item.Metadata.HasValue(v.name) {
+			item.Metadata[v.name] = v.value
+			changed = true
+		}
+	}
+	return changed, nil
+}
+
+// traceIDs stores the ids found, the first found value for each one is kept.
+type traceIDs struct {
+	traceID, spanID, parentSpanID, requestID string
+}
+
+func (t *traceIDs) complete() bool {
+	return t.traceID != "" && t.spanID != "" && t.parentSpanID != "" && t.requestID != ""
+}
+
+func (t *traceIDs) set(field *string, value string) {
+	if *field == "" {
+		*field = value
+	}
+}
+
+// setValue sets the id of the kind of key, returning false if the key is not known.
+func (m Trace) setValue(ids *traceIDs, key string, value string) bool {
+	switch {
+	case traceKeyIn(key, m.TraceParentKeys, DefaultTraceParentKeys):
+		if traceID, spanID, ok := ParseTraceParent(value); ok {
+			ids.set(&ids.traceID, traceID)
+			ids.set(&ids.spanID, spanID)
+		}
+	case traceKeyIn(key, m.B3Keys, DefaultTraceB3Keys):
+		if traceID, spanID, parentSpanID, ok := ParseB3(value); ok {
+			ids.set(&ids.traceID, traceID)
+			ids.set(&ids.spanID, spanID)
+			ids.set(&ids.parentSpanID, parentSpanID)
+		}
+	case traceKeyIn(key, m.TraceIDKeys, DefaultTraceIDKeys):
+		if id, ok := normalizeTraceHex(value, 32); ok {
+			ids.set(&ids.traceID, id)
+		}
+	case traceKeyIn(key, m.SpanIDKeys, DefaultSpanIDKeys):
+		if id, ok := normalizeTraceHex(value, 16); ok {
+			ids.set(&ids.spanID, id)
+		}
+	case traceKeyIn(key, m.ParentSpanIDKeys, DefaultParentSpanIDKeys):
+		if id, ok := normalizeTraceHex(value, 16); ok {
+			ids.set(&ids.parentSpanID, id)
+		}
+	case traceKeyIn(key, m.RequestIDKeys, DefaultRequestIDKeys):
+		if value = strings.TrimSpace(value); value != "" {
+			ids.set(&ids.requestID, value)
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+func (m Trace) fromData(ids *traceIDs, data map[string]any, prefix string, depth int) {
+	// sort the keys so the first value found is always the same, and check the keys of this level before the
+	// nested ones
+	keys := slices.Sorted(maps.Keys(data))
+	for _, key := range keys {
+		if s, ok := data[key].(string); ok {
+			if !m.setValue(ids, key, s) && prefix != "" {
+				m.setValue(ids, prefix+"."+key, s)
+			}
+		}
+	}
+	if ids.complete() || depth >= traceDataMaxDepth {
+		return
+	}
+	for _, key := range keys {
+		if nested, ok := data[key].(map[string]any); ok {
+			m.fromData(ids, nested, key, depth+1)
+		}
+	}
+}
+
+func (m Trace) fromMessage(ids *traceIDs, item *panyl.Item) {
+	message := item.Metadata.StringValue(panyl.MetadataMessage)
+	if message == "" {
+		message = item.Line
+	}
+	if message == "" {
+		return
+	}
+	for _, kv := range traceKeyValueRe.FindAllStringSubmatch(message, -1) {
+		m.setValue(ids, kv[1], kv[2])
+	}
+	if ids.traceID == "" {
+		if tp := traceParentRegex.FindString(message); tp != "" {
+			if traceID, spanID, ok := ParseTraceParent(tp); ok {
+				ids.set(&ids.traceID, traceID)
+				ids.set(&ids.spanID, spanID)
+			}
+		}
+	}
+}
+
+func (m Trace) IsPanylPlugin() {}
+
+// ParseTraceParent parses a W3C trace-context traceparent header, like
+// "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", returning the trace id and the parent id, which is
+// the span id of the caller.
+func ParseTraceParent(value string) (traceID, spanID string, ok bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return "", "", false
+	}
+	// version ff is invalid, and version 00 must have exactly 4 fields
+	if !isTraceHex(parts[0]) || strings.EqualFold(parts[0], "ff") ||
+		(parts[0] == "00" && len(parts) != 4) {
+		return "", "", false
+	}
+	if traceID, ok = normalizeTraceHex(parts[1], 32); !ok {
+		return "", "", false
+	}
+	if spanID, ok = normalizeTraceHex(parts[2], 16); !ok {
+		return "", "", false
+	}
+	return traceID, spanID, true
+}
+
+// ParseB3 parses a B3 single header, like "80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-1-05e3ac9a4f6e3b90".
+// The parent span id is optional. Headers containing only the sampling state are not valid.
+func ParseB3(value string) (traceID, spanID, parentSpanID string, ok bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 2 || len(parts) > 4 {
+		return "", "", "", false
+	}
+	if len(parts[0]) != 16 && len(parts[0]) != 32 {
+		return "", "", "", false
+	}
+	if traceID, ok = normalizeTraceHex(parts[0], 32); !ok {
+		return "", "", "", false
+	}
+	if len(parts[1]) != 16 {
+		return "", "", "", false
+	}
+	if spanID, ok = normalizeTraceHex(parts[1], 16); !ok {
+		return "", "", "", false
+	}
+	if len(parts) == 4 {
+		if parentSpanID, ok = normalizeTraceHex(parts[3], 16); !ok {
+			return "", "", "", false
+		}
+	}
+	return traceID, spanID, parentSpanID, true
+}
+
+// normalizeTraceHex returns the lowercase hex id left-padded with zeros to size. Ids that are not hex, are larger
+// than size or are all zeros are invalid.
+func normalizeTraceHex(value string, size int) (string, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" || len(value) > size || !isTraceHex(value) || strings.Trim(value, "0") == "" {
+		return "", false
+	}
+	return strings.Repeat("0", size-len(value)) + strings.ToLower(value), true
+}
+
+func isTraceHex(value string) bool {
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+// traceKeyIn returns whether key is in keys, or in defaultKeys if keys is nil.
+func traceKeyIn(key string, keys []string, defaultKeys []string) bool {
+	if keys == nil {
+		keys = defaultKeys
+	}
+	for _, k := range keys {
+		if traceKeyEqual(key, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// traceKeyEqual compares keys ignoring ASCII case and the "_", "-" and "." separators.
+func traceKeyEqual(a, b string) bool {
+	i, j := 0, 0
+	for {
+		for i < len(a) && isTraceKeySeparator(a[i]) {
+			i++
+		}
+		for j < len(b) && isTraceKeySeparator(b[j]) {
+			j++
+		}
+		if i == len(a) || j == len(b) {
+			return i == len(a) && j == len(b)
+		}
+		if lowerASCII(a[i]) != lowerASCII(b[j]) {
+			return false
+		}
+		i++
+		j++
+	}
+}
+
+func isTraceKeySeparator(c byte) bool {
+	return c == '_' || c == '-' || c == '.'
+}
+
+func lowerASCII(c byte) byte {
+	if c >= 'A' && c <= 'Z' {
+		return c + ('a' - 'A')
+	}
+	return c
+}
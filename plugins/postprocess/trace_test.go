@@ -0,0 +1,98 @@
+package postprocess
+
+import (
+	"context"
+	"testing"
+
+	"github.com/RangelReale/panyl/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrace(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		plugin   Trace
+		data     map[string]any
+		line     string
+		expected map[string]any
+	}{
+		{
+			name: "traceparent",
+			data: map[string]any{"traceparent": "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01"},
+			expected: map[string]any{
+				panyl.MetadataTraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+				panyl.MetadataSpanID:  "00f067aa0ba902b7",
+			},
+		},
+		{
+			name: "b3 single header",
+			data: map[string]any{"headers": map[string]any{"b3": "a3ce929d0e0e4736-e457b5a2e4d86bd1-1-05e3ac9a4f6e3b90"}},
+			expected: map[string]any{
+				panyl.MetadataTraceID:      "0000000000000000a3ce929d0e0e4736",
+				panyl.MetadataSpanID:       "e457b5a2e4d86bd1",
+				panyl.MetadataParentSpanID: "05e3ac9a4f6e3b90",
+			},
+		},
+		{
+			name: "keys",
+			data: map[string]any{
+				"traceId":      "4bf92f3577b34da6a3ce929d0e0e4736",
+				"X-B3-SpanId":  "e457b5a2e4d86bd1",
+				"x-request-id": "req-123",
+			},
+			expected: map[string]any{
+				panyl.MetadataTraceID:   "4bf92f3577b34da6a3ce929d0e0e4736",
+				panyl.MetadataSpanID:    "e457b5a2e4d86bd1",
+				panyl.MetadataRequestID: "req-123",
+			},
+		},
+		{
+			name: "nested ECS",
+			data: map[string]any{"trace": map[string]any{"id": "4bf92f3577b34da6a3ce929d0e0e4736"}},
+			expected: map[string]any{
+				panyl.MetadataTraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+			},
+		},
+		{
+			name:     "invalid",
+			data:     map[string]any{"trace_id": "00000000000000000000000000000000", "span_id": "not-hex"},
+			expected: map[string]any{},
+		},
+		{
+			name:   "message",
+			plugin: Trace{SearchMessage: true},
+			line:   "GET /api traceparent=00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01 request_id=\"abc\"",
+			expected: map[string]any{
+				panyl.MetadataTraceID:   "4bf92f3577b34da6a3ce929d0e0e4736",
+				panyl.MetadataSpanID:    "00f067aa0ba902b7",
+				panyl.MetadataRequestID: "abc",
+			},
+		},
+		{
+			name:     "message disabled",
+			line:     "request_id=abc",
+			expected: map[string]any{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			item := panyl.InitItem(panyl.WithInitLine(test.line))
+			item.Data.Merge(test.data)
+			_, err := test.plugin.PostProcess(ctx, item)
+			assert.NoError(t, err)
+			assert.Equal(t, panyl.MapValue(test.expected), item.Metadata)
+		})
+	}
+}
+
+func TestTrace_KeepExisting(t *testing.T) {
+	item := panyl.InitItem()
+	item.Metadata[panyl.MetadataRequestID] = "existing"
+	item.Data["request_id"] = "new"
+	_, err := Trace{}.PostProcess(context.Background(), item)
+	assert.NoError(t, err)
+	assert.Equal(t, "existing", item.Metadata.StringValue(panyl.MetadataRequestID))
+}
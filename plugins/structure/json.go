@@ -3,11 +3,9 @@ package structure
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 	"strings"
 
 	"github.com/RangelReale/panyl/v2"
-	"github.com/imdario/mergo"
 )
 
 // JSON extracts JSON data from the entire line.
@@ -16,13 +14,19 @@ type JSON struct {
 }
 
 var _ panyl.PluginStructure = JSON{}
+var _ panyl.PluginBoundary = JSON{}
+var _ panyl.PluginIncomplete = JSON{}
 
 func (m JSON) ExtractStructure(ctx context.Context, lines panyl.ItemLines, item *panyl.Item) (bool, error) {
-	jdec := json.NewDecoder(strings.NewReader(lines.Line()))
+	// a JSON object must start and end with braces, check it before joining the lines
+	if len(lines) == 0 || !m.CanStart(ctx, lines[0]) || !m.CanEnd(ctx, lines[len(lines)-1]) {
+		return false, nil
+	}
+
 	jdata := map[string]interface{}{}
-	err := jdec.Decode(&jdata)
+	err := json.Unmarshal([]byte(lines.Line()), &jdata)
 	// check if the entire string was used
-	if err != nil || jdec.More() {
+	if err != nil {
 		return false, nil
 	}
 
@@ -34,9 +38,11 @@ func (m JSON) ExtractStructure(ctx context.Context, lines panyl.ItemLines, item
 	// clean the line as it was used entirely
 	item.Line = ""
 
-	// copy the parsed data to the item
-	if err := mergo.Map(&item.Data, jdata); err != nil {
-		return false, fmt.Errorf("Error merging structs: %v", err)
+	// copy the parsed data to the item, using the decoded map directly if there is nothing to merge
+	if len(item.Data) == 0 {
+		item.Data = jdata
+	} else {
+		item.Data.Merge(jdata)
 	}
 
 	item.Metadata[panyl.MetadataStructure] = panyl.MetadataStructureJSON
@@ -44,4 +50,28 @@ func (m JSON) ExtractStructure(ctx context.Context, lines panyl.ItemLines, item
 	return true, nil
 }
 
+// CanStart checks whether the line can be the start of a JSON object.
+func (m JSON) CanStart(ctx context.Context, item *panyl.Item) bool {
+	return strings.HasPrefix(strings.TrimSpace(item.Line), "{")
+}
+
+// CanEnd checks whether the line can be the end of a JSON object.
+func (m JSON) CanEnd(ctx context.Context, item *panyl.Item) bool {
+	return strings.HasSuffix(strings.TrimSpace(item.Line), "}")
+}
+
+// StartIncomplete checks whether the line is the beginning of a JSON object which was not closed yet. The returned
+// scanner validates the JSON syntax of the following lines, so the reservation is dropped as soon as a line is not
+// valid JSON or the object is closed.
+func (m JSON) StartIncomplete(ctx context.Context, item *panyl.Item) panyl.IncompleteScanner {
+	if !m.CanStart(ctx, item) {
+		return nil
+	}
+	s := &jsonIncompleteScanner{}
+	if !s.ScanLine(ctx, item) {
+		return nil
+	}
+	return s
+}
+
 func (m JSON) IsPanylPlugin() {}
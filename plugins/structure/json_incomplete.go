@@ -0,0 +1,150 @@
+package structure
+
+import (
+	"context"
+	"strings"
+
+	"github.com/RangelReale/panyl/v2"
+)
+
+// jsonExpect is the next JSON token expected by jsonIncompleteScanner.
+type jsonExpect int
+
+const (
+	jsonExpectValue        jsonExpect = iota
+	jsonExpectValueOrClose            // after "["
+	jsonExpectKeyOrClose              // after "{"
+	jsonExpectKey                     // after "," in an object
+	jsonExpectColon
+	jsonExpectCommaOrClose
+	jsonExpectNothing // the top-level value was closed
+)
+
+// jsonIncompleteScanner incrementally validates the syntax of a JSON value spanning multiple lines, scanning each
+// line only once.
+type jsonIncompleteScanner struct {
+	stack  []byte // open objects and arrays
+	expect jsonExpect
+}
+
+var _ panyl.IncompleteScanner = (*jsonIncompleteScanner)(nil)
+
+// ScanLine returns whether the JSON value is still incomplete and valid after the line.
+func (s *jsonIncompleteScanner) ScanLine(ctx context.Context, item *panyl.Item) bool {
+	line := item.Line
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		if c == ' ' || c == '\t' || c == '\r' {
+			continue
+		}
+		switch s.expect {
+		case jsonExpectValue, jsonExpectValueOrClose:
+			if c == ']' && s.expect == jsonExpectValueOrClose {
+				s.close()
+				continue
+			}
+			n := s.scanValue(line[i:])
+			if n <= 0 {
+				return false
+			}
+			i += n - 1
+		case jsonExpectKeyOrClose, jsonExpectKey:
+			if c == '}' && s.expect == jsonExpectKeyOrClose {
+				s.close()
+				continue
+			}
+			n := scanJSONString(line[i:])
+			if n <= 0 {
+				return false
+			}
+			i += n - 1
+			s.expect = jsonExpectColon
+		case jsonExpectColon:
+			if c != ':' {
+				return false
+			}
+			s.expect = jsonExpectValue
+		case jsonExpectCommaOrClose:
+			top := s.stack[len(s.stack)-1]
+			switch {
+			case c == ',' && top == '{':
+				s.expect = jsonExpectKey
+			case c == ',':
+				s.expect = jsonExpectValue
+			case c == '}' && top == '{', c == ']' && top == '[':
+				s.close()
+			default:
+				return false
+			}
+		default:
+			// data after the top-level value
+			return false
+		}
+	}
+	return s.expect != jsonExpectNothing
+}
+
+// scanValue scans the value at the start of data, returning the amount of bytes used or 0 if it is invalid.
+// Objects and arrays are only opened.
+func (s *jsonIncompleteScanner) scanValue(data string) int {
+	var n int
+	switch c := data[0]; {
+	case c == '{':
+		s.stack = append(s.stack, c)
+		s.expect = jsonExpectKeyOrClose
+		return 1
+	case c == '[':
+		s.stack = append(s.stack, c)
+		s.expect = jsonExpectValueOrClose
+		return 1
+	case c == '"':
+		n = scanJSONString(data)
+	case c == '-' || (c >= '0' && c <= '9'):
+		n = 1
+		for n < len(data) && strings.IndexByte("0123456789.eE+-", data[n]) >= 0 {
+			n++
+		}
+	default:
+		for _, literal := range []string{"true", "false", "null"} {
+			if len(data) >= len(literal) && data[:len(literal)] == literal {
+				n = len(literal)
+				break
+			}
+		}
+	}
+	if n > 0 {
+		s.valueDone()
+	}
+	return n
+}
+
+// close closes the innermost object or array.
+func (s *jsonIncompleteScanner) close() {
+	s.stack = s.stack[:len(s.stack)-1]
+	s.valueDone()
+}
+
+func (s *jsonIncompleteScanner) valueDone() {
+	if len(s.stack) == 0 {
+		s.expect = jsonExpectNothing
+	} else {
+		s.expect = jsonExpectCommaOrClose
+	}
+}
+
+// scanJSONString returns the length of the JSON string at the start of data, or 0 if it isn't closed. JSON strings
+// can't span multiple lines.
+func scanJSONString(data string) int {
+	if len(data) == 0 || data[0] != '"' {
+		return 0
+	}
+	for i := 1; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return 0
+}
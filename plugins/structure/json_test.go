@@ -0,0 +1,88 @@
+package structure
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/RangelReale/panyl/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSON_IncompleteBacklog(t *testing.T) {
+	ctx := context.Background()
+
+	var valid strings.Builder
+	for i := 0; i < 10; i++ {
+		_, _ = fmt.Fprintf(&valid, "{\"a\":%d}\n", i)
+	}
+
+	for _, tt := range []struct {
+		name           string
+		data           string
+		maxIncomplete  int
+		expectedItems  int
+		expectedStruct int
+	}{
+		{"stray brace disabled", "{truncated message\n" + valid.String(), 0, 11, 10},
+		{"stray brace", "{truncated message\n" + valid.String(), 200, 11, 10},
+		{"valid prefix", "{\"msg\": \"x\", \"a\":\n" + valid.String(), 200, 11, 10},
+		{"multiline", "{\n\"a\": [\n{\"b\": 1},\n{\"b\": 2}\n]\n}\ntext\n", 200, 2, 1},
+		{"never closed", "{\"msg\":\n{\n\"a\": {\"b\": 1}\n}\n", 200, 2, 1},
+		{"invalid line", "{\n\"a\": 1,\ntext\n{\"b\": 2}\n", 200, 4, 1},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			p := panyl.NewProcessor(panyl.WithPlugins(&JSON{}))
+
+			res := &panyl.OutputArray{}
+			err := p.Process(ctx, strings.NewReader(tt.data), res,
+				panyl.WithMaxBacklogLines(3),
+				panyl.WithMaxIncompleteBacklogLines(tt.maxIncomplete))
+			assert.NoError(t, err)
+
+			structured := 0
+			for _, item := range res.List {
+				if item.Metadata[panyl.MetadataStructure] == panyl.MetadataStructureJSON {
+					structured++
+				}
+			}
+			assert.Len(t, res.List, tt.expectedItems)
+			assert.Equal(t, tt.expectedStruct, structured)
+		})
+	}
+}
+
+func TestJSON_StartIncomplete(t *testing.T) {
+	ctx := context.Background()
+
+	for _, tt := range []struct {
+		lines    []string
+		expected []bool
+	}{
+		{[]string{`{"a": 1}`}, []bool{false}},
+		{[]string{`{truncated`}, []bool{false}},
+		{[]string{`{"a": "not closed`}, []bool{false}},
+		{[]string{`{`, `"a": [1, 2.5e3, -3,`, `true, false, null, "x\"y"`, `]`, `}`}, []bool{true, true, true, true, false}},
+		{[]string{`{ "a":`, `{"b": {}}`, `{"c": 1}`}, []bool{true, true, false}},
+		{[]string{`{`, `"a": 1`, `"b": 2`}, []bool{true, true, false}},
+		{[]string{`{`, `}`, `{`}, []bool{true, false}},
+	} {
+		t.Run(strings.Join(tt.lines, "|"), func(t *testing.T) {
+			var scanner panyl.IncompleteScanner
+			for i, line := range tt.lines {
+				if i >= len(tt.expected) {
+					break
+				}
+				item := panyl.InitItem()
+				item.Line = line
+				if i == 0 {
+					scanner = JSON{}.StartIncomplete(ctx, item)
+					assert.Equal(t, tt.expected[i], scanner != nil)
+				} else {
+					assert.Equal(t, tt.expected[i], scanner.ScanLine(ctx, item), "line %d", i)
+				}
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"io"
+	"log/slog"
 )
 
 // Processor sends lines to Job using a LineProvider.
@@ -68,27 +69,60 @@ func (p *Processor) Process(ctx context.Context, r io.Reader, output Output, opt
 }
 
 // ProcessProvider reads lines from a [LineProvider] until [LineProvider.Scan] returns false, sending the items found to
-// Output.
+// Output. If ctx is cancelled it stops without outputting the lines left, returning the context error.
+// The context passed to the plugins and to Output is cancelled when ProcessProvider returns.
 func (p *Processor) ProcessProvider(ctx context.Context, scanner LineProvider, output Output,
 	options ...JobOption) error {
 	job := NewJob(p, output, options...)
-	var err error
-	for scanner.Scan(ctx) {
-		err = job.ProcessLine(ctx, scanner.Line())
-		if err != nil {
-			if errors.Is(err, ErrFinished) {
-				break
+	// the context is cancelled when the job ends, even on errors, so plugins can release the state of the job with
+	// context.AfterFunc
+	ctx, cancel := context.WithCancel(JobToContext(ctx, job))
+	defer cancel()
+	if err := job.seekStartLine(scanner); err != nil {
+		return err
+	}
+	if job.Concurrency > 1 {
+		// the LineProvider error is returned by processConcurrent, as the scanner may still be in use
+		if err := job.processConcurrent(ctx, scanner); err != nil && !errors.Is(err, ErrFinished) {
+			return err
+		}
+	} else {
+		position, hasPosition := scanner.(LineProviderPosition)
+		var err error
+		// stop when the context is cancelled, even if the LineProvider doesn't check it
+		for ctx.Err() == nil && scanner.Scan(ctx) {
+			if hasPosition {
+				err = job.ProcessLineAt(ctx, scanner.Line(), position.LineNo(), position.Offset())
+			} else {
+				err = job.ProcessLine(ctx, scanner.Line())
+			}
+			if err != nil {
+				if errors.Is(err, ErrFinished) {
+					break
+				}
+				return err
 			}
+		}
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
 			return err
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
+	// output the lines left and the items created on finish, so the callbacks see all the items
+	if err := job.flushBacklog(ctx); err != nil {
+		return err
+	}
+	if err := job.createFinish(ctx); err != nil {
 		return err
 	}
 
 	for _, jobFinished := range p.onJobFinished {
-		_ = jobFinished(ctx, job)
+		if err := jobFinished(ctx, job); err != nil {
+			SLogFromContext(ctx).WarnContext(ctx, "error on job finished callback", slog.Any("error", err))
+		}
 	}
 
 	return job.Finish(ctx)
@@ -1,6 +1,7 @@
 package panyl
 
 import (
+	"bufio"
 	"context"
 	"fmt"
 	"strings"
@@ -83,7 +84,83 @@ func TestProcessor_PostProcessOrder(t *testing.T) {
 	assert.Equal(t, res.List[0].Line, "line_1_2_5_7_7_10")
 }
 
+func TestProcessor_SeekLineProvider(t *testing.T) {
+	ctx := context.Background()
+
+	p := NewProcessor()
+
+	lp := NewSeekLineProvider(strings.NewReader("first\nsecond\nthird\nfourth"), 0, nil)
+	assert.NoError(t, lp.SeekLine(2))
+
+	res := &OutputArray{}
+	err := p.ProcessProvider(ctx, lp, res)
+
+	assert.NoError(t, err)
+
+	assert.Len(t, res.List, 3)
+	assert.Equal(t, "second", res.List[0].Line)
+	assert.Equal(t, 2, res.List[0].LineNo)
+	assert.Equal(t, int64(6), res.List[0].Offset)
+	assert.Equal(t, "third", res.List[1].Line)
+	assert.Equal(t, 3, res.List[1].LineNo)
+	assert.Equal(t, int64(13), res.List[1].Offset)
+}
+
+func TestProcessor_SeekLineProviderLineLimit(t *testing.T) {
+	ctx := context.Background()
+
+	// the first line is longer than the maximum line size, so reading it fails
+	data := "first line is too long\nsecond\nthird\nfourth"
+	index, err := BuildLineIndex(ctx, strings.NewReader(data), 1)
+	assert.NoError(t, err)
+
+	for _, tt := range []struct {
+		name        string
+		index       *LineIndex
+		expectedErr bool
+	}{
+		{"without index", nil, true},
+		{"with index", index, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			lp := NewSeekLineProvider(strings.NewReader(data), 10, tt.index)
+
+			res := &OutputArray{}
+			err := NewProcessor().ProcessProvider(ctx, lp, res, WithLineLimit(3, 1))
+			if tt.expectedErr {
+				assert.ErrorIs(t, err, bufio.ErrTooLong)
+				return
+			}
+			assert.NoError(t, err)
+			if assert.Len(t, res.List, 2) {
+				assert.Equal(t, "third", res.List[0].Line)
+				assert.Equal(t, 3, res.List[0].LineNo)
+				assert.Equal(t, int64(30), res.List[0].Offset)
+				assert.Equal(t, 4, res.List[1].LineNo)
+			}
+		})
+	}
+}
+
 // AllPlugins
+func TestProcessor_ContextCancelled(t *testing.T) {
+	ctx := context.Background()
+
+	var jobCtx context.Context
+	p := NewProcessor(WithOnJobFinished(func(ctx context.Context, job *Job) error {
+		jobCtx = ctx
+		assert.NoError(t, ctx.Err())
+		return nil
+	}))
+
+	err := p.Process(ctx, strings.NewReader("line 1"), &OutputArray{})
+	assert.NoError(t, err)
+	// the job context is cancelled when the job ends
+	if assert.NotNil(t, jobCtx) {
+		assert.ErrorIs(t, jobCtx.Err(), context.Canceled)
+	}
+}
+
 type AllPlugins struct {
 }
 
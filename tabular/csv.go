@@ -0,0 +1,134 @@
+package tabular
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/RangelReale/panyl/v2"
+)
+
+// MetadataStructureCSV is the MetadataStructure of the items read by CSVLineProvider.
+const MetadataStructureCSV = "csv"
+
+// CSVLineProvider is a panyl.LineProvider which reads CSV or TSV rows, returning a *panyl.Item for each one with
+// the columns in Data, converted using the column types. The column names are read from the first row, unless set
+// using WithHeader. Values of rows with more columns than the header are named "column_N".
+// MetadataStructure is set to MetadataStructureCSV, so the Job doesn't try to parse the items again.
+// The line number and offset of each row in the source are informed.
+type CSVLineProvider struct {
+	cfg     config
+	csv     *csv.Reader   // with quotes
+	lines   *bufio.Reader // without quotes
+	header  []string
+	item    *panyl.Item
+	lineNo  int
+	offset  int64
+	nextPos int64 // offset of the next line, without quotes
+	nextNo  int   // line number of the next line, without quotes
+	err     error
+}
+
+var _ panyl.LineProvider = (*CSVLineProvider)(nil)
+var _ panyl.LineProviderPosition = (*CSVLineProvider)(nil)
+
+// NewCSVLineProvider creates a CSVLineProvider reading from r, with "," as delimiter.
+func NewCSVLineProvider(r io.Reader, options ...Option) *CSVLineProvider {
+	ret := &CSVLineProvider{
+		cfg: newConfig(',', options...),
+	}
+	ret.header = ret.cfg.header
+	if ret.cfg.quotes {
+		ret.csv = csv.NewReader(r)
+		ret.csv.Comma = ret.cfg.delimiter
+		ret.csv.FieldsPerRecord = -1
+		ret.csv.LazyQuotes = true
+	} else {
+		ret.lines = bufio.NewReader(r)
+	}
+	return ret
+}
+
+// NewTSVLineProvider creates a CSVLineProvider reading from r, with tab as delimiter and without quotes.
+func NewTSVLineProvider(r io.Reader, options ...Option) *CSVLineProvider {
+	return NewCSVLineProvider(r, append([]Option{WithDelimiter('\t'), WithQuotes(false)}, options...)...)
+}
+
+func (c *CSVLineProvider) Err() error {
+	return c.err
+}
+
+func (c *CSVLineProvider) Line() any {
+	return c.item
+}
+
+func (c *CSVLineProvider) LineNo() int {
+	return c.lineNo
+}
+
+func (c *CSVLineProvider) Offset() int64 {
+	return c.offset
+}
+
+func (c *CSVLineProvider) Scan(ctx context.Context) bool {
+	for {
+		if c.err != nil {
+			return false
+		}
+		if err := ctx.Err(); err != nil {
+			c.err = err
+			return false
+		}
+
+		values, err := c.readRow()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				c.err = err
+			}
+			return false
+		}
+		if c.header == nil {
+			c.header = make([]string, len(values))
+			for i, column := range values {
+				c.header[i] = strings.TrimSpace(column)
+			}
+			// remove the UTF-8 byte order mark
+			if len(c.header) > 0 {
+				c.header[0] = strings.TrimPrefix(c.header[0], "\ufeff")
+			}
+			continue
+		}
+		if len(values) == 1 && values[0] == "" {
+			// empty line
+			continue
+		}
+		c.item = c.cfg.newItem(MetadataStructureCSV, c.header, values)
+		return true
+	}
+}
+
+// readRow reads the values of the next row.
+func (c *CSVLineProvider) readRow() ([]string, error) {
+	if c.csv != nil {
+		c.offset = c.csv.InputOffset()
+		values, err := c.csv.Read()
+		if err != nil {
+			return nil, err
+		}
+		c.lineNo, _ = c.csv.FieldPos(0)
+		return values, nil
+	}
+
+	line, err := c.lines.ReadString('\n')
+	if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+		return nil, err
+	}
+	c.nextNo++
+	c.lineNo, c.offset = c.nextNo, c.nextPos
+	c.nextPos += int64(len(line))
+	line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+	return strings.Split(line, string(c.cfg.delimiter)), nil
+}
@@ -0,0 +1,76 @@
+package tabular
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RangelReale/panyl/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCSVLineProvider(t *testing.T) {
+	ctx := context.Background()
+
+	input := "\ufefftime,level,message,count,ratio,ok,id\n" +
+		"2024-03-05T10:00:00Z,info,\"started, with comma\",3,0.5,true,0012\n" +
+		"\n" +
+		"2024-03-05T10:00:01Z,error,\"multi\nline\",,nan,FALSE,7,extra\n"
+
+	p := panyl.NewProcessor()
+	res := &panyl.OutputArray{}
+	err := p.ProcessProvider(ctx, NewCSVLineProvider(strings.NewReader(input),
+		WithTimestampColumn("time", ""),
+		WithMetadataColumns(map[string]string{"level": panyl.MetadataLevel, "message": panyl.MetadataMessage}),
+		WithColumnTypes(map[string]ColumnType{"id": ColumnString})), res)
+	assert.NoError(t, err)
+
+	if assert.Len(t, res.List, 2) {
+		item := res.List[0]
+		assert.Equal(t, 2, item.LineNo)
+		assert.Equal(t, MetadataStructureCSV, item.Metadata[panyl.MetadataStructure])
+		assert.Equal(t, time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC), item.Metadata[panyl.MetadataTimestamp])
+		assert.Equal(t, panyl.MetadataLevelINFO, item.Metadata[panyl.MetadataLevel])
+		assert.Equal(t, "started, with comma", item.Metadata[panyl.MetadataMessage])
+		assert.Equal(t, panyl.MapValue{
+			"time":    "2024-03-05T10:00:00Z",
+			"level":   "info",
+			"message": "started, with comma",
+			"count":   int64(3),
+			"ratio":   0.5,
+			"ok":      true,
+			"id":      "0012",
+		}, item.Data)
+
+		item = res.List[1]
+		assert.Equal(t, 4, item.LineNo)
+		assert.Equal(t, "multi\nline", item.Data["message"])
+		assert.NotContains(t, item.Data, "count")
+		assert.Equal(t, "nan", item.Data["ratio"])
+		assert.Equal(t, false, item.Data["ok"])
+		assert.Equal(t, "extra", item.Data["column_8"])
+	}
+}
+
+func TestTSVLineProvider(t *testing.T) {
+	ctx := context.Background()
+
+	input := "a\t\"quoted\n1\t\"x\"\r\n2\ty\n"
+	lp := NewTSVLineProvider(strings.NewReader(input), WithHeader("n", "s"))
+
+	var items []*panyl.Item
+	var offsets []int64
+	for lp.Scan(ctx) {
+		items = append(items, lp.Line().(*panyl.Item))
+		offsets = append(offsets, lp.Offset())
+	}
+	assert.NoError(t, lp.Err())
+
+	if assert.Len(t, items, 3) {
+		assert.Equal(t, panyl.MapValue{"n": "a", "s": `"quoted`}, items[0].Data)
+		assert.Equal(t, panyl.MapValue{"n": int64(1), "s": `"x"`}, items[1].Data)
+		assert.Equal(t, panyl.MapValue{"n": int64(2), "s": "y"}, items[2].Data)
+		assert.Equal(t, []int64{0, 10, 17}, offsets)
+	}
+}
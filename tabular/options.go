@@ -0,0 +1,188 @@
+// Package tabular reads tabular logs, like CSV and TSV exports and the W3C extended log format used by IIS, where
+// each row becomes an item with the columns in its Data.
+package tabular
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RangelReale/panyl/v2"
+)
+
+// ColumnType is the type a column value is converted to.
+type ColumnType int
+
+const (
+	// ColumnAuto converts the value to the first type that parses it, int64, float64 or bool, or keeps it as a
+	// string. Numbers with leading zeros, like ids such as "007", and integers which don't fit an int64 are kept as
+	// strings, so they are not changed.
+	ColumnAuto ColumnType = iota
+	ColumnString
+	ColumnInt
+	ColumnFloat
+	ColumnBool
+)
+
+// ParseValue converts a value to a column type. If the value can't be converted it is returned as a string.
+func ParseValue(value string, columnType ColumnType) any {
+	switch columnType {
+	case ColumnInt:
+		if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return v
+		}
+	case ColumnFloat:
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			return v
+		}
+	case ColumnBool:
+		if v, err := strconv.ParseBool(value); err == nil {
+			return v
+		}
+	case ColumnAuto:
+		if hasLeadingZero(value) {
+			return value
+		}
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err == nil {
+			return v
+		} else if errors.Is(err, strconv.ErrRange) {
+			// a float64 would lose precision
+			return value
+		}
+		// don't convert words like "nan" and "inf", which also can't be encoded as JSON
+		if v, err := strconv.ParseFloat(value, 64); err == nil && !math.IsNaN(v) && !math.IsInf(v, 0) {
+			return v
+		}
+		if strings.EqualFold(value, "true") || strings.EqualFold(value, "false") {
+			return strings.EqualFold(value, "true")
+		}
+	}
+	return value
+}
+
+// hasLeadingZero returns whether the integer part of a number has a leading zero, like "007" or "-01.5".
+func hasLeadingZero(value string) bool {
+	value = strings.TrimLeft(value, "+-")
+	return len(value) > 1 && value[0] == '0' && value[1] >= '0' && value[1] <= '9'
+}
+
+// config holds the options of the line providers.
+type config struct {
+	delimiter       rune
+	quotes          bool
+	header          []string
+	columnTypes     map[string]ColumnType
+	metadataColumns map[string]string
+	timestampColumn string
+	timestampLayout string
+	location        *time.Location
+}
+
+func newConfig(delimiter rune, options ...Option) config {
+	ret := config{
+		delimiter: delimiter,
+		quotes:    true,
+		location:  time.UTC,
+	}
+	for _, opt := range options {
+		opt(&ret)
+	}
+	return ret
+}
+
+type Option func(*config)
+
+// WithDelimiter sets the column delimiter, by default "," for CSV.
+func WithDelimiter(delimiter rune) Option {
+	return func(c *config) {
+		c.delimiter = delimiter
+	}
+}
+
+// WithQuotes sets whether double-quoted values are supported, true by default. When false, quotes are part of the
+// values and rows can't span multiple lines, like in most TSV files.
+func WithQuotes(quotes bool) Option {
+	return func(c *config) {
+		c.quotes = quotes
+	}
+}
+
+// WithHeader sets the column names, for files without a header row.
+func WithHeader(columns ...string) Option {
+	return func(c *config) {
+		c.header = columns
+	}
+}
+
+// WithColumnTypes sets the type of columns, by default ColumnAuto.
+func WithColumnTypes(columnTypes map[string]ColumnType) Option {
+	return func(c *config) {
+		c.columnTypes = columnTypes
+	}
+}
+
+// WithMetadataColumns sets the columns which are copied to Metadata, as a map of column name to metadata key, like
+// {"severity": panyl.MetadataLevel}. The values are copied as strings.
+func WithMetadataColumns(metadataColumns map[string]string) Option {
+	return func(c *config) {
+		c.metadataColumns = metadataColumns
+	}
+}
+
+// WithTimestampColumn sets the column parsed as MetadataTimestamp, using the time layout. If the layout is empty,
+// time.RFC3339Nano is used.
+func WithTimestampColumn(column string, layout string) Option {
+	return func(c *config) {
+		c.timestampColumn = column
+		c.timestampLayout = layout
+	}
+}
+
+// WithLocation sets the location of the timestamps without time zone, by default UTC.
+func WithLocation(location *time.Location) Option {
+	return func(c *config) {
+		c.location = location
+	}
+}
+
+// columnType returns the type of a column.
+func (c *config) columnType(column string) ColumnType {
+	if t, ok := c.columnTypes[column]; ok {
+		return t
+	}
+	return ColumnAuto
+}
+
+// newItem creates the item of a row. Empty values are not set.
+func (c *config) newItem(structure string, columns []string, values []string) *panyl.Item {
+	item := panyl.InitItem(panyl.WithInitData(make(panyl.MapValue, len(values))))
+	item.Metadata[panyl.MetadataStructure] = structure
+	for i, value := range values {
+		var column string
+		if i < len(columns) {
+			column = columns[i]
+		} else {
+			column = "column_" + strconv.Itoa(i+1)
+		}
+		if value == "" {
+			continue
+		}
+		if metadata, ok := c.metadataColumns[column]; ok {
+			item.Metadata[metadata] = value
+		}
+		if column == c.timestampColumn {
+			layout := c.timestampLayout
+			if layout == "" {
+				layout = time.RFC3339Nano
+			}
+			if ts, err := time.ParseInLocation(layout, strings.TrimSpace(value), c.location); err == nil {
+				item.Metadata[panyl.MetadataTimestamp] = ts
+			}
+		}
+		item.Data[column] = ParseValue(value, c.columnType(column))
+	}
+	return item
+}
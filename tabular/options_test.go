@@ -0,0 +1,33 @@
+package tabular
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseValue(t *testing.T) {
+	for _, tt := range []struct {
+		value    string
+		expected any
+	}{
+		{"7", int64(7)},
+		{"-7", int64(-7)},
+		{"0", int64(0)},
+		{"0.5", 0.5},
+		{"1.5", 1.5},
+		{"007", "007"},
+		{"-01.5", "-01.5"},
+		{"9223372036854775807", int64(9223372036854775807)},
+		{"9223372036854775808", "9223372036854775808"},
+		{"123456789012345678901234567890", "123456789012345678901234567890"},
+		{"1e3", 1000.0},
+		{"nan", "nan"},
+		{"TRUE", true},
+		{"text", "text"},
+	} {
+		assert.Equal(t, tt.expected, ParseValue(tt.value, ColumnAuto), tt.value)
+	}
+	// explicit types are still converted
+	assert.Equal(t, int64(7), ParseValue("007", ColumnInt))
+}
@@ -0,0 +1,175 @@
+package tabular
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/RangelReale/panyl/v2"
+)
+
+// MetadataStructureW3C is the MetadataStructure of the items read by W3CLineProvider.
+const MetadataStructureW3C = "w3c"
+
+// W3C extended log format fields used for the timestamp.
+const (
+	W3CFieldDate = "date"
+	W3CFieldTime = "time"
+)
+
+// W3CLineProvider is a panyl.LineProvider which reads the W3C extended log format, used by IIS, returning a
+// *panyl.Item for each entry with the fields in Data, converted using the column types. The field names are read
+// from the "#Fields:" directive, which may appear again to change them. The value "-", meaning no value, is not
+// set.
+// The "date" and "time" fields are parsed as MetadataTimestamp. If there is no date field, the date of the "#Date:"
+// directive is used.
+// MetadataStructure is set to MetadataStructureW3C, so the Job doesn't try to parse the items again.
+// The line number and offset of each entry in the source are informed.
+type W3CLineProvider struct {
+	cfg     config
+	r       *bufio.Reader
+	fields  []string
+	date    string // date of the #Date directive
+	item    *panyl.Item
+	lineNo  int
+	offset  int64
+	nextPos int64
+	err     error
+}
+
+var _ panyl.LineProvider = (*W3CLineProvider)(nil)
+var _ panyl.LineProviderPosition = (*W3CLineProvider)(nil)
+
+// NewW3CLineProvider creates a W3CLineProvider reading from r. The delimiter is space, and quoted values are
+// supported unless WithQuotes(false) is set.
+func NewW3CLineProvider(r io.Reader, options ...Option) *W3CLineProvider {
+	return &W3CLineProvider{
+		cfg: newConfig(' ', options...),
+		r:   bufio.NewReader(r),
+	}
+}
+
+func (w *W3CLineProvider) Err() error {
+	return w.err
+}
+
+func (w *W3CLineProvider) Line() any {
+	return w.item
+}
+
+func (w *W3CLineProvider) LineNo() int {
+	return w.lineNo
+}
+
+func (w *W3CLineProvider) Offset() int64 {
+	return w.offset
+}
+
+func (w *W3CLineProvider) Scan(ctx context.Context) bool {
+	for {
+		if w.err != nil {
+			return false
+		}
+		if err := ctx.Err(); err != nil {
+			w.err = err
+			return false
+		}
+
+		line, err := w.r.ReadString('\n')
+		if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+			if !errors.Is(err, io.EOF) {
+				w.err = err
+			}
+			return false
+		}
+		w.lineNo++
+		w.offset = w.nextPos
+		w.nextPos += int64(len(line))
+		line = strings.TrimRight(line, "\r\n")
+
+		if directive, ok := strings.CutPrefix(line, "#"); ok {
+			w.directive(directive)
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		values := w.split(line)
+		for i, value := range values {
+			if value == "-" {
+				values[i] = ""
+			}
+		}
+		w.item = w.cfg.newItem(MetadataStructureW3C, w.fields, values)
+		w.setTimestamp(values)
+		return true
+	}
+}
+
+// directive handles the "#Fields:" and "#Date:" directives.
+func (w *W3CLineProvider) directive(directive string) {
+	name, value, _ := strings.Cut(directive, ":")
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "fields":
+		w.fields = strings.Fields(value)
+	case "date":
+		// the time part is optional
+		w.date, _, _ = strings.Cut(strings.TrimSpace(value), " ")
+	}
+}
+
+// split splits a line on the delimiter, supporting double-quoted values if enabled.
+func (w *W3CLineProvider) split(line string) []string {
+	var ret []string
+	var value strings.Builder
+	quoted, inValue := false, false
+	for _, c := range line {
+		switch {
+		case c == '"' && w.cfg.quotes && (quoted || !inValue):
+			quoted = !quoted
+			inValue = true
+		case c == w.cfg.delimiter && !quoted:
+			if inValue {
+				ret = append(ret, value.String())
+				value.Reset()
+				inValue = false
+			}
+		default:
+			value.WriteRune(c)
+			inValue = true
+		}
+	}
+	if inValue {
+		ret = append(ret, value.String())
+	}
+	return ret
+}
+
+// setTimestamp sets MetadataTimestamp from the date and time fields.
+func (w *W3CLineProvider) setTimestamp(values []string) {
+	if w.item.Metadata.HasValue(panyl.MetadataTimestamp) {
+		return
+	}
+	date, tm := w.date, ""
+	for i, field := range w.fields {
+		if i >= len(values) {
+			break
+		}
+		switch field {
+		case W3CFieldDate:
+			date = values[i]
+		case W3CFieldTime:
+			tm = values[i]
+		}
+	}
+	if date == "" || tm == "" {
+		return
+	}
+	if ts, err := time.ParseInLocation("2006-01-02 15:04:05", date+" "+tm, w.cfg.location); err == nil {
+		w.item.Metadata[panyl.MetadataTimestamp] = ts
+	}
+}
@@ -0,0 +1,73 @@
+package tabular
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RangelReale/panyl/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestW3CLineProvider(t *testing.T) {
+	ctx := context.Background()
+
+	input := "#Software: Microsoft Internet Information Services 10.0\r\n" +
+		"#Version: 1.0\r\n" +
+		"#Date: 2024-03-05 10:00:00\r\n" +
+		"#Fields: date time s-ip cs-method cs-uri-stem cs(User-Agent) sc-status time-taken\r\n" +
+		"2024-03-05 10:00:01 10.0.0.1 GET /index.html Mozilla/5.0+(Windows) 200 15\r\n" +
+		"2024-03-05 10:00:02 10.0.0.1 POST /api - 500 1.5\r\n" +
+		"#Fields: time cs-method cs-uri-stem\r\n" +
+		"10:01:00 GET \"/with space\"\r\n"
+
+	p := panyl.NewProcessor()
+	res := &panyl.OutputArray{}
+	err := p.ProcessProvider(ctx, NewW3CLineProvider(strings.NewReader(input)), res)
+	assert.NoError(t, err)
+
+	if assert.Len(t, res.List, 3) {
+		item := res.List[0]
+		assert.Equal(t, 5, item.LineNo)
+		assert.Equal(t, MetadataStructureW3C, item.Metadata[panyl.MetadataStructure])
+		assert.Equal(t, time.Date(2024, 3, 5, 10, 0, 1, 0, time.UTC), item.Metadata[panyl.MetadataTimestamp])
+		assert.Equal(t, panyl.MapValue{
+			"date":           "2024-03-05",
+			"time":           "10:00:01",
+			"s-ip":           "10.0.0.1",
+			"cs-method":      "GET",
+			"cs-uri-stem":    "/index.html",
+			"cs(User-Agent)": "Mozilla/5.0+(Windows)",
+			"sc-status":      int64(200),
+			"time-taken":     int64(15),
+		}, item.Data)
+
+		item = res.List[1]
+		assert.NotContains(t, item.Data, "cs(User-Agent)")
+		assert.Equal(t, 1.5, item.Data["time-taken"])
+
+		// the date comes from the #Date directive
+		item = res.List[2]
+		assert.Equal(t, 8, item.LineNo)
+		assert.Equal(t, time.Date(2024, 3, 5, 10, 1, 0, 0, time.UTC), item.Metadata[panyl.MetadataTimestamp])
+		assert.Equal(t, "/with space", item.Data["cs-uri-stem"])
+	}
+}
+
+func TestW3CLineProvider_DateWithoutTime(t *testing.T) {
+	ctx := context.Background()
+
+	input := "#Date: 2024-03-05\n" +
+		"#Fields: time cs-method\n" +
+		"10:01:00 GET\n"
+
+	p := panyl.NewProcessor()
+	res := &panyl.OutputArray{}
+	err := p.ProcessProvider(ctx, NewW3CLineProvider(strings.NewReader(input)), res)
+	assert.NoError(t, err)
+
+	if assert.Len(t, res.List, 1) {
+		assert.Equal(t, time.Date(2024, 3, 5, 10, 1, 0, 0, time.UTC), res.List[0].Metadata[panyl.MetadataTimestamp])
+	}
+}
@@ -1,23 +1,49 @@
 package util
 
 import (
-	"regexp"
+	"strings"
 )
 
-// https://stackoverflow.com/a/33925425/784175
-var cleanAnsiEscapeRE = regexp.MustCompile(`(\x9B|\x1B\[)[0-?]*[ -\/]*[@-~]`)
-
 // AnsiEscapeString escapes ANSI characters from a string, returning whether any was found.
+// It removes CSI sequences, matching the regular expression `(\x9B|\x1B\[)[0-?]*[ -\/]*[@-~]`
+// (https://stackoverflow.com/a/33925425/784175).
 func AnsiEscapeString(s string) (bool, string) {
-	count := 0
-	ret := cleanAnsiEscapeRE.ReplaceAllStringFunc(s, func(s string) string {
-		count++
-		return ""
-	})
-	if count > 0 {
-		return true, ret
+	var ret strings.Builder
+	found := false
+	last := 0
+	for i := 0; i < len(s); {
+		// sequence introducer, ESC + '[' or the UTF-8 encoding of U+009B
+		if i+1 >= len(s) || !((s[i] == '\x1b' && s[i+1] == '[') || (s[i] == 0xc2 && s[i+1] == 0x9b)) {
+			i++
+			continue
+		}
+		j := i + 2
+		// parameter bytes
+		for j < len(s) && s[j] >= 0x30 && s[j] <= 0x3f {
+			j++
+		}
+		// intermediate bytes
+		for j < len(s) && s[j] >= 0x20 && s[j] <= 0x2f {
+			j++
+		}
+		// final byte
+		if j >= len(s) || s[j] < 0x40 || s[j] > 0x7e {
+			i++
+			continue
+		}
+		if !found {
+			found = true
+			ret.Grow(len(s))
+		}
+		_, _ = ret.WriteString(s[last:i])
+		last = j + 1
+		i = j + 1
+	}
+	if !found {
+		return false, ""
 	}
-	return false, ""
+	_, _ = ret.WriteString(s[last:])
+	return true, ret.String()
 }
 
 // DoAnsiEscapeString escapes ANSI characters from a string.
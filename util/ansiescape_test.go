@@ -0,0 +1,72 @@
+package util
+
+import (
+	"math/rand"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// ansiEscapeRE is the regular expression AnsiEscapeString was previously implemented with
+var ansiEscapeRE = regexp.MustCompile(`(\x9B|\x1B\[)[0-?]*[ -\/]*[@-~]`)
+
+func regexAnsiEscapeString(s string) (bool, string) {
+	if !ansiEscapeRE.MatchString(s) {
+		return false, ""
+	}
+	return true, ansiEscapeRE.ReplaceAllString(s, "")
+}
+
+func TestAnsiEscapeString(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		s        string
+		found    bool
+		expected string
+	}{
+		{"no escapes", "plain text", false, ""},
+		{"empty", "", false, ""},
+		{"esc", "\x1b[31mred\x1b[0m text", true, "red text"},
+		{"8-bit introducer", "\u009b1mbold\u009bm", true, "bold"},
+		{"raw 0x9b byte", "a\x9b1mb", false, ""},
+		{"parameters", "\x1b[1;31;48;5;200mtext", true, "text"},
+		{"private parameters", "\x1b[?25lhidden\x1b[?25h", true, "hidden"},
+		{"intermediate", "\x1b[1 qcursor", true, "cursor"},
+		{"parameters after intermediate", "\x1b[ 1mtext", false, ""},
+		{"cut at end", "text\x1b[31", false, ""},
+		{"cut introducer", "text\x1b", false, ""},
+		{"cut 8-bit introducer", "text\xc2", false, ""},
+		{"cut before next", "\x1b[12\x1b[0mtext", true, "\x1b[12text"},
+		{"esc without bracket", "\x1b]0;title\x07text", false, ""},
+		{"adjacent", "\x1b[1m\x1b[4m\u009b0mtext", true, "text"},
+		{"unicode text", "ação \x1b[32m✓\x1b[0m", true, "ação ✓"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			found, s := AnsiEscapeString(tt.s)
+			assert.Equal(t, tt.found, found)
+			assert.Equal(t, tt.expected, s)
+
+			reFound, reS := regexAnsiEscapeString(tt.s)
+			assert.Equal(t, reFound, found)
+			assert.Equal(t, reS, s)
+		})
+	}
+}
+
+func TestAnsiEscapeString_Regex(t *testing.T) {
+	// compare random strings of relevant bytes with the regular expression
+	alphabet := []string{"\x1b", "[", "\u009b", "\xc2", "\x9b", "0", "9", ";", "?", " ", "/", "@", "m", "~", "a", "\x7f"}
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 20000; i++ {
+		var s string
+		for n := r.Intn(12); n > 0; n-- {
+			s += alphabet[r.Intn(len(alphabet))]
+		}
+		found, es := AnsiEscapeString(s)
+		reFound, reS := regexAnsiEscapeString(s)
+		if !assert.Equal(t, reFound, found, "%q", s) || !assert.Equal(t, reS, es, "%q", s) {
+			return
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package util
+
+import (
+	"hash/fnv"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Placeholders used by MessageTemplate for the masked tokens.
+const (
+	TemplateString   = "<STR>"
+	TemplateUUID     = "<UUID>"
+	TemplateIP       = "<IP>"
+	TemplateHex      = "<HEX>"
+	TemplateNumber   = "<NUM>"
+	TemplateVariable = "<*>"
+)
+
+// templateTokenRegex matches the variable tokens, the order of the alternatives sets their priority.
+// Single quotes must not be next to word characters, so apostrophes like in "can't" and "don't" don't start or
+// end a quoted value.
+var templateTokenRegex = regexp.MustCompile(`"(?:[^"\\]|\\.)*"` +
+	`|\B'(?:[^'\\]|\\.)*'\B` +
+	`|\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b` +
+	`|\b\d{1,3}(?:\.\d{1,3}){3}(?::\d{1,5})?\b` +
+	`|\b[0-9a-fA-F]{0,4}(?::[0-9a-fA-F]{0,4}){2,7}\b` +
+	`|\b0[xX][0-9a-fA-F]+\b` +
+	`|\b\d+(?:\.\d+)*\b` +
+	`|\b\w*\d\w*\b`)
+
+// MessageTemplate returns the template of a log message, replacing its variable tokens (quoted strings, UUIDs,
+// IP addresses, hex values, numbers and words containing digits) with placeholders, and collapsing spaces.
+// Messages that differ only on these tokens return the same template, like in the Drain algorithm.
+func MessageTemplate(message string) string {
+	masked := templateTokenRegex.ReplaceAllStringFunc(message, templateToken)
+	return strings.Join(strings.Fields(masked), " ")
+}
+
+func templateToken(token string) string {
+	switch token[0] {
+	case '"', '\'':
+		return TemplateString
+	}
+	switch {
+	case len(token) == 36 && strings.Count(token, "-") == 4:
+		return TemplateUUID
+	case strings.Contains(token, ":"):
+		// IPv4 with port, IPv6, or values like times
+		host := token
+		if h, _, err := net.SplitHostPort(token); err == nil {
+			host = h
+		}
+		if net.ParseIP(host) != nil {
+			return TemplateIP
+		}
+		if !strings.ContainsAny(token, "0123456789") {
+			// words separated by colons
+			return token
+		}
+		return TemplateVariable
+	case strings.Count(token, ".") == 3 && net.ParseIP(token) != nil:
+		return TemplateIP
+	case len(token) > 2 && (token[1] == 'x' || token[1] == 'X') && token[0] == '0':
+		return TemplateHex
+	}
+	if _, err := strconv.ParseFloat(token, 64); err == nil {
+		return TemplateNumber
+	}
+	if len(token) >= 8 && isHexString(token) {
+		return TemplateHex
+	}
+	return TemplateVariable
+}
+
+func isHexString(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+// TemplateID returns a stable id for a template, the hex FNV-1a 64-bit hash of the string.
+func TemplateID(template string) string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(template))
+	return strconv.FormatUint(h.Sum64(), 16)
+}
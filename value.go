@@ -1,6 +1,7 @@
 package panyl
 
 import (
+	"reflect"
 	"strconv"
 )
 
@@ -175,3 +176,107 @@ func (m MapValue) ListValueContains(name string, value string) bool {
 	}
 	return false
 }
+
+// Merge merges the values of src into m, without overwriting non-empty values. Map values are merged recursively.
+// nil values from src are ignored, and nested maps are copied, so later changes to m don't change src.
+func (m MapValue) Merge(src map[string]any) {
+	for name, sv := range src {
+		if sv == nil {
+			continue
+		}
+		dv, ok := m[name]
+		if !ok || isEmptyValue(dv) {
+			m[name] = copyValue(sv)
+			continue
+		}
+		mergeValue(dv, sv)
+	}
+}
+
+// copyValue returns a deep copy of nested maps, so merging into the destination later doesn't change the source.
+// Other values are returned unchanged.
+func copyValue(v any) any {
+	switch vv := v.(type) {
+	case map[string]any:
+		ret := make(map[string]any, len(vv))
+		for name, value := range vv {
+			ret[name] = copyValue(value)
+		}
+		return ret
+	case MapValue:
+		return MapValue(copyValue(map[string]any(vv)).(map[string]any))
+	}
+	return v
+}
+
+// mergeValue merges src into dst if both are maps of the same type.
+func mergeValue(dst, src any) {
+	if dm, ok := asStringMap(dst); ok {
+		if sm, ok := asStringMap(src); ok {
+			MapValue(dm).Merge(sm)
+		}
+		return
+	}
+
+	dv, sv := reflect.ValueOf(dst), reflect.ValueOf(src)
+	if dv.Kind() != reflect.Map || dv.Type() != sv.Type() {
+		return
+	}
+	iter := sv.MapRange()
+	for iter.Next() {
+		ev := dv.MapIndex(iter.Key())
+		if !ev.IsValid() || isEmptyValue(ev.Interface()) {
+			dv.SetMapIndex(iter.Key(), iter.Value())
+		}
+	}
+}
+
+func asStringMap(v any) (map[string]any, bool) {
+	switch vv := v.(type) {
+	case map[string]any:
+		return vv, true
+	case MapValue:
+		return vv, true
+	}
+	return nil, false
+}
+
+func isEmptyValue(v any) bool {
+	switch vv := v.(type) {
+	case nil:
+		return true
+	case string:
+		return vv == ""
+	case bool:
+		return !vv
+	case int:
+		return vv == 0
+	case int64:
+		return vv == 0
+	case float64:
+		return vv == 0
+	case map[string]any:
+		return len(vv) == 0
+	case MapValue:
+		return len(vv) == 0
+	case []any:
+		return len(vv) == 0
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return rv.Len() == 0
+	case reflect.Bool:
+		return !rv.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return rv.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return rv.Float() == 0
+	case reflect.Interface, reflect.Pointer, reflect.Func:
+		return rv.IsNil()
+	}
+	return false
+}
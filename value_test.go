@@ -0,0 +1,137 @@
+package panyl
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapValue_Merge(t *testing.T) {
+	dst := MapValue{
+		"a": 1,
+		"b": "",
+		"c": map[string]any{"x": 1, "z": ""},
+		"d": map[string]string{"x": "1"},
+		"e": "keep",
+	}
+	dst.Merge(map[string]any{
+		"a": 2,
+		"b": "B",
+		"c": map[string]any{"x": 2, "y": 3, "z": "Z"},
+		"d": map[string]string{"x": "2", "y": "3"},
+		"e": map[string]any{"x": 1},
+		"f": nil,
+		"g": 0,
+	})
+
+	assert.Equal(t, MapValue{
+		"a": 1,
+		"b": "B",
+		"c": map[string]any{"x": 1, "y": 3, "z": "Z"},
+		"d": map[string]string{"x": "1", "y": "3"},
+		"e": "keep",
+		"g": 0,
+	}, dst)
+}
+
+func TestMapValue_MergeCopiesMaps(t *testing.T) {
+	src := MapValue{
+		"a": map[string]any{"x": 1, "n": map[string]any{"y": 1}},
+		"b": MapValue{"x": 1},
+	}
+	dst := MapValue{}
+	dst.Merge(src)
+	dst.Merge(map[string]any{
+		"a": map[string]any{"z": 2, "n": map[string]any{"w": 2}},
+		"b": map[string]any{"z": 2},
+	})
+
+	assert.Equal(t, MapValue{
+		"a": map[string]any{"x": 1, "n": map[string]any{"y": 1}},
+		"b": MapValue{"x": 1},
+	}, src)
+	assert.Equal(t, MapValue{
+		"a": map[string]any{"x": 1, "z": 2, "n": map[string]any{"y": 1, "w": 2}},
+		"b": MapValue{"x": 1, "z": 2},
+	}, dst)
+}
+
+func TestMapValue_MergeMergo(t *testing.T) {
+	// the expected values are the results of mergo.Map (github.com/imdario/mergo v0.3.12), which Merge replaced,
+	// for the kinds of values the plugins merge: Metadata and decoded JSON or text data.
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	for _, tt := range []struct {
+		name     string
+		dst, src map[string]any
+		expected map[string]any
+	}{
+		{"empty destination",
+			map[string]any{},
+			map[string]any{"a": "x", "b": 1.5, "c": true, "d": nil, "e": []any{1.0}, "f": map[string]any{"x": 1.0}, "t": ts},
+			map[string]any{"a": "x", "b": 1.5, "c": true, "e": []any{1.0}, "f": map[string]any{"x": 1.0}, "t": ts}},
+		{"non-empty values are kept",
+			map[string]any{"a": "x", "b": 1.5, "c": true, "t": ts},
+			map[string]any{"a": "y", "b": 2.5, "c": false, "t": ts.Add(time.Second)},
+			map[string]any{"a": "x", "b": 1.5, "c": true, "t": ts}},
+		{"empty values are replaced",
+			map[string]any{"a": "", "b": 0.0, "c": false, "e": []any{}},
+			map[string]any{"a": "y", "b": 2.5, "c": true, "e": []any{1.0}},
+			map[string]any{"a": "y", "b": 2.5, "c": true, "e": []any{1.0}}},
+		{"zero time is not empty",
+			map[string]any{"t": time.Time{}},
+			map[string]any{"t": ts},
+			map[string]any{"t": time.Time{}}},
+		{"nil source values are ignored",
+			map[string]any{"a": "x", "b": 1.0},
+			map[string]any{"a": nil, "b": nil},
+			map[string]any{"a": "x", "b": 1.0}},
+		{"nil destination values are replaced",
+			map[string]any{"x": nil},
+			map[string]any{"x": "v"},
+			map[string]any{"x": "v"}},
+		{"nested maps",
+			map[string]any{"f": map[string]any{"x": 1.0, "z": ""}},
+			map[string]any{"f": map[string]any{"x": 2.0, "y": 3.0, "z": "Z"}},
+			map[string]any{"f": map[string]any{"x": 1.0, "y": 3.0, "z": "Z"}}},
+		{"deeply nested maps",
+			map[string]any{"f": map[string]any{"x": map[string]any{"a": 1.0}}},
+			map[string]any{"f": map[string]any{"x": map[string]any{"a": 2.0, "b": 3.0}}},
+			map[string]any{"f": map[string]any{"x": map[string]any{"a": 1.0, "b": 3.0}}}},
+		{"empty nested map",
+			map[string]any{"f": map[string]any{}},
+			map[string]any{"f": map[string]any{"x": 1.0}},
+			map[string]any{"f": map[string]any{"x": 1.0}}},
+		{"map over value",
+			map[string]any{"f": "str"},
+			map[string]any{"f": map[string]any{"x": 1.0}},
+			map[string]any{"f": "str"}},
+		{"value over map",
+			map[string]any{"f": map[string]any{"x": 1.0}},
+			map[string]any{"f": "str"},
+			map[string]any{"f": map[string]any{"x": 1.0}}},
+		{"slices are not appended",
+			map[string]any{"e": []any{1.0}, "l": []string{"a"}},
+			map[string]any{"e": []any{2.0, 3.0}, "l": []string{"b"}},
+			map[string]any{"e": []any{1.0}, "l": []string{"a"}}},
+		{"list over string",
+			map[string]any{"l": "a"},
+			map[string]any{"l": []string{"b"}},
+			map[string]any{"l": "a"}},
+		{"typed maps",
+			map[string]any{"d": map[string]string{"x": "1"}},
+			map[string]any{"d": map[string]string{"x": "2", "y": "3"}},
+			map[string]any{"d": map[string]string{"x": "1", "y": "3"}}},
+		{"integers",
+			map[string]any{"i": 0, "j": int64(5)},
+			map[string]any{"i": 3, "j": int64(6)},
+			map[string]any{"i": 3, "j": int64(5)}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := MapValue(tt.dst)
+			dst.Merge(tt.src)
+			assert.Equal(t, MapValue(tt.expected), dst)
+		})
+	}
+}